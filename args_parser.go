@@ -26,21 +26,68 @@ type kingpinParser struct {
 	numConns          uint64
 	timeout           time.Duration
 	latencies         bool
+	connStats         bool
+	percentiles       *percentileList
+	histogramDigits   uint64
+	hdrLogPath        string
+	hdrLogInterval    time.Duration
+	hgrmPath          string
+	reports           *reportList
+	reportInterval    time.Duration
+	reportTags        *tagList
+	traceparent       bool
+	otlpEndpoint      string
+	traceSlowest      uint64
 	insecure          bool
 	disableKeepAlives bool
+	tlsHandshake      bool
+	tlsResumption     bool
 	method            string
 	body              string
 	bodyFilePath      string
+	form              *formFields
 	stream            bool
+	compressBody      string
+	acceptEncoding    string
+	sampleResponses   uint64
+	samplesDir        string
+	logRequestsPath   string
+	logSample         float64
+	requestTemplates  bool
+	dataFilePath      string
+	dataMode          string
+	dataOnEOF         string
+	scenarioPath      string
+	mixPath           string
+	replayPath        string
+	replayHeaders     string
+	replaySpeed       float64
+	cookies           bool
+	cookieFilePath    string
+	maxRedirects      uint64
+	basicAuth         string
+	bearerTokenFile   string
+	bearerTokenCmd    string
+	bearerRefresh     time.Duration
+	hmacSecret        string
+	hmacHeader        string
+	awsSigV4          string
+	oauth2TokenURL    string
+	oauth2ClientID    string
+	oauth2Secret      string
+	oauth2Scope       string
 	certPath          string
 	keyPath           string
 	rate              *nullableUint64
+	thinkTime         string
 	clientType        clientTyp
 
 	printSpec *nullableString
 	noPrint   bool
 
 	formatSpec string
+	noHeader   bool
+	outputs    *outputList
 }
 
 func newKingpinParser() argsParser {
@@ -48,6 +95,11 @@ func newKingpinParser() argsParser {
 		numReqs:      new(nullableUint64),
 		duration:     new(nullableDuration),
 		headers:      new(headersList),
+		form:         new(formFields),
+		percentiles:  new(percentileList),
+		outputs:      new(outputList),
+		reports:      new(reportList),
+		reportTags:   new(tagList),
 		numConns:     defaultNumberOfConns,
 		timeout:      defaultTimeout,
 		latencies:    false,
@@ -64,6 +116,8 @@ func newKingpinParser() argsParser {
 		printSpec:    new(nullableString),
 		noPrint:      false,
 		formatSpec:   "plain-text",
+		dataMode:     feedSequential.String(),
+		dataOnEOF:    "wrap",
 	}
 
 	app := kingpin.New("", "Fast cross-platform HTTP benchmarking tool").
@@ -80,6 +134,52 @@ func newKingpinParser() argsParser {
 	app.Flag("latencies", "Print latency statistics").
 		Short('l').
 		BoolVar(&kparser.latencies)
+	app.Flag("conn-stats", "Print connection statistics").
+		BoolVar(&kparser.connStats)
+	app.Flag("percentiles", "Comma-separated percentiles to report, "+
+		"e.g. 50,90,99,99.9 (default: "+defaultPercentiles.String()+")").
+		PlaceHolder("<list>").
+		SetValue(kparser.percentiles)
+	app.Flag("histogram-digits", "Number of significant digits "+
+		"histograms keep, from 1 to 4 (default: 3)").
+		PlaceHolder("N").
+		Uint64Var(&kparser.histogramDigits)
+	app.Flag("hdr-log", "Write latency histograms into the file "+
+		"in HdrHistogram interval log format").
+		PlaceHolder("<path>").
+		StringVar(&kparser.hdrLogPath)
+	app.Flag("hdr-log-interval", "Log a histogram for each interval "+
+		"instead of a single one for the whole test").
+		PlaceHolder("<duration>").
+		DurationVar(&kparser.hdrLogInterval)
+	app.Flag("hgrm", "Write latency percentile distribution into "+
+		"the file in HdrHistogram's .hgrm format").
+		PlaceHolder("<path>").
+		StringVar(&kparser.hgrmPath)
+	app.Flag("report", "Send metrics during the test to "+
+		"statsd:<host:port> (UDP), graphite:<host:port> (TCP) or "+
+		"influxdb:<URL> (line protocol over HTTP). Can be repeated").
+		PlaceHolder("<spec>").
+		SetValue(kparser.reports)
+	app.Flag("report-interval", "How often to send metrics").
+		PlaceHolder(defaultReportInterval.String()).
+		DurationVar(&kparser.reportInterval)
+	app.Flag("report-tag", "Tag to send metrics with, e.g. "+
+		"test=checkout. Can be repeated").
+		PlaceHolder("<key=value>").
+		SetValue(kparser.reportTags)
+	app.Flag("traceparent", "Send W3C traceparent header with "+
+		"a new trace id for each request").
+		BoolVar(&kparser.traceparent)
+	app.Flag("otlp-endpoint", "Export client spans of requests to "+
+		"OTLP/HTTP collector, e.g. http://localhost:4318 "+
+		"(implies --traceparent)").
+		PlaceHolder("<URL>").
+		StringVar(&kparser.otlpEndpoint)
+	app.Flag("trace-slowest", "How many of the slowest traced "+
+		"requests to list in the result").
+		PlaceHolder(strconv.FormatUint(defaultTraceSlowest, decBase)).
+		Uint64Var(&kparser.traceSlowest)
 	app.Flag("method", "Request method").
 		PlaceHolder("GET").
 		Short('m').
@@ -92,10 +192,129 @@ func newKingpinParser() argsParser {
 		Default("").
 		Short('f').
 		StringVar(&kparser.bodyFilePath)
+	app.Flag("form", "Form field to send, K=@path uploads the file "+
+		"(can be repeated)").
+		PlaceHolder("\"K=V\"").
+		SetValue(kparser.form)
 	app.Flag("stream", "Specify whether to stream body using "+
 		"chunked transfer encoding or to serve it from memory").
 		Short('s').
 		BoolVar(&kparser.stream)
+	app.Flag("compress-body", "Compress request body and set "+
+		"Content-Encoding: gzip, zstd or br").
+		PlaceHolder("<enc>").
+		EnumVar(&kparser.compressBody, bodyEncodings...)
+	app.Flag("accept-encoding", "Send Accept-Encoding header and "+
+		"report sizes of response bodies before and after "+
+		"decompression").
+		PlaceHolder("<encodings>").
+		StringVar(&kparser.acceptEncoding)
+	app.Flag("sample-responses", "Save headers and bodies of the "+
+		"first N responses with each status code").
+		PlaceHolder("N").
+		Uint64Var(&kparser.sampleResponses)
+	app.Flag("samples-dir", "Directory to save sampled responses "+
+		"to (default: "+defaultSamplesDir+")").
+		PlaceHolder("<dir>").
+		StringVar(&kparser.samplesDir)
+	app.Flag("log-requests", "Write a JSON Lines record of each "+
+		"request into the file").
+		PlaceHolder("<path>").
+		StringVar(&kparser.logRequestsPath)
+	app.Flag("log-sample", "Fraction of requests to log, e.g. 0.01 "+
+		"for 1%").
+		Default("1").
+		PlaceHolder("1").
+		Float64Var(&kparser.logSample)
+	app.Flag("request-templates", "Evaluate URL path and query, "+
+		"header values and body as templates for each request").
+		BoolVar(&kparser.requestTemplates)
+	app.Flag("data", "CSV (with header) or JSON Lines (.jsonl) file, "+
+		"whose rows are available as .Data in request templates").
+		PlaceHolder("<path>").
+		StringVar(&kparser.dataFilePath)
+	app.Flag("data-mode", "How to pick rows of the data file: "+
+		"sequential, random or unique (rows are split between connections)").
+		PlaceHolder(feedSequential.String()).
+		EnumVar(&kparser.dataMode, "sequential", "random", "unique")
+	app.Flag("data-eof", "What to do upon reaching the end of the "+
+		"data file: wrap or stop").
+		PlaceHolder("wrap").
+		EnumVar(&kparser.dataOnEOF, "wrap", "stop")
+	app.Flag("scenario", "JSON file with the steps each connection "+
+		"performs in order, see docs for the format").
+		PlaceHolder("<path>").
+		StringVar(&kparser.scenarioPath)
+	app.Flag("mix", "JSON file with weighted requests, one of which "+
+		"is picked at random for each request, see docs for the format").
+		PlaceHolder("<path>").
+		StringVar(&kparser.mixPath)
+	app.Flag("replay", "HAR file or access log (combined format) "+
+		"with requests to replay against the URL").
+		PlaceHolder("<path>").
+		StringVar(&kparser.replayPath)
+	app.Flag("replay-headers", "Comma-separated names of the "+
+		"headers to keep when replaying requests").
+		PlaceHolder("<header-names>").
+		StringVar(&kparser.replayHeaders)
+	app.Flag("replay-speed", "Keep the original timing of replayed "+
+		"requests, sped up by this ratio").
+		PlaceHolder("<ratio>").
+		Float64Var(&kparser.replaySpeed)
+	app.Flag("cookies", "Keep a separate cookie jar for each connection").
+		BoolVar(&kparser.cookies)
+	app.Flag("cookie-file", "File with cookies in Netscape format to "+
+		"seed cookie jars with (implies --cookies)").
+		PlaceHolder("<path>").
+		StringVar(&kparser.cookieFilePath)
+	app.Flag(followRedirectsFlag, "Follow up to N redirects "+
+		"(N is "+strconv.FormatUint(defaultMaxRedirects, decBase)+
+		", if omitted), redirect loops are reported as errors").
+		PlaceHolder("N").
+		Uint64Var(&kparser.maxRedirects)
+	app.Flag("basic-auth", "Use HTTP Basic authentication").
+		PlaceHolder("<user:password>").
+		StringVar(&kparser.basicAuth)
+	app.Flag("bearer-token-file", "File with the bearer token, "+
+		"which is re-read whenever the file changes").
+		PlaceHolder("<path-to-token>").
+		StringVar(&kparser.bearerTokenFile)
+	app.Flag("bearer-token-cmd", "Shell command, which outputs "+
+		"the bearer token").
+		PlaceHolder("<command>").
+		StringVar(&kparser.bearerTokenCmd)
+	app.Flag("bearer-token-refresh", "How often to run "+
+		"--bearer-token-cmd to refresh the token").
+		PlaceHolder(defaultBearerTokenRefresh.String()).
+		DurationVar(&kparser.bearerRefresh)
+	app.Flag("hmac-secret", "Sign each request with HMAC-SHA256 "+
+		"using this secret, see docs for details").
+		PlaceHolder("<secret>").
+		StringVar(&kparser.hmacSecret)
+	app.Flag("hmac-header", "Header to send HMAC signature in "+
+		"(default: "+defaultHMACHeader+")").
+		PlaceHolder("<name>").
+		StringVar(&kparser.hmacHeader)
+	app.Flag("aws-sigv4", "Sign each request with AWS Signature V4 "+
+		"for the given region and service, credentials are taken "+
+		"from AWS_* environment variables").
+		PlaceHolder("<region:service>").
+		StringVar(&kparser.awsSigV4)
+	app.Flag("oauth2-token-url", "Obtain bearer token from this "+
+		"endpoint via OAuth 2.0 client credentials grant and refresh "+
+		"it before it expires").
+		PlaceHolder("<endpoint>").
+		StringVar(&kparser.oauth2TokenURL)
+	app.Flag("oauth2-client-id", "OAuth 2.0 client id").
+		PlaceHolder("<client-id>").
+		StringVar(&kparser.oauth2ClientID)
+	app.Flag("oauth2-client-secret", "OAuth 2.0 client secret").
+		PlaceHolder("<secret>").
+		StringVar(&kparser.oauth2Secret)
+	app.Flag("oauth2-scope", "Space-separated list of scopes to "+
+		"request").
+		PlaceHolder("<scope-list>").
+		StringVar(&kparser.oauth2Scope)
 	app.Flag("cert", "Path to the client's TLS Certificate").
 		Default("").
 		StringVar(&kparser.certPath)
@@ -108,9 +327,17 @@ func newKingpinParser() argsParser {
 		Short('k').
 		BoolVar(&kparser.insecure)
 	app.Flag("disableKeepAlives",
-		"Disable HTTP keep-alive. For fasthttp use -H 'Connection: close'").
+		"Disable HTTP keep-alive").
 		Short('a').
 		BoolVar(&kparser.disableKeepAlives)
+	app.Flag("tls-handshake",
+		"Benchmark TLS handshakes: use a fresh connection for every "+
+			"request and report handshake statistics").
+		BoolVar(&kparser.tlsHandshake)
+	app.Flag("tls-resume",
+		"Allow TLS session resumption via session tickets "+
+			"(only with --tls-handshake)").
+		BoolVar(&kparser.tlsResumption)
 
 	app.Flag("header", "HTTP headers to use(can be repeated)").
 		PlaceHolder("\"K: V\"").
@@ -129,6 +356,11 @@ func newKingpinParser() argsParser {
 		PlaceHolder("[pos. int.]").
 		Short('r').
 		SetValue(kparser.rate)
+	app.Flag("think-time", "Pause between requests of each "+
+		"connection: 500ms, 100ms-2s (uniform), exp:500ms "+
+		"(exponential) or file:<path>").
+		PlaceHolder("<spec>").
+		StringVar(&kparser.thinkTime)
 
 	app.Flag("fasthttp", "Use fasthttp client").
 		Action(func(*kingpin.ParseContext) error {
@@ -172,10 +404,26 @@ func newKingpinParser() argsParser {
 		" or \"path:C:\\some\\path\\to\\your.template\" in case of Windows. "+
 		"Formats understood by bombardier are:"+
 		"\n\t* plain-text (short: pt)"+
-		"\n\t* json (short: j)").
+		"\n\t* json (short: j)"+
+		"\n\t* html (short: h)"+
+		"\n\t* csv (short: c)"+
+		"\n\t* markdown (short: md)"+
+		"\n\t* junit (short: ju), with a testcase for each of the "+
+		"checks: no failed requests, no 4xx/5xx codes, no failed "+
+		"requests of each scenario step and of each request of the mix "+
+		"(latencies and throughput aren't checked)").
 		PlaceHolder("<spec>").
 		Short('o').
 		StringVar(&kparser.formatSpec)
+	app.Flag("no-header", "Don't print the header of csv format, "+
+		"e.g. to append the result to a file").
+		BoolVar(&kparser.noHeader)
+	app.Flag("output", "Also write the result to the file, e.g. "+
+		"format=json,file=result.json. Optionally followed by "+
+		",append=true to append to the file and ,header=false to omit "+
+		"the header of csv format. Can be repeated").
+		PlaceHolder("<spec>").
+		SetValue(kparser.outputs)
 
 	app.Arg("url", "Target's URL").Required().
 		StringVar(&kparser.url)
@@ -186,7 +434,7 @@ func newKingpinParser() argsParser {
 
 func (k *kingpinParser) parse(args []string) (config, error) {
 	k.app.Name = args[0]
-	_, err := k.app.Parse(args[1:])
+	_, err := k.app.Parse(expandOptionalValues(args[1:]))
 	if err != nil {
 		return emptyConf, err
 	}
@@ -206,35 +454,141 @@ func (k *kingpinParser) parse(args []string) (config, error) {
 			"unknown format or invalid format spec %q", k.formatSpec,
 		)
 	}
+	dataMode, err := feedModeFromString(k.dataMode)
+	if err != nil {
+		return emptyConf, err
+	}
 	url, err := urlx.Parse(k.url)
 	if err != nil {
 		return emptyConf, err
 	}
+	var form *formFields
+	if len(*k.form) > 0 {
+		form = k.form
+	}
+	var reports *reportList
+	if len(*k.reports) > 0 {
+		reports = k.reports
+	}
+	var reportTags *tagList
+	if len(*k.reportTags) > 0 {
+		reportTags = k.reportTags
+	}
+	var outputs *outputList
+	if len(*k.outputs) > 0 {
+		outputs = k.outputs
+	}
+	var percentiles *percentileList
+	if len(*k.percentiles) > 0 {
+		percentiles = k.percentiles
+	}
 	return config{
-		numConns:          k.numConns,
-		numReqs:           k.numReqs.val,
-		duration:          k.duration.val,
-		url:               url,
-		headers:           k.headers,
-		timeout:           k.timeout,
-		method:            k.method,
-		body:              k.body,
-		bodyFilePath:      k.bodyFilePath,
-		stream:            k.stream,
-		keyPath:           k.keyPath,
-		certPath:          k.certPath,
-		printLatencies:    k.latencies,
-		insecure:          k.insecure,
-		disableKeepAlives: k.disableKeepAlives,
-		rate:              k.rate.val,
-		clientType:        k.clientType,
-		printIntro:        pi,
-		printProgress:     pp,
-		printResult:       pr,
-		format:            format,
+		numConns:           k.numConns,
+		numReqs:            k.numReqs.val,
+		duration:           k.duration.val,
+		url:                url,
+		headers:            k.headers,
+		timeout:            k.timeout,
+		method:             k.method,
+		body:               k.body,
+		bodyFilePath:       k.bodyFilePath,
+		form:               form,
+		stream:             k.stream,
+		compressBody:       k.compressBody,
+		acceptEncoding:     k.acceptEncoding,
+		sampleResponses:    k.sampleResponses,
+		samplesDir:         k.samplesDir,
+		logRequestsPath:    k.logRequestsPath,
+		logSample:          k.logSample,
+		requestTemplates:   k.requestTemplates,
+		dataFilePath:       k.dataFilePath,
+		dataMode:           dataMode,
+		dataStopAtEOF:      k.dataOnEOF == "stop",
+		scenarioPath:       k.scenarioPath,
+		mixPath:            k.mixPath,
+		replayPath:         k.replayPath,
+		replayHeaders:      k.replayHeaders,
+		replaySpeed:        k.replaySpeed,
+		cookies:            k.cookies,
+		cookieFilePath:     k.cookieFilePath,
+		maxRedirects:       k.maxRedirects,
+		basicAuth:          k.basicAuth,
+		bearerTokenFile:    k.bearerTokenFile,
+		bearerTokenCmd:     k.bearerTokenCmd,
+		bearerTokenRefresh: k.bearerRefresh,
+		hmacSecret:         k.hmacSecret,
+		hmacHeader:         k.hmacHeader,
+		awsSigV4:           k.awsSigV4,
+		oauth2TokenURL:     k.oauth2TokenURL,
+		oauth2ClientID:     k.oauth2ClientID,
+		oauth2ClientSecret: k.oauth2Secret,
+		oauth2Scope:        k.oauth2Scope,
+		keyPath:            k.keyPath,
+		certPath:           k.certPath,
+		printLatencies:     k.latencies,
+		connStats:          k.connStats,
+		percentiles:        percentiles,
+		histogramDigits:    k.histogramDigits,
+		hdrLogPath:         k.hdrLogPath,
+		hdrLogInterval:     k.hdrLogInterval,
+		hgrmPath:           k.hgrmPath,
+		reports:            reports,
+		reportInterval:     k.reportInterval,
+		reportTags:         reportTags,
+		traceparent:        k.traceparent || k.otlpEndpoint != "",
+		otlpEndpoint:       k.otlpEndpoint,
+		traceSlowest:       k.traceSlowest,
+		insecure:           k.insecure,
+		disableKeepAlives:  k.disableKeepAlives,
+		tlsHandshake:       k.tlsHandshake,
+		tlsResumption:      k.tlsResumption,
+		rate:               k.rate.val,
+		thinkTime:          k.thinkTime,
+		clientType:         k.clientType,
+		printIntro:         pi,
+		printProgress:      pp,
+		printResult:        pr,
+		format:             format,
+		noHeader:           k.noHeader,
+		outputs:            outputs,
 	}, nil
 }
 
+const followRedirectsFlag = "follow-redirects"
+
+// optionalValues are the values of the flags, whose value may be
+// omitted, which are used in such case. kingpin has no notion of
+// optional values, so these are added before parsing.
+var optionalValues = map[string]string{
+	"--" + followRedirectsFlag: strconv.FormatUint(
+		defaultMaxRedirects, decBase,
+	),
+}
+
+func expandOptionalValues(args []string) []string {
+	expanded := make([]string, 0, len(args))
+	for i, arg := range args {
+		if arg == "--" {
+			return append(expanded, args[i:]...)
+		}
+		if value, ok := optionalValues[arg]; ok && !isUint(args, i+1) {
+			arg += "=" + value
+		}
+		expanded = append(expanded, arg)
+	}
+	return expanded
+}
+
+// isUint tells whether args[i] exists and is the value of the flag
+// preceding it rather than e.g. the URL.
+func isUint(args []string, i int) bool {
+	if i >= len(args) {
+		return false
+	}
+	_, err := strconv.ParseUint(args[i], decBase, 64)
+	return err == nil
+}
+
 func parsePrintSpec(spec string) (bool, bool, bool, error) {
 	pi, pp, pr := false, false, false
 	if spec == "" {
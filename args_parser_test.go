@@ -56,6 +56,7 @@ func TestArgsParsing(t *testing.T) {
 			},
 			config{
 				numConns:      defaultNumberOfConns,
+				logSample:     1,
 				timeout:       defaultTimeout,
 				headers:       new(headersList),
 				method:        "GET",
@@ -72,6 +73,7 @@ func TestArgsParsing(t *testing.T) {
 			},
 			config{
 				numConns:      defaultNumberOfConns,
+				logSample:     1,
 				timeout:       defaultTimeout,
 				headers:       new(headersList),
 				method:        "GET",
@@ -86,6 +88,7 @@ func TestArgsParsing(t *testing.T) {
 			[][]string{{programName, "https://somehost.somedomain"}},
 			config{
 				numConns:      defaultNumberOfConns,
+				logSample:     1,
 				timeout:       defaultTimeout,
 				headers:       new(headersList),
 				method:        "GET",
@@ -129,6 +132,7 @@ func TestArgsParsing(t *testing.T) {
 			},
 			config{
 				numConns:      10,
+				logSample:     1,
 				timeout:       10 * time.Second,
 				headers:       new(headersList),
 				method:        "GET",
@@ -155,6 +159,7 @@ func TestArgsParsing(t *testing.T) {
 			},
 			config{
 				numConns:       defaultNumberOfConns,
+				logSample:      1,
 				timeout:        defaultTimeout,
 				headers:        new(headersList),
 				printLatencies: true,
@@ -166,6 +171,28 @@ func TestArgsParsing(t *testing.T) {
 				format:         knownFormat("plain-text"),
 			},
 		},
+		{
+			[][]string{
+				{
+					programName,
+					"--conn-stats",
+					"https://somehost.somedomain",
+				},
+			},
+			config{
+				numConns:      defaultNumberOfConns,
+				logSample:     1,
+				timeout:       defaultTimeout,
+				headers:       new(headersList),
+				connStats:     true,
+				method:        "GET",
+				url:           ParseURLOrPanic("https://somehost.somedomain"),
+				printIntro:    true,
+				printProgress: true,
+				printResult:   true,
+				format:        knownFormat("plain-text"),
+			},
+		},
 		{
 			[][]string{
 				{
@@ -181,6 +208,7 @@ func TestArgsParsing(t *testing.T) {
 			},
 			config{
 				numConns:      defaultNumberOfConns,
+				logSample:     1,
 				timeout:       defaultTimeout,
 				headers:       new(headersList),
 				insecure:      true,
@@ -209,6 +237,7 @@ func TestArgsParsing(t *testing.T) {
 			},
 			config{
 				numConns:      defaultNumberOfConns,
+				logSample:     1,
 				timeout:       defaultTimeout,
 				headers:       new(headersList),
 				method:        "GET",
@@ -250,6 +279,7 @@ func TestArgsParsing(t *testing.T) {
 			},
 			config{
 				numConns:      defaultNumberOfConns,
+				logSample:     1,
 				timeout:       defaultTimeout,
 				headers:       new(headersList),
 				method:        "POST",
@@ -283,8 +313,9 @@ func TestArgsParsing(t *testing.T) {
 				},
 			},
 			config{
-				numConns: defaultNumberOfConns,
-				timeout:  defaultTimeout,
+				numConns:  defaultNumberOfConns,
+				logSample: 1,
+				timeout:   defaultTimeout,
 				headers: &headersList{
 					{"One", "Value one"},
 					{"Two", "Value two"},
@@ -322,6 +353,7 @@ func TestArgsParsing(t *testing.T) {
 			},
 			config{
 				numConns:      defaultNumberOfConns,
+				logSample:     1,
 				timeout:       defaultTimeout,
 				headers:       new(headersList),
 				method:        "GET",
@@ -347,6 +379,7 @@ func TestArgsParsing(t *testing.T) {
 			},
 			config{
 				numConns:      defaultNumberOfConns,
+				logSample:     1,
 				timeout:       defaultTimeout,
 				headers:       new(headersList),
 				method:        "GET",
@@ -368,6 +401,7 @@ func TestArgsParsing(t *testing.T) {
 			},
 			config{
 				numConns:      defaultNumberOfConns,
+				logSample:     1,
 				timeout:       defaultTimeout,
 				headers:       new(headersList),
 				method:        "GET",
@@ -389,6 +423,7 @@ func TestArgsParsing(t *testing.T) {
 			},
 			config{
 				numConns:      defaultNumberOfConns,
+				logSample:     1,
 				timeout:       defaultTimeout,
 				headers:       new(headersList),
 				method:        "GET",
@@ -420,6 +455,7 @@ func TestArgsParsing(t *testing.T) {
 			},
 			config{
 				numConns:      defaultNumberOfConns,
+				logSample:     1,
 				timeout:       defaultTimeout,
 				headers:       new(headersList),
 				method:        "GET",
@@ -446,6 +482,7 @@ func TestArgsParsing(t *testing.T) {
 			},
 			config{
 				numConns:      defaultNumberOfConns,
+				logSample:     1,
 				timeout:       defaultTimeout,
 				headers:       new(headersList),
 				method:        "GET",
@@ -466,6 +503,7 @@ func TestArgsParsing(t *testing.T) {
 			},
 			config{
 				numConns:      defaultNumberOfConns,
+				logSample:     1,
 				timeout:       defaultTimeout,
 				headers:       new(headersList),
 				method:        "GET",
@@ -511,6 +549,7 @@ func TestArgsParsing(t *testing.T) {
 			},
 			config{
 				numConns:      defaultNumberOfConns,
+				logSample:     1,
 				timeout:       defaultTimeout,
 				headers:       new(headersList),
 				method:        "GET",
@@ -556,6 +595,7 @@ func TestArgsParsing(t *testing.T) {
 			},
 			config{
 				numConns:      defaultNumberOfConns,
+				logSample:     1,
 				timeout:       defaultTimeout,
 				headers:       new(headersList),
 				method:        "GET",
@@ -566,6 +606,498 @@ func TestArgsParsing(t *testing.T) {
 				format:        knownFormat("plain-text"),
 			},
 		},
+		{
+			[][]string{
+				{
+					programName,
+					"--request-templates",
+					"https://somehost.somedomain/{{ .Counter }}",
+				},
+			},
+			config{
+				numConns:         defaultNumberOfConns,
+				logSample:        1,
+				timeout:          defaultTimeout,
+				headers:          new(headersList),
+				method:           "GET",
+				url:              ParseURLOrPanic("https://somehost.somedomain/{{ .Counter }}"),
+				requestTemplates: true,
+				printIntro:       true,
+				printProgress:    true,
+				printResult:      true,
+				format:           knownFormat("plain-text"),
+			},
+		},
+		{
+			[][]string{
+				{
+					programName,
+					"--follow-redirects",
+					"https://somehost.somedomain",
+				},
+				{
+					programName,
+					"--follow-redirects=10",
+					"https://somehost.somedomain",
+				},
+				{
+					programName,
+					"--follow-redirects", "--",
+					"https://somehost.somedomain",
+				},
+			},
+			config{
+				numConns:      defaultNumberOfConns,
+				logSample:     1,
+				timeout:       defaultTimeout,
+				headers:       new(headersList),
+				method:        "GET",
+				url:           ParseURLOrPanic("https://somehost.somedomain"),
+				maxRedirects:  defaultMaxRedirects,
+				printIntro:    true,
+				printProgress: true,
+				printResult:   true,
+				format:        knownFormat("plain-text"),
+			},
+		},
+		{
+			[][]string{
+				{
+					programName,
+					"--bearer-token-cmd", "echo token",
+					"--bearer-token-refresh", "1m",
+					"--hmac-secret", "secret",
+					"--hmac-header", "X-Sig",
+					"https://somehost.somedomain",
+				},
+			},
+			config{
+				numConns:           defaultNumberOfConns,
+				logSample:          1,
+				timeout:            defaultTimeout,
+				headers:            new(headersList),
+				method:             "GET",
+				url:                ParseURLOrPanic("https://somehost.somedomain"),
+				bearerTokenCmd:     "echo token",
+				bearerTokenRefresh: time.Minute,
+				hmacSecret:         "secret",
+				hmacHeader:         "X-Sig",
+				printIntro:         true,
+				printProgress:      true,
+				printResult:        true,
+				format:             knownFormat("plain-text"),
+			},
+		},
+		{
+			[][]string{
+				{
+					programName,
+					"--form", "name=value",
+					"--form=file=@/some/path",
+					"-m", "POST",
+					"https://somehost.somedomain",
+				},
+			},
+			config{
+				numConns:  defaultNumberOfConns,
+				logSample: 1,
+				timeout:   defaultTimeout,
+				headers:   new(headersList),
+				method:    "POST",
+				url:       ParseURLOrPanic("https://somehost.somedomain"),
+				form: &formFields{
+					{name: "name", value: "value"},
+					{name: "file", value: "/some/path", file: true},
+				},
+				printIntro:    true,
+				printProgress: true,
+				printResult:   true,
+				format:        knownFormat("plain-text"),
+			},
+		},
+		{
+			[][]string{
+				{
+					programName,
+					"--percentiles", "50,99.9",
+					"--histogram-digits", "2",
+					"https://somehost.somedomain",
+				},
+				{
+					programName,
+					"--percentiles=99.9,50",
+					"--histogram-digits=2",
+					"https://somehost.somedomain",
+				},
+			},
+			config{
+				numConns:        defaultNumberOfConns,
+				logSample:       1,
+				timeout:         defaultTimeout,
+				headers:         new(headersList),
+				method:          "GET",
+				url:             ParseURLOrPanic("https://somehost.somedomain"),
+				percentiles:     &percentileList{0.5, 0.999},
+				histogramDigits: 2,
+				printIntro:      true,
+				printProgress:   true,
+				printResult:     true,
+				format:          knownFormat("plain-text"),
+			},
+		},
+		{
+			[][]string{
+				{
+					programName,
+					"--hdr-log", "latencies.hlog",
+					"--hdr-log-interval", "1s",
+					"--hgrm", "latencies.hgrm",
+					"https://somehost.somedomain",
+				},
+				{
+					programName,
+					"--hdr-log=latencies.hlog",
+					"--hdr-log-interval=1s",
+					"--hgrm=latencies.hgrm",
+					"https://somehost.somedomain",
+				},
+			},
+			config{
+				numConns:       defaultNumberOfConns,
+				logSample:      1,
+				timeout:        defaultTimeout,
+				headers:        new(headersList),
+				method:         "GET",
+				url:            ParseURLOrPanic("https://somehost.somedomain"),
+				hdrLogPath:     "latencies.hlog",
+				hdrLogInterval: time.Second,
+				hgrmPath:       "latencies.hgrm",
+				printIntro:     true,
+				printProgress:  true,
+				printResult:    true,
+				format:         knownFormat("plain-text"),
+			},
+		},
+		{
+			[][]string{
+				{
+					programName,
+					"--report", "statsd:localhost:8125",
+					"--report", "influxdb:http://localhost:8086/write?db=b",
+					"--report-interval", "5s",
+					"--report-tag", "test=checkout",
+					"--report-tag", "sha=abc",
+					"https://somehost.somedomain",
+				},
+				{
+					programName,
+					"--report=statsd:localhost:8125",
+					"--report=influxdb:http://localhost:8086/write?db=b",
+					"--report-interval=5s",
+					"--report-tag=test=checkout",
+					"--report-tag=sha=abc",
+					"https://somehost.somedomain",
+				},
+			},
+			config{
+				numConns:  defaultNumberOfConns,
+				logSample: 1,
+				timeout:   defaultTimeout,
+				headers:   new(headersList),
+				method:    "GET",
+				url:       ParseURLOrPanic("https://somehost.somedomain"),
+				reports: &reportList{
+					{"statsd", "localhost:8125"},
+					{"influxdb", "http://localhost:8086/write?db=b"},
+				},
+				reportInterval: 5 * time.Second,
+				reportTags:     &tagList{{"test", "checkout"}, {"sha", "abc"}},
+				printIntro:     true,
+				printProgress:  true,
+				printResult:    true,
+				format:         knownFormat("plain-text"),
+			},
+		},
+		{
+			[][]string{
+				{
+					programName,
+					"--otlp-endpoint", "http://localhost:4318",
+					"--trace-slowest", "5",
+					"https://somehost.somedomain",
+				},
+				{
+					programName,
+					"--otlp-endpoint=http://localhost:4318",
+					"--trace-slowest=5",
+					"https://somehost.somedomain",
+				},
+			},
+			config{
+				numConns:      defaultNumberOfConns,
+				logSample:     1,
+				timeout:       defaultTimeout,
+				headers:       new(headersList),
+				method:        "GET",
+				url:           ParseURLOrPanic("https://somehost.somedomain"),
+				traceparent:   true,
+				otlpEndpoint:  "http://localhost:4318",
+				traceSlowest:  5,
+				printIntro:    true,
+				printProgress: true,
+				printResult:   true,
+				format:        knownFormat("plain-text"),
+			},
+		},
+		{
+			[][]string{
+				{
+					programName,
+					"--traceparent",
+					"https://somehost.somedomain",
+				},
+			},
+			config{
+				numConns:      defaultNumberOfConns,
+				logSample:     1,
+				timeout:       defaultTimeout,
+				headers:       new(headersList),
+				method:        "GET",
+				url:           ParseURLOrPanic("https://somehost.somedomain"),
+				traceparent:   true,
+				printIntro:    true,
+				printProgress: true,
+				printResult:   true,
+				format:        knownFormat("plain-text"),
+			},
+		},
+		{
+			[][]string{
+				{
+					programName,
+					"--think-time", "100ms-2s",
+					"https://somehost.somedomain",
+				},
+				{
+					programName,
+					"--think-time=100ms-2s",
+					"https://somehost.somedomain",
+				},
+			},
+			config{
+				numConns:      defaultNumberOfConns,
+				logSample:     1,
+				timeout:       defaultTimeout,
+				headers:       new(headersList),
+				method:        "GET",
+				url:           ParseURLOrPanic("https://somehost.somedomain"),
+				thinkTime:     "100ms-2s",
+				printIntro:    true,
+				printProgress: true,
+				printResult:   true,
+				format:        knownFormat("plain-text"),
+			},
+		},
+		{
+			[][]string{
+				{
+					programName,
+					"--mix", "mix.json",
+					"https://somehost.somedomain",
+				},
+				{
+					programName,
+					"--mix=mix.json",
+					"https://somehost.somedomain",
+				},
+			},
+			config{
+				numConns:      defaultNumberOfConns,
+				logSample:     1,
+				timeout:       defaultTimeout,
+				headers:       new(headersList),
+				method:        "GET",
+				url:           ParseURLOrPanic("https://somehost.somedomain"),
+				mixPath:       "mix.json",
+				printIntro:    true,
+				printProgress: true,
+				printResult:   true,
+				format:        knownFormat("plain-text"),
+			},
+		},
+		{
+			[][]string{
+				{
+					programName,
+					"--replay", "access.log",
+					"--replay-headers", "User-Agent,Referer",
+					"--replay-speed", "2.5",
+					"https://somehost.somedomain",
+				},
+				{
+					programName,
+					"--replay=access.log",
+					"--replay-headers=User-Agent,Referer",
+					"--replay-speed=2.5",
+					"https://somehost.somedomain",
+				},
+			},
+			config{
+				numConns:      defaultNumberOfConns,
+				logSample:     1,
+				timeout:       defaultTimeout,
+				headers:       new(headersList),
+				method:        "GET",
+				url:           ParseURLOrPanic("https://somehost.somedomain"),
+				replayPath:    "access.log",
+				replayHeaders: "User-Agent,Referer",
+				replaySpeed:   2.5,
+				printIntro:    true,
+				printProgress: true,
+				printResult:   true,
+				format:        knownFormat("plain-text"),
+			},
+		},
+		{
+			[][]string{
+				{
+					programName,
+					"--log-requests", "requests.jsonl",
+					"--log-sample", "0.01",
+					"https://somehost.somedomain",
+				},
+				{
+					programName,
+					"--log-requests=requests.jsonl",
+					"--log-sample=0.01",
+					"https://somehost.somedomain",
+				},
+			},
+			config{
+				numConns:        defaultNumberOfConns,
+				timeout:         defaultTimeout,
+				headers:         new(headersList),
+				method:          "GET",
+				url:             ParseURLOrPanic("https://somehost.somedomain"),
+				logRequestsPath: "requests.jsonl",
+				logSample:       0.01,
+				printIntro:      true,
+				printProgress:   true,
+				printResult:     true,
+				format:          knownFormat("plain-text"),
+			},
+		},
+		{
+			[][]string{
+				{
+					programName,
+					"--sample-responses", "5",
+					"--samples-dir", "/tmp/samples",
+					"https://somehost.somedomain",
+				},
+				{
+					programName,
+					"--sample-responses=5",
+					"--samples-dir=/tmp/samples",
+					"https://somehost.somedomain",
+				},
+			},
+			config{
+				numConns:        defaultNumberOfConns,
+				logSample:       1,
+				timeout:         defaultTimeout,
+				headers:         new(headersList),
+				method:          "GET",
+				url:             ParseURLOrPanic("https://somehost.somedomain"),
+				sampleResponses: 5,
+				samplesDir:      "/tmp/samples",
+				printIntro:      true,
+				printProgress:   true,
+				printResult:     true,
+				format:          knownFormat("plain-text"),
+			},
+		},
+		{
+			[][]string{
+				{
+					programName,
+					"--compress-body", "zstd",
+					"--accept-encoding", "gzip, br",
+					"https://somehost.somedomain",
+				},
+				{
+					programName,
+					"--compress-body=zstd",
+					"--accept-encoding=gzip, br",
+					"https://somehost.somedomain",
+				},
+			},
+			config{
+				numConns:       defaultNumberOfConns,
+				logSample:      1,
+				timeout:        defaultTimeout,
+				headers:        new(headersList),
+				method:         "GET",
+				url:            ParseURLOrPanic("https://somehost.somedomain"),
+				compressBody:   "zstd",
+				acceptEncoding: "gzip, br",
+				printIntro:     true,
+				printProgress:  true,
+				printResult:    true,
+				format:         knownFormat("plain-text"),
+			},
+		},
+		{
+			[][]string{
+				{
+					programName,
+					"--follow-redirects=3",
+					"https://somehost.somedomain",
+				},
+				{
+					programName,
+					"--follow-redirects", "3",
+					"https://somehost.somedomain",
+				},
+			},
+			config{
+				numConns:      defaultNumberOfConns,
+				logSample:     1,
+				timeout:       defaultTimeout,
+				headers:       new(headersList),
+				method:        "GET",
+				url:           ParseURLOrPanic("https://somehost.somedomain"),
+				maxRedirects:  3,
+				printIntro:    true,
+				printProgress: true,
+				printResult:   true,
+				format:        knownFormat("plain-text"),
+			},
+		},
+		{
+			[][]string{
+				{
+					programName,
+					"--tls-handshake",
+					"--tls-resume",
+					"https://somehost.somedomain",
+				},
+			},
+			config{
+				numConns:      defaultNumberOfConns,
+				logSample:     1,
+				timeout:       defaultTimeout,
+				headers:       new(headersList),
+				method:        "GET",
+				url:           ParseURLOrPanic("https://somehost.somedomain"),
+				tlsHandshake:  true,
+				tlsResumption: true,
+				printIntro:    true,
+				printProgress: true,
+				printResult:   true,
+				format:        knownFormat("plain-text"),
+			},
+		},
 		{
 			[][]string{
 				{
@@ -581,6 +1113,7 @@ func TestArgsParsing(t *testing.T) {
 			},
 			config{
 				numConns:      defaultNumberOfConns,
+				logSample:     1,
 				timeout:       defaultTimeout,
 				headers:       new(headersList),
 				method:        "GET",
@@ -626,6 +1159,7 @@ func TestArgsParsing(t *testing.T) {
 			},
 			config{
 				numConns:      defaultNumberOfConns,
+				logSample:     1,
 				timeout:       defaultTimeout,
 				headers:       new(headersList),
 				method:        "GET",
@@ -671,6 +1205,7 @@ func TestArgsParsing(t *testing.T) {
 			},
 			config{
 				numConns:      defaultNumberOfConns,
+				logSample:     1,
 				timeout:       defaultTimeout,
 				headers:       new(headersList),
 				method:        "GET",
@@ -681,6 +1216,172 @@ func TestArgsParsing(t *testing.T) {
 				format:        knownFormat("json"),
 			},
 		},
+		{
+			[][]string{
+				{
+					programName,
+					"--format", "html",
+					"https://somehost.somedomain",
+				},
+				{
+					programName,
+					"--format=h",
+					"https://somehost.somedomain",
+				},
+				{
+					programName,
+					"-o", "html",
+					"https://somehost.somedomain",
+				},
+				{
+					programName,
+					"-o", "h",
+					"https://somehost.somedomain",
+				},
+			},
+			config{
+				numConns:      defaultNumberOfConns,
+				logSample:     1,
+				timeout:       defaultTimeout,
+				headers:       new(headersList),
+				method:        "GET",
+				url:           ParseURLOrPanic("https://somehost.somedomain"),
+				printIntro:    true,
+				printProgress: true,
+				printResult:   true,
+				format:        knownFormat("html"),
+			},
+		},
+		{
+			[][]string{
+				{
+					programName,
+					"--format", "csv",
+					"https://somehost.somedomain",
+				},
+				{
+					programName,
+					"-o", "c",
+					"https://somehost.somedomain",
+				},
+			},
+			config{
+				numConns:      defaultNumberOfConns,
+				logSample:     1,
+				timeout:       defaultTimeout,
+				headers:       new(headersList),
+				method:        "GET",
+				url:           ParseURLOrPanic("https://somehost.somedomain"),
+				printIntro:    true,
+				printProgress: true,
+				printResult:   true,
+				format:        knownFormat("csv"),
+			},
+		},
+		{
+			[][]string{
+				{
+					programName,
+					"--format", "markdown",
+					"https://somehost.somedomain",
+				},
+				{
+					programName,
+					"-o", "md",
+					"https://somehost.somedomain",
+				},
+			},
+			config{
+				numConns:      defaultNumberOfConns,
+				logSample:     1,
+				timeout:       defaultTimeout,
+				headers:       new(headersList),
+				method:        "GET",
+				url:           ParseURLOrPanic("https://somehost.somedomain"),
+				printIntro:    true,
+				printProgress: true,
+				printResult:   true,
+				format:        knownFormat("markdown"),
+			},
+		},
+		{
+			[][]string{
+				{
+					programName,
+					"--format", "junit",
+					"https://somehost.somedomain",
+				},
+				{
+					programName,
+					"-o", "ju",
+					"https://somehost.somedomain",
+				},
+			},
+			config{
+				numConns:      defaultNumberOfConns,
+				logSample:     1,
+				timeout:       defaultTimeout,
+				headers:       new(headersList),
+				method:        "GET",
+				url:           ParseURLOrPanic("https://somehost.somedomain"),
+				printIntro:    true,
+				printProgress: true,
+				printResult:   true,
+				format:        knownFormat("junit"),
+			},
+		},
+		{
+			[][]string{
+				{
+					programName,
+					"-o", "c", "--no-header",
+					"https://somehost.somedomain",
+				},
+			},
+			config{
+				numConns:      defaultNumberOfConns,
+				logSample:     1,
+				timeout:       defaultTimeout,
+				headers:       new(headersList),
+				method:        "GET",
+				url:           ParseURLOrPanic("https://somehost.somedomain"),
+				printIntro:    true,
+				printProgress: true,
+				printResult:   true,
+				format:        knownFormat("csv"),
+				noHeader:      true,
+			},
+		},
+		{
+			[][]string{
+				{
+					programName,
+					"--output", "format=json,file=result.json",
+					"--output=format=c,file=results.csv,append=true",
+					"https://somehost.somedomain",
+				},
+			},
+			config{
+				numConns:      defaultNumberOfConns,
+				logSample:     1,
+				timeout:       defaultTimeout,
+				headers:       new(headersList),
+				method:        "GET",
+				url:           ParseURLOrPanic("https://somehost.somedomain"),
+				printIntro:    true,
+				printProgress: true,
+				printResult:   true,
+				format:        knownFormat("plain-text"),
+				outputs: &outputList{
+					{format: knownFormat("json"), path: "result.json"},
+					{
+						format: knownFormat("csv"),
+						path:   "results.csv",
+						append: true,
+					},
+				},
+			},
+		},
 		{
 			[][]string{
 				{
@@ -701,6 +1402,7 @@ func TestArgsParsing(t *testing.T) {
 			},
 			config{
 				numConns:      defaultNumberOfConns,
+				logSample:     1,
 				timeout:       defaultTimeout,
 				headers:       new(headersList),
 				method:        "GET",
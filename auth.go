@@ -0,0 +1,423 @@
+package main
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"os/exec"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	defaultBearerTokenRefresh = 5 * time.Minute
+	tokenFilePollInterval     = 1 * time.Second
+
+	defaultHMACHeader   = "X-Signature"
+	hmacTimestampHeader = "X-Timestamp"
+
+	awsV4Algorithm  = "AWS4-HMAC-SHA256"
+	awsV4TimeFormat = "20060102T150405Z"
+	awsV4DateFormat = "20060102"
+)
+
+// authRequest is the request about to be sent, as seen by
+// authorizers.
+type authRequest struct {
+	method string
+	host   string
+	url    *url.URL
+	body   []byte
+}
+
+// requestAuthorizer computes authentication headers for each request.
+// Signing authorizers use the actual method, URL and body, so they
+// have to be invoked after all parts of the request are prepared.
+type requestAuthorizer interface {
+	authorize(r *authRequest) ([]header, error)
+	// headerNames returns the names of headers authorize sets.
+	headerNames() []string
+}
+
+// authorizers applies several authorizers in order.
+type authorizers []requestAuthorizer
+
+func (as authorizers) authorize(r *authRequest) ([]header, error) {
+	var headers []header
+	for _, a := range as {
+		hs, err := a.authorize(r)
+		if err != nil {
+			return nil, err
+		}
+		headers = append(headers, hs...)
+	}
+	return headers, nil
+}
+
+func (as authorizers) headerNames() []string {
+	var names []string
+	for _, a := range as {
+		names = append(names, a.headerNames()...)
+	}
+	return names
+}
+
+// staticAuth sends the same Authorization header with every request.
+type staticAuth string
+
+func newBasicAuth(credentials string) staticAuth {
+	return staticAuth(
+		"Basic " + base64.StdEncoding.EncodeToString([]byte(credentials)),
+	)
+}
+
+func (s staticAuth) authorize(*authRequest) ([]header, error) {
+	return []header{{"Authorization", string(s)}}, nil
+}
+
+func (s staticAuth) headerNames() []string {
+	return []string{"Authorization"}
+}
+
+// bearerToken is a bearer token, which may be refreshed in the
+// background while the test is running.
+type bearerToken struct {
+	value atomic.Value
+
+	// failures is the number of failed refreshes, the last known
+	// token is used until the next successful one.
+	failures uint64
+
+	done chan struct{}
+}
+
+func newBearerToken(token string) *bearerToken {
+	t := &bearerToken{done: make(chan struct{})}
+	t.set(token)
+	return t
+}
+
+// newFileBearerToken reads the token from the file at path and re-reads
+// it whenever the file changes.
+func newFileBearerToken(path string) (*bearerToken, error) {
+	fi, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	token, err := readToken(path)
+	if err != nil {
+		return nil, err
+	}
+	t := newBearerToken(token)
+	modTime, size := fi.ModTime(), fi.Size()
+	go t.refreshEvery(tokenFilePollInterval, func() error {
+		fi, err := os.Stat(path)
+		if err != nil {
+			return err
+		}
+		if fi.ModTime().Equal(modTime) && fi.Size() == size {
+			return nil
+		}
+		token, err := readToken(path)
+		if err != nil {
+			return err
+		}
+		modTime, size = fi.ModTime(), fi.Size()
+		t.set(token)
+		return nil
+	})
+	return t, nil
+}
+
+// newCmdBearerToken uses the output of the shell command as the token
+// and runs the command again every interval.
+func newCmdBearerToken(
+	cmd string, interval time.Duration,
+) (*bearerToken, error) {
+	token, err := runTokenCmd(cmd)
+	if err != nil {
+		return nil, err
+	}
+	t := newBearerToken(token)
+	go t.refreshEvery(interval, func() error {
+		token, err := runTokenCmd(cmd)
+		if err != nil {
+			return err
+		}
+		t.set(token)
+		return nil
+	})
+	return t, nil
+}
+
+func readToken(path string) (string, error) {
+	bytes, err := ioutil.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	return parseToken(bytes)
+}
+
+func runTokenCmd(cmd string) (string, error) {
+	shell, flag := "sh", "-c"
+	if runtime.GOOS == "windows" {
+		shell, flag = "cmd", "/C"
+	}
+	out, err := exec.Command(shell, flag, cmd).Output()
+	if err != nil {
+		return "", err
+	}
+	return parseToken(out)
+}
+
+func parseToken(bytes []byte) (string, error) {
+	token := strings.TrimSpace(string(bytes))
+	if token == "" {
+		return "", errEmptyToken
+	}
+	return token, nil
+}
+
+func (t *bearerToken) set(token string) {
+	t.value.Store(token)
+}
+
+func (t *bearerToken) get() string {
+	return t.value.Load().(string)
+}
+
+func (t *bearerToken) refreshEvery(
+	interval time.Duration, refresh func() error,
+) {
+	t.keepRefreshed(interval, func() (time.Duration, error) {
+		return interval, refresh()
+	})
+}
+
+// keepRefreshed calls refresh after delay and then again after the
+// delay returned by refresh, until the token is closed.
+func (t *bearerToken) keepRefreshed(
+	delay time.Duration, refresh func() (time.Duration, error),
+) {
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	for {
+		select {
+		case <-timer.C:
+			next, err := refresh()
+			if err != nil {
+				atomic.AddUint64(&t.failures, 1)
+			}
+			timer.Reset(next)
+		case <-t.done:
+			return
+		}
+	}
+}
+
+func (t *bearerToken) refreshFailures() uint64 {
+	return atomic.LoadUint64(&t.failures)
+}
+
+func (t *bearerToken) close() {
+	close(t.done)
+}
+
+func (t *bearerToken) authorize(*authRequest) ([]header, error) {
+	return []header{{"Authorization", "Bearer " + t.get()}}, nil
+}
+
+func (t *bearerToken) headerNames() []string {
+	return []string{"Authorization"}
+}
+
+// hmacSigner signs requests with HMAC-SHA256 over
+//
+//	METHOD "\n" REQUEST-URI "\n" UNIX-TIMESTAMP "\n" HEX(SHA256(BODY))
+//
+// and sends hex-encoded signature alongside with the timestamp.
+type hmacSigner struct {
+	secret []byte
+	header string
+	now    func() time.Time
+}
+
+func newHMACSigner(secret, header string) *hmacSigner {
+	if header == "" {
+		header = defaultHMACHeader
+	}
+	return &hmacSigner{
+		secret: []byte(secret),
+		header: header,
+		now:    time.Now,
+	}
+}
+
+func (h *hmacSigner) authorize(r *authRequest) ([]header, error) {
+	timestamp := strconv.FormatInt(h.now().Unix(), decBase)
+	mac := hmac.New(sha256.New, h.secret)
+	mac.Write([]byte(r.method + "\n" + r.url.RequestURI() + "\n" +
+		timestamp + "\n" + sha256Hex(r.body)))
+	return []header{
+		{hmacTimestampHeader, timestamp},
+		{h.header, hex.EncodeToString(mac.Sum(nil))},
+	}, nil
+}
+
+func (h *hmacSigner) headerNames() []string {
+	return []string{hmacTimestampHeader, h.header}
+}
+
+// awsV4Signer signs requests with AWS Signature Version 4.
+type awsV4Signer struct {
+	accessKey, secretKey, sessionToken string
+	region, service                    string
+	now                                func() time.Time
+}
+
+// newAWSV4Signer takes "region:service" spec and credentials from the
+// standard AWS environment variables.
+func newAWSV4Signer(spec string) (*awsV4Signer, error) {
+	region, service, ok := strings.Cut(spec, ":")
+	if !ok || region == "" || service == "" {
+		return nil, errInvalidAWSSigV4
+	}
+	s := &awsV4Signer{
+		accessKey:    os.Getenv("AWS_ACCESS_KEY_ID"),
+		secretKey:    os.Getenv("AWS_SECRET_ACCESS_KEY"),
+		sessionToken: os.Getenv("AWS_SESSION_TOKEN"),
+		region:       region,
+		service:      service,
+		now:          time.Now,
+	}
+	if s.accessKey == "" || s.secretKey == "" {
+		return nil, errNoAWSCredentials
+	}
+	return s, nil
+}
+
+func (s *awsV4Signer) authorize(r *authRequest) ([]header, error) {
+	now := s.now().UTC()
+	amzDate := now.Format(awsV4TimeFormat)
+	payloadHash := sha256Hex(r.body)
+
+	headers := []header{{"X-Amz-Date", amzDate}}
+	if s.service == "s3" {
+		headers = append(headers, header{"X-Amz-Content-Sha256", payloadHash})
+	}
+	if s.sessionToken != "" {
+		headers = append(headers, header{"X-Amz-Security-Token", s.sessionToken})
+	}
+
+	signed := append([]header{{"host", r.host}}, headers...)
+	for i := range signed {
+		signed[i].key = strings.ToLower(signed[i].key)
+	}
+	sort.Slice(signed, func(i, j int) bool {
+		return signed[i].key < signed[j].key
+	})
+	var canonicalHeaders strings.Builder
+	signedHeaders := make([]string, 0, len(signed))
+	for _, h := range signed {
+		canonicalHeaders.WriteString(h.key + ":" + strings.TrimSpace(h.value) + "\n")
+		signedHeaders = append(signedHeaders, h.key)
+	}
+
+	// All services, but S3, expect the path that is sent (which is
+	// already escaped) to be encoded once again.
+	path := r.url.EscapedPath()
+	if s.service == "s3" {
+		path = r.url.Path
+	}
+	canonicalRequest := strings.Join([]string{
+		r.method,
+		awsV4CanonicalURI(path),
+		awsV4CanonicalQuery(r.url.Query()),
+		canonicalHeaders.String(),
+		strings.Join(signedHeaders, ";"),
+		payloadHash,
+	}, "\n")
+
+	date := now.Format(awsV4DateFormat)
+	scope := date + "/" + s.region + "/" + s.service + "/aws4_request"
+	stringToSign := strings.Join([]string{
+		awsV4Algorithm, amzDate, scope,
+		sha256Hex([]byte(canonicalRequest)),
+	}, "\n")
+
+	key := hmacSHA256([]byte("AWS4"+s.secretKey), date)
+	key = hmacSHA256(key, s.region)
+	key = hmacSHA256(key, s.service)
+	key = hmacSHA256(key, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	return append(headers, header{
+		"Authorization",
+		awsV4Algorithm + " Credential=" + s.accessKey + "/" + scope +
+			", SignedHeaders=" + strings.Join(signedHeaders, ";") +
+			", Signature=" + signature,
+	}), nil
+}
+
+func (s *awsV4Signer) headerNames() []string {
+	return []string{
+		"X-Amz-Date", "X-Amz-Content-Sha256", "X-Amz-Security-Token",
+		"Authorization",
+	}
+}
+
+// awsV4CanonicalURI percent-encodes each segment of the path.
+func awsV4CanonicalURI(path string) string {
+	if path == "" {
+		return "/"
+	}
+	segments := strings.Split(path, "/")
+	for i, s := range segments {
+		segments[i] = awsV4Escape(s)
+	}
+	return strings.Join(segments, "/")
+}
+
+func awsV4CanonicalQuery(query url.Values) string {
+	pairs := make([]header, 0, len(query))
+	for k, vs := range query {
+		for _, v := range vs {
+			pairs = append(pairs, header{awsV4Escape(k), awsV4Escape(v)})
+		}
+	}
+	sort.Slice(pairs, func(i, j int) bool {
+		if pairs[i].key != pairs[j].key {
+			return pairs[i].key < pairs[j].key
+		}
+		return pairs[i].value < pairs[j].value
+	})
+	encoded := make([]string, len(pairs))
+	for i, p := range pairs {
+		encoded[i] = p.key + "=" + p.value
+	}
+	return strings.Join(encoded, "&")
+}
+
+// awsV4Escape percent-encodes everything, but unreserved characters.
+func awsV4Escape(s string) string {
+	return strings.ReplaceAll(url.QueryEscape(s), "+", "%20")
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+func sha256Hex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
@@ -1,6 +1,8 @@
 package main
 
 import (
+	"crypto/tls"
+	"encoding/json"
 	"fmt"
 	"io"
 	"io/ioutil"
@@ -34,29 +36,83 @@ type bombardier struct {
 	conf        config
 	barrier     completionBarrier
 	ratelimiter limiter
+	think       *thinkTime
 	wg          sync.WaitGroup
 
 	timeTaken time.Duration
-	latencies *uhist.Histogram
+	latencies *hdrHistogram
 	requests  *fhist.Histogram
 
 	client   client
 	doneChan chan struct{}
 
+	// busy is the total time (in microseconds) connections spent
+	// waiting for responses, only counted with think time.
+	busy uint64
+
 	// RPS metrics
-	rpl   sync.Mutex
-	reqs  int64
-	start time.Time
+	rpl      sync.Mutex
+	reqs     int64
+	start    time.Time
+	timeline *timeline
 
 	// Errors
 	errors *errorMap
 
+	// TLS handshakes
+	handshakes *handshakeStats
+
+	// Connections opened by the client
+	conns *connStats
+
+	// Data file
+	feeder *dataFeeder
+
+	// Scenario
+	scenario *scenario
+
+	// Weighted request mix
+	mix *mix
+
+	// Replayed requests
+	replay *replay
+
+	// Cookies used to seed cookie jars of the sessions
+	seedCookies []seedCookie
+
+	// Number of redirects followed by requests
+	redirects *uhist.Histogram
+
+	// Bearer token refreshed in the background, if any
+	bearer *bearerToken
+
+	// Sizes of response bodies
+	bodies *bodyStats
+	sizes  *hdrHistogram
+
+	// Saves first responses with each status code, if any
+	sampler *responseSampler
+
+	// Request log, if any
+	reqLog *requestLogger
+
+	// Latency histograms in HdrHistogram formats, if requested
+	hdrLog *hdrLog
+	hgrm   *os.File
+
+	// Metrics sent during the test, if any
+	reports *metricsReports
+
+	// Trace context propagation and span export, if enabled
+	tracer *tracer
+
 	// Progress bar
 	bar *pb.ProgressBar
 
 	// Output
 	out      io.Writer
 	template *template.Template
+	outputs  []*resultOutput
 }
 
 func newBombardier(c config) (*bombardier, error) {
@@ -65,8 +121,10 @@ func newBombardier(c config) (*bombardier, error) {
 	}
 	b := new(bombardier)
 	b.conf = c
-	b.latencies = uhist.Default()
+	b.latencies = newHDRHistogram(c.histogramDigits)
 	b.requests = fhist.Default()
+	b.sizes = newHDRHistogram(c.histogramDigits)
+	b.timeline = newTimeline()
 
 	if b.conf.testType() == counted {
 		b.bar = pb.New64(int64(*b.conf.numReqs))
@@ -90,18 +148,62 @@ func newBombardier(c config) (*bombardier, error) {
 		b.ratelimiter = &nooplimiter{}
 	}
 
+	if c.thinkTime != "" {
+		var err error
+		b.think, err = parseThinkTime(c.thinkTime)
+		if err != nil {
+			return nil, err
+		}
+	}
+
 	b.out = os.Stdout
 
+	if c.tlsHandshake {
+		b.handshakes = newHandshakeStats(c.histogramDigits)
+	}
+	if c.connStats {
+		b.conns = newConnStats(c.histogramDigits)
+	}
+
+	if c.maxRedirects > 0 {
+		b.redirects = uhist.Default()
+	}
+
+	if c.cookieFilePath != "" {
+		var err error
+		b.seedCookies, err = readNetscapeCookies(c.cookieFilePath)
+		if err != nil {
+			return nil, err
+		}
+	}
+
 	tlsConfig, err := generateTLSConfig(c)
 	if err != nil {
 		return nil, err
 	}
 
 	var (
-		pbody *string
-		bsp   bodyStreamProducer
+		pbody   *string
+		bsp     bodyStreamProducer
+		headers = c.headers
 	)
-	if c.stream {
+	if c.form != nil {
+		form, err := newFormBody(*c.form)
+		if err != nil {
+			return nil, err
+		}
+		headers = withHeader(c.headers, "Content-Type", form.contentType())
+		if c.stream {
+			bsp = form.stream
+		} else {
+			bodyBytes, err := form.bytes()
+			if err != nil {
+				return nil, err
+			}
+			sbody := string(bodyBytes)
+			pbody = &sbody
+		}
+	} else if c.stream {
 		if c.bodyFilePath != "" {
 			bsp = func() (io.ReadCloser, error) {
 				return os.Open(c.bodyFilePath)
@@ -126,20 +228,158 @@ func newBombardier(c config) (*bombardier, error) {
 		}
 	}
 
+	// bodies are compressed after templates are applied
+	tbody := pbody
+	if c.form != nil {
+		// form bodies are never templated
+		tbody = nil
+	}
+	// templated bodies are compressed, and Content-Encoding is set,
+	// by the templates for each request, unless they render empty
+	templatedBody := c.requestTemplates && tbody != nil && isTemplated(*tbody)
+	if c.compressBody != "" && !templatedBody &&
+		(bsp != nil || *pbody != "") {
+		headers = withHeader(headers, "Content-Encoding", c.compressBody)
+		if bsp != nil {
+			bsp = compressStream(c.compressBody, bsp)
+		} else {
+			compressed, err := compressBytes(c.compressBody, []byte(*pbody))
+			if err != nil {
+				return nil, err
+			}
+			scompressed := string(compressed)
+			pbody = &scompressed
+		}
+	}
+	if c.acceptEncoding != "" {
+		headers = withHeader(headers, "Accept-Encoding", c.acceptEncoding)
+		b.bodies = new(bodyStats)
+	}
+
+	if c.sampleResponses > 0 {
+		b.sampler, err = newResponseSampler(c.samplesDir, c.sampleResponses)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if c.logRequestsPath != "" {
+		b.reqLog, err = newRequestLogger(c.logRequestsPath, c.logSample)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if c.hdrLogPath != "" {
+		b.hdrLog, err = newHDRLog(c.hdrLogPath, c.hdrLogInterval, b.latencies)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if c.hgrmPath != "" {
+		b.hgrm, err = os.Create(c.hgrmPath)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if c.reports != nil {
+		b.reports, err = newMetricsReports(c, b.latencies, [6]*uint64{
+			&b.req1xx, &b.req2xx, &b.req3xx, &b.req4xx, &b.req5xx,
+			&b.others,
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	if c.traceparent {
+		b.tracer = newTracer(c.traceSlowest, c.otlpEndpoint, c.timeout)
+	}
+
+	if c.dataFilePath != "" {
+		b.feeder, err = newDataFeeder(
+			c.dataFilePath, c.dataMode, !c.dataStopAtEOF, c.numConns,
+		)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var preparer requestPreparer
+	if c.requestTemplates {
+		templates, err := newRequestTemplates(
+			c.url, headers, tbody, b.feeder,
+		)
+		if err != nil {
+			return nil, err
+		}
+		if templates != nil {
+			preparer = templates
+		}
+	}
+	if c.scenarioPath != "" {
+		b.scenario, err = loadScenario(
+			c.scenarioPath, c.url, b.feeder, c.histogramDigits,
+		)
+		if err != nil {
+			return nil, err
+		}
+		preparer = b.scenario
+	}
+	if c.mixPath != "" {
+		b.mix, err = loadMix(
+			c.mixPath, c.url, b.feeder, c.histogramDigits,
+		)
+		if err != nil {
+			return nil, err
+		}
+		preparer = b.mix
+	}
+	if c.replayPath != "" {
+		b.replay, err = loadReplay(
+			c.replayPath, c.url, splitList(c.replayHeaders),
+			c.replaySpeed, b.barrier.done(),
+		)
+		if err != nil {
+			return nil, err
+		}
+		preparer = b.replay
+	}
+	if bc, ok := preparer.(bodyCompressor); ok && c.compressBody != "" {
+		if err := bc.compressBodies(c.compressBody); err != nil {
+			return nil, err
+		}
+	}
+
+	authorizer, err := b.makeAuthorizer(tlsConfig)
+	if err != nil {
+		return nil, err
+	}
+
 	cc := &clientOpts{
 		HTTP2:             false,
 		maxConns:          c.numConns,
 		timeout:           c.timeout,
 		tlsConfig:         tlsConfig,
-		disableKeepAlives: c.disableKeepAlives,
+		disableKeepAlives: c.disableKeepAlives || c.tlsHandshake,
+		tlsHandshakes:     b.handshakes,
 
-		headers:      c.headers,
+		headers:      headers,
 		requestURL:   c.url,
 		method:       c.method,
 		body:         pbody,
 		bodProd:      bsp,
+		preparer:     preparer,
+		maxRedirects: c.maxRedirects,
+		redirects:    b.redirects,
+		authorizer:   authorizer,
+		bodyStats:    b.bodies,
+
+		responseSizes: b.sizes,
+		sampler:       b.sampler,
+
 		bytesRead:    &b.bytesRead,
 		bytesWritten: &b.bytesWritten,
+		conns:        b.conns,
 	}
 	b.client = makeHTTPClient(c.clientType, cc)
 
@@ -148,10 +388,19 @@ func newBombardier(c config) (*bombardier, error) {
 		b.bar.NotPrint = true
 	}
 
-	b.template, err = b.prepareTemplate()
+	b.template, err = b.prepareTemplate(c.format, c.noHeader)
 	if err != nil {
 		return nil, err
 	}
+	if c.outputs != nil {
+		for _, spec := range *c.outputs {
+			o, err := b.openOutput(spec)
+			if err != nil {
+				return nil, err
+			}
+			b.outputs = append(b.outputs, o)
+		}
+	}
 
 	b.wg.Add(int(c.numConns))
 	b.errors = newErrorMap()
@@ -159,6 +408,57 @@ func newBombardier(c config) (*bombardier, error) {
 	return b, nil
 }
 
+func (b *bombardier) makeAuthorizer(
+	tlsConfig *tls.Config,
+) (requestAuthorizer, error) {
+	c := b.conf
+	var (
+		as  authorizers
+		err error
+	)
+	switch {
+	case c.basicAuth != "":
+		as = append(as, newBasicAuth(c.basicAuth))
+	case c.bearerTokenFile != "":
+		b.bearer, err = newFileBearerToken(c.bearerTokenFile)
+	case c.bearerTokenCmd != "":
+		refresh := c.bearerTokenRefresh
+		if refresh <= 0 {
+			refresh = defaultBearerTokenRefresh
+		}
+		b.bearer, err = newCmdBearerToken(c.bearerTokenCmd, refresh)
+	case c.oauth2TokenURL != "":
+		b.bearer, err = newOAuth2BearerToken(oauth2Credentials{
+			tokenURL:     c.oauth2TokenURL,
+			clientID:     c.oauth2ClientID,
+			clientSecret: c.oauth2ClientSecret,
+			scope:        c.oauth2Scope,
+		}, c.timeout, tlsConfig)
+	case c.awsSigV4 != "":
+		var signer *awsV4Signer
+		signer, err = newAWSV4Signer(c.awsSigV4)
+		if err == nil {
+			as = append(as, signer)
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	if b.bearer != nil {
+		as = append(as, b.bearer)
+	}
+	if c.hmacSecret != "" {
+		as = append(as, newHMACSigner(c.hmacSecret, c.hmacHeader))
+	}
+	switch len(as) {
+	case 0:
+		return nil, nil
+	case 1:
+		return as[0], nil
+	}
+	return as, nil
+}
+
 func makeHTTPClient(clientType clientTyp, cc *clientOpts) client {
 	var cl client
 	switch clientType {
@@ -175,12 +475,14 @@ func makeHTTPClient(clientType clientTyp, cc *clientOpts) client {
 	return cl
 }
 
-func (b *bombardier) prepareTemplate() (*template.Template, error) {
+func (b *bombardier) prepareTemplate(
+	f format, noHeader bool,
+) (*template.Template, error) {
 	var (
 		templateBytes []byte
 		err           error
 	)
-	switch f := b.conf.format.(type) {
+	switch f := f.(type) {
 	case knownFormat:
 		templateBytes = f.template()
 	case userDefinedTemplate:
@@ -196,14 +498,33 @@ func (b *bombardier) prepareTemplate() (*template.Template, error) {
 			"WithLatencies": func() bool {
 				return b.conf.printLatencies
 			},
+			"WithHeader": func() bool {
+				return !noHeader
+			},
 			"FormatBinary": formatBinary,
 			"FormatTimeUs": formatTimeUs,
 			"FormatTimeUsUint64": func(us uint64) string {
 				return formatTimeUs(float64(us))
 			},
+			"FormatBinaryUint64": func(n uint64) string {
+				return formatBinary(float64(n))
+			},
 			"FloatsToArray": func(ps ...float64) []float64 {
 				return ps
 			},
+			"Percentiles": func() []float64 {
+				if b.conf.percentiles != nil {
+					return *b.conf.percentiles
+				}
+				return defaultPercentiles
+			},
+			"FormatPercentile": formatPercentile,
+			"CSVField":         formatCSVField,
+			"MarkdownCell":     formatMarkdownCell,
+			"JSON": func(v interface{}) (string, error) {
+				bytes, err := json.Marshal(v)
+				return string(bytes), err
+			},
 			"Multiply": func(num, coeff float64) float64 {
 				return num * coeff
 			},
@@ -227,6 +548,9 @@ func (b *bombardier) writeStatistics(
 	code int, usTaken uint64,
 ) {
 	b.latencies.Increment(usTaken)
+	if b.think != nil {
+		atomic.AddUint64(&b.busy, usTaken)
+	}
 	b.rpl.Lock()
 	b.reqs++
 	b.rpl.Unlock()
@@ -248,23 +572,78 @@ func (b *bombardier) writeStatistics(
 	atomic.AddUint64(counter, 1)
 }
 
-func (b *bombardier) performSingleRequest() {
-	code, usTaken, err := b.client.do()
+func (b *bombardier) performSingleRequest(s *session) {
+	var start time.Time
+	if b.reqLog != nil && b.reqLog.sampled() {
+		s.record = &requestRecord{Worker: s.id}
+		start = time.Now()
+	}
+	if b.tracer != nil {
+		s.trace = newRequestTrace()
+	}
+	code, usTaken, err := b.client.do(s)
+	if err == errDataExhausted {
+		s.trace = nil
+		b.barrier.cancel()
+		return
+	}
+	if s.trace != nil {
+		// requests that failed before being sent have nothing to trace
+		if s.trace.sent {
+			b.tracer.finish(s.trace, code, usTaken, err)
+		}
+		s.trace = nil
+	}
+	if s.record != nil {
+		b.logRequest(s, start, code, usTaken, err)
+	}
+	if b.scenario != nil {
+		b.scenario.record(s, code, usTaken, err)
+	}
+	if b.mix != nil {
+		b.mix.record(s, code, usTaken, err)
+	}
 	if err != nil {
 		b.errors.add(err)
 	}
 	b.writeStatistics(code, usTaken)
 }
 
-func (b *bombardier) worker() {
+func (b *bombardier) logRequest(
+	s *session, start time.Time, code int, usTaken uint64, err error,
+) {
+	r := s.record
+	s.record = nil
+	r.Time, r.LatencyUs = start, usTaken
+	if code > 0 {
+		r.Status = code
+	}
+	if err != nil {
+		r.Error = err.Error()
+	}
+	b.reqLog.log(r)
+}
+
+func (b *bombardier) worker(s *session) {
 	done := b.barrier.done()
 	for b.barrier.tryGrabWork() {
 		if b.ratelimiter.pace(done) == brk {
 			break
 		}
-		b.performSingleRequest()
+		b.performSingleRequest(s)
 		b.barrier.jobDone()
+		if b.think != nil && b.think.pause(done) == brk {
+			break
+		}
+	}
+}
+
+func (b *bombardier) startSession(id uint64) *session {
+	s := newSession(id)
+	if b.conf.cookies || b.conf.cookieFilePath != "" {
+		s.jar = newCookieJar(b.seedCookies)
 	}
+	return s
 }
 
 func (b *bombardier) barUpdater() {
@@ -300,8 +679,11 @@ func (b *bombardier) rateMeter() {
 	done := b.barrier.done()
 	for {
 		select {
-		case <-ticker.C:
+		case now := <-ticker.C:
 			b.recordRps()
+			if b.reports != nil {
+				b.reports.tick(now)
+			}
 			continue
 		case <-done:
 			b.wg.Wait()
@@ -314,14 +696,15 @@ func (b *bombardier) rateMeter() {
 
 func (b *bombardier) recordRps() {
 	b.rpl.Lock()
-	duration := time.Since(b.start)
+	from, to := b.start, time.Now()
 	reqs := b.reqs
 	b.reqs = 0
-	b.start = time.Now()
+	b.start = to
 	b.rpl.Unlock()
 
-	reqsf := float64(reqs) / duration.Seconds()
+	reqsf := float64(reqs) / to.Sub(from).Seconds()
 	b.requests.Increment(reqsf)
+	b.timeline.add(from, to, float64(reqs))
 }
 
 func (b *bombardier) bombard() {
@@ -331,18 +714,85 @@ func (b *bombardier) bombard() {
 	b.bar.Start()
 	bombardmentBegin := time.Now()
 	b.start = time.Now()
+	b.timeline.begin(b.start)
+	if b.replay != nil {
+		b.replay.begin()
+	}
+	if b.hdrLog != nil {
+		b.hdrLog.begin(bombardmentBegin)
+	}
+	if b.reports != nil {
+		b.reports.begin(bombardmentBegin)
+	}
 	for i := uint64(0); i < b.conf.numConns; i++ {
-		go func() {
+		go func(id uint64) {
 			defer b.wg.Done()
-			b.worker()
-		}()
+			b.worker(b.startSession(id))
+		}(i)
 	}
 	go b.rateMeter()
 	go b.barUpdater()
 	b.wg.Wait()
 	b.timeTaken = time.Since(bombardmentBegin)
+	if b.conns != nil {
+		b.conns.stop(bombardmentBegin.Add(b.timeTaken))
+	}
 	<-b.doneChan
 	<-b.doneChan
+	if b.conf.tlsHandshake {
+		// connections are closed after each request, don't let them
+		// be closed in the background while results are gathered
+		b.client.closeIdleConnections()
+	}
+	if b.feeder != nil {
+		if err := b.feeder.close(); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+		}
+	}
+	if b.bearer != nil {
+		b.bearer.close()
+	}
+	if b.reqLog != nil {
+		if err := b.reqLog.close(); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+		}
+	}
+	if b.hdrLog != nil {
+		if err := b.hdrLog.close(); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+		}
+	}
+	if b.hgrm != nil {
+		if err := b.writeHgrm(); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+		}
+	}
+	if b.reports != nil {
+		if err := b.reports.close(time.Now()); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+		}
+	}
+	if b.tracer != nil {
+		if err := b.tracer.close(); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+		}
+	}
+	if len(b.outputs) > 0 {
+		info := b.gatherInfo()
+		for _, o := range b.outputs {
+			if err := o.write(info); err != nil {
+				fmt.Fprintln(os.Stderr, err)
+			}
+		}
+	}
+}
+
+func (b *bombardier) writeHgrm() error {
+	err := writeHgrm(b.hgrm, b.latencies)
+	if cerr := b.hgrm.Close(); err == nil {
+		err = cerr
+	}
+	return err
 }
 
 func (b *bombardier) printIntro() {
@@ -374,11 +824,27 @@ func (b *bombardier) gatherInfo() internal.TestInfo {
 			Timeout:    b.conf.timeout,
 			ClientType: internal.ClientType(b.conf.clientType),
 
-			Rate: b.conf.rate,
+			Rate:      b.conf.rate,
+			ThinkTime: b.conf.thinkTime,
+
+			TLSHandshake:         b.conf.tlsHandshake,
+			TLSSessionResumption: b.conf.tlsResumption,
+
+			ScenarioPath: b.conf.scenarioPath,
+			MixPath:      b.conf.mixPath,
+			ReplayPath:   b.conf.replayPath,
+			ReplaySpeed:  b.conf.replaySpeed,
+
+			MaxRedirects: b.conf.maxRedirects,
+
+			CompressBody:   b.conf.compressBody,
+			AcceptEncoding: b.conf.acceptEncoding,
+
+			SampleResponses: b.conf.sampleResponses,
 		},
 		Result: internal.Results{
-			BytesRead:    b.bytesRead,
-			BytesWritten: b.bytesWritten,
+			BytesRead:    atomic.LoadInt64(&b.bytesRead),
+			BytesWritten: atomic.LoadInt64(&b.bytesWritten),
 			TimeTaken:    b.timeTaken,
 
 			Req1XX: b.req1xx,
@@ -390,6 +856,8 @@ func (b *bombardier) gatherInfo() internal.TestInfo {
 
 			Latencies: b.latencies,
 			Requests:  b.requests,
+
+			ResponseSizes: b.sizes,
 		},
 	}
 
@@ -411,11 +879,121 @@ func (b *bombardier) gatherInfo() internal.TestInfo {
 		}
 	}
 
+	if b.handshakes != nil {
+		info.Result.TLSHandshakes = &internal.TLSHandshakes{
+			Full:      atomic.LoadUint64(&b.handshakes.full),
+			Resumed:   atomic.LoadUint64(&b.handshakes.resumed),
+			Latencies: b.handshakes.latencies,
+		}
+	}
+
+	if b.conns != nil {
+		info.Result.Connections = &internal.Connections{
+			Opened:         atomic.LoadUint64(&b.conns.opened),
+			Reused:         atomic.LoadUint64(&b.conns.reused),
+			ClosedByServer: atomic.LoadUint64(&b.conns.closedByServer),
+			ClosedByClient: atomic.LoadUint64(&b.conns.closedByClient),
+			Open:           atomic.LoadUint64(&b.conns.leftOpen),
+			DialFailures:   atomic.LoadUint64(&b.conns.dialFailures),
+			Peak:           uint64(atomic.LoadInt64(&b.conns.peak)),
+			Requests:       b.conns.requests,
+			Lifetimes:      b.conns.lifetimes,
+		}
+	}
+
+	if b.scenario != nil {
+		for _, step := range b.scenario.steps {
+			info.Result.Steps = append(info.Result.Steps,
+				internal.StepStats{
+					Name:      step.name,
+					Method:    step.method,
+					Requests:  step.requests,
+					Errors:    step.errors,
+					Latencies: step.latencies,
+				})
+		}
+	}
+
+	if b.mix != nil {
+		total := uint64(0)
+		for _, entry := range b.mix.entries {
+			total += entry.requests
+		}
+		for i, entry := range b.mix.entries {
+			stats := internal.MixEntryStats{
+				Name:      entry.name,
+				Method:    entry.method,
+				Expected:  b.mix.weights[i] / b.mix.totalWeight(),
+				Requests:  entry.requests,
+				Errors:    entry.errors,
+				Latencies: entry.latencies,
+			}
+			if total > 0 {
+				stats.Actual = float64(entry.requests) / float64(total)
+			}
+			info.Result.Mix = append(info.Result.Mix, stats)
+		}
+	}
+
+	if b.think != nil {
+		info.Result.ThinkTime = &internal.ThinkTime{
+			Pauses:  atomic.LoadUint64(&b.think.pauses),
+			Total:   time.Duration(atomic.LoadUint64(&b.think.thought)),
+			Waiting: time.Duration(atomic.LoadUint64(&b.busy)) * time.Microsecond,
+		}
+	}
+
+	if b.bearer != nil {
+		info.Result.TokenFailures = b.bearer.refreshFailures()
+	}
+
+	if b.bodies != nil {
+		info.Result.ResponseBodies = &internal.ResponseBodies{
+			Compressed:     atomic.LoadUint64(&b.bodies.compressed),
+			Decompressed:   atomic.LoadUint64(&b.bodies.decompressed),
+			DecodeFailures: atomic.LoadUint64(&b.bodies.failures),
+		}
+	}
+
+	if b.sampler != nil {
+		info.Result.ResponseSamples = &internal.ResponseSamples{
+			Dir:    b.sampler.dir,
+			Saved:  b.sampler.savedCount(),
+			Failed: b.sampler.failedCount(),
+		}
+	}
+
+	if b.reqLog != nil {
+		info.Result.RequestLog = &internal.RequestLog{
+			Path:    b.reqLog.path,
+			Written: atomic.LoadUint64(&b.reqLog.written),
+			Dropped: atomic.LoadUint64(&b.reqLog.dropped),
+		}
+	}
+
+	if b.redirects != nil {
+		info.Result.Redirects = &internal.Redirects{
+			Hops: b.redirects,
+		}
+	}
+
+	info.Result.Timeline = &internal.Timeline{
+		Interval: b.timeline.interval,
+		Requests: b.timeline.counts,
+		End:      b.timeline.end,
+	}
+
+	if b.tracer != nil {
+		info.Result.Tracing = b.tracer.info()
+	}
+
 	for _, ewc := range b.errors.byFrequency() {
 		info.Result.Errors = append(info.Result.Errors,
 			internal.ErrorWithCount{
-				Error: ewc.error,
-				Count: ewc.count,
+				Error:    ewc.error,
+				Count:    ewc.count,
+				Category: ewc.category,
+				Example:  ewc.example,
 			})
 	}
 
@@ -441,6 +1019,9 @@ func (b *bombardier) disableOutput() {
 }
 
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == compareCommand {
+		os.Exit(runCompare(os.Args, os.Stdout))
+	}
 	cfg, err := parser.parse(os.Args)
 	if err != nil {
 		fmt.Println("Error parsing the arguments:", err)
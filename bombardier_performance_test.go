@@ -3,6 +3,7 @@ package main
 import (
 	"flag"
 	"runtime"
+	"sync/atomic"
 	"testing"
 	"time"
 )
@@ -61,11 +62,13 @@ func benchmarkFireRequest(c config, bm *testing.B) {
 	b.disableOutput()
 	bm.SetParallelism(int(defaultNumberOfConns) / runtime.NumCPU())
 	bm.ResetTimer()
+	sessions := uint64(0)
 	bm.RunParallel(func(pb *testing.PB) {
+		s := b.startSession(atomic.AddUint64(&sessions, 1) - 1)
 		done := b.barrier.done()
 		for pb.Next() {
 			b.ratelimiter.pace(done)
-			b.performSingleRequest()
+			b.performSingleRequest(s)
 		}
 	})
 }
@@ -3,14 +3,24 @@ package main
 import (
 	"bytes"
 	"container/ring"
+	"crypto/hmac"
+	"crypto/sha256"
 	"crypto/tls"
 	"crypto/x509"
+	"encoding/hex"
+	"encoding/json"
 	"errors"
+	"fmt"
 	"io/ioutil"
+	"math"
+	"net"
 	"net/http"
 	"net/http/httptest"
 	"os"
+	"path/filepath"
 	"reflect"
+	"strconv"
+	"strings"
 	"sync"
 	"sync/atomic"
 	"testing"
@@ -727,3 +737,1511 @@ func testBombardierShouldSendCustomHostHeader(
 	b.disableOutput()
 	b.bombard()
 }
+
+func TestBombardierTLSHandshakes(t *testing.T) {
+	testAllClients(t, testBombardierTLSHandshakes)
+}
+
+func testBombardierTLSHandshakes(clientType clientTyp, t *testing.T) {
+	s := httptest.NewUnstartedServer(
+		http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+			rw.WriteHeader(http.StatusOK)
+		}),
+	)
+	s.EnableHTTP2 = true
+	s.StartTLS()
+	defer s.Close()
+	for _, resume := range []bool{false, true} {
+		numReqs := uint64(50)
+		b, e := newBombardier(config{
+			numConns:      5,
+			numReqs:       &numReqs,
+			url:           ParseURLOrPanic(s.URL),
+			headers:       new(headersList),
+			timeout:       defaultTimeout,
+			method:        "GET",
+			insecure:      true,
+			tlsHandshake:  true,
+			tlsResumption: resume,
+			clientType:    clientType,
+			format:        knownFormat("json"),
+		})
+		if e != nil {
+			t.Error(e)
+			return
+		}
+		b.disableOutput()
+		b.bombard()
+		if b.req2xx != numReqs {
+			t.Errorf("expected %v 2xx responses, but got %v: %v",
+				numReqs, b.req2xx, b.errors.byFrequency())
+		}
+		full := atomic.LoadUint64(&b.handshakes.full)
+		resumed := atomic.LoadUint64(&b.handshakes.resumed)
+		// net/http may occasionally dial more connections than needed
+		// for HTTP/2, so only check that there were enough of them
+		if full+resumed < numReqs {
+			t.Errorf("expected %v handshakes, but got %v full and %v resumed",
+				numReqs, full, resumed)
+		}
+		if !resume && resumed != 0 {
+			t.Errorf("expected no resumed handshakes, but got %v", resumed)
+		}
+		if resume && resumed == 0 {
+			t.Error("expected some handshakes to be resumed")
+		}
+		out := new(bytes.Buffer)
+		b.redirectOutputTo(out)
+		b.printStats()
+		var res map[string]interface{}
+		if err := json.Unmarshal(out.Bytes(), &res); err != nil {
+			t.Errorf("invalid JSON output %q: %v", out.String(), err)
+		}
+	}
+}
+
+func TestBombardierRequestTemplates(t *testing.T) {
+	testAllClients(t, testBombardierRequestTemplates)
+}
+
+func testBombardierRequestTemplates(clientType clientTyp, t *testing.T) {
+	var (
+		m    sync.Mutex
+		seen = make(map[string]bool)
+	)
+	s := httptest.NewServer(
+		http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+			body, err := ioutil.ReadAll(r.Body)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			id := r.URL.Query().Get("id")
+			if h := r.Header.Get("X-Id"); h != id || string(body) != id {
+				t.Errorf("expected %q everywhere, but got header %q and body %q",
+					id, h, body)
+			}
+			m.Lock()
+			seen[r.URL.Path+"?"+id] = true
+			m.Unlock()
+		}),
+	)
+	defer s.Close()
+	numReqs := uint64(100)
+	headers := headersList([]header{{"X-Id", "{{ .Counter }}"}})
+	b, e := newBombardier(config{
+		numConns:         10,
+		numReqs:          &numReqs,
+		url:              ParseURLOrPanic(s.URL + "/items/{{ .Counter }}?id={{ .Counter }}"),
+		headers:          &headers,
+		timeout:          defaultTimeout,
+		method:           "POST",
+		body:             "{{ .Counter }}",
+		requestTemplates: true,
+		clientType:       clientType,
+		format:           knownFormat("plain-text"),
+	})
+	if e != nil {
+		t.Error(e)
+		return
+	}
+	b.disableOutput()
+	b.bombard()
+	if uint64(len(seen)) != numReqs {
+		t.Errorf("expected %v unique requests, but got %v", numReqs, len(seen))
+	}
+}
+
+func TestBombardierDataFeeding(t *testing.T) {
+	testAllClients(t, testBombardierDataFeeding)
+}
+
+func testBombardierDataFeeding(clientType clientTyp, t *testing.T) {
+	var (
+		m    sync.Mutex
+		seen []string
+	)
+	s := httptest.NewServer(
+		http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+			m.Lock()
+			seen = append(seen, r.URL.Path+" "+r.Header.Get("X-Name"))
+			m.Unlock()
+		}),
+	)
+	defer s.Close()
+	path := writeDataFile(t, "users.csv", testCSVData)
+	numReqs := uint64(100)
+	headers := headersList([]header{{"X-Name", "{{ .Data.name }}"}})
+	b, e := newBombardier(config{
+		numConns:         1,
+		numReqs:          &numReqs,
+		url:              ParseURLOrPanic(s.URL + "/users/{{ .Data.id }}"),
+		headers:          &headers,
+		timeout:          defaultTimeout,
+		method:           "GET",
+		requestTemplates: true,
+		dataFilePath:     path,
+		dataStopAtEOF:    true,
+		clientType:       clientType,
+		format:           knownFormat("plain-text"),
+	})
+	if e != nil {
+		t.Error(e)
+		return
+	}
+	b.disableOutput()
+	b.bombard()
+	exp := []string{"/users/1 alice", "/users/2 bob, jr.", "/users/3 carol"}
+	if !reflect.DeepEqual(seen, exp) {
+		t.Errorf("expected %v, but got %v", exp, seen)
+	}
+	if b.errors.sum() != 0 {
+		t.Errorf("unexpected errors: %v", b.errors.byFrequency())
+	}
+}
+
+func TestBombardierScenario(t *testing.T) {
+	testAllClients(t, testBombardierScenario)
+}
+
+func testBombardierScenario(clientType clientTyp, t *testing.T) {
+	var tokens uint64
+	s := httptest.NewServer(
+		http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+			switch r.URL.Path {
+			case "/login":
+				if r.Method != "POST" {
+					rw.WriteHeader(http.StatusMethodNotAllowed)
+					return
+				}
+				token := atomic.AddUint64(&tokens, 1)
+				rw.Header().Set("X-Session", "session")
+				fmt.Fprintf(rw, `{"auth":{"token":"t%v"}}`, token)
+			case "/items/session":
+				auth := r.Header.Get("Authorization")
+				if auth == "" || auth == "Bearer " {
+					rw.WriteHeader(http.StatusUnauthorized)
+				}
+			default:
+				rw.WriteHeader(http.StatusNotFound)
+			}
+		}),
+	)
+	defer s.Close()
+	scenario := writeDataFile(t, "scenario.json", `{"steps":[
+		{"name":"login","method":"POST","path":"/login",
+		 "body":"{\"user\":\"u{{ .Counter }}\"}",
+		 "extract":[
+			{"var":"token","json":"auth.token"},
+			{"var":"session","header":"X-Session"}
+		 ]},
+		{"name":"items","path":"/items/{{ .Vars.session }}",
+		 "headers":{"Authorization":"Bearer {{ .Vars.token }}"}}
+	]}`)
+	numReqs := uint64(100)
+	b, e := newBombardier(config{
+		numConns:     10,
+		numReqs:      &numReqs,
+		url:          ParseURLOrPanic(s.URL),
+		headers:      new(headersList),
+		timeout:      defaultTimeout,
+		method:       "GET",
+		scenarioPath: scenario,
+		clientType:   clientType,
+		format:       knownFormat("json"),
+	})
+	if e != nil {
+		t.Error(e)
+		return
+	}
+	b.disableOutput()
+	b.bombard()
+	if b.req2xx != numReqs {
+		t.Errorf("expected %v 2xx responses, but got %v: %v",
+			numReqs, b.req2xx, b.errors.byFrequency())
+	}
+	info := b.gatherInfo()
+	if len(info.Result.Steps) != 2 {
+		t.Fatalf("expected stats for 2 steps, but got %+v", info.Result.Steps)
+	}
+	total := uint64(0)
+	for _, step := range info.Result.Steps {
+		if step.Errors != 0 {
+			t.Errorf("step %q has %v errors", step.Name, step.Errors)
+		}
+		total += step.Requests
+	}
+	if total != numReqs {
+		t.Errorf("expected %v requests in steps, but got %v", numReqs, total)
+	}
+	out := new(bytes.Buffer)
+	b.redirectOutputTo(out)
+	b.printStats()
+	var res map[string]interface{}
+	if err := json.Unmarshal(out.Bytes(), &res); err != nil {
+		t.Errorf("invalid JSON output %q: %v", out.String(), err)
+	}
+}
+
+func TestBombardierCookies(t *testing.T) {
+	testAllClients(t, testBombardierCookies)
+}
+
+func testBombardierCookies(clientType clientTyp, t *testing.T) {
+	var sessions, withSession uint64
+	s := httptest.NewServer(
+		http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+			if c, err := r.Cookie("seed"); err != nil || c.Value != "seeded" {
+				t.Errorf("seed cookie is missing: %v", r.Cookies())
+			}
+			if _, err := r.Cookie("sid"); err == nil {
+				atomic.AddUint64(&withSession, 1)
+				return
+			}
+			sid := atomic.AddUint64(&sessions, 1)
+			http.SetCookie(rw, &http.Cookie{
+				Name: "sid", Value: fmt.Sprint(sid), Path: "/",
+			})
+		}),
+	)
+	defer s.Close()
+	u := ParseURLOrPanic(s.URL)
+	cookieFile := writeDataFile(t, "cookies.txt",
+		u.Hostname()+"\tFALSE\t/\tFALSE\t0\tseed\tseeded\n")
+	numConns, numReqs := uint64(5), uint64(100)
+	b, e := newBombardier(config{
+		numConns:       numConns,
+		numReqs:        &numReqs,
+		url:            u,
+		headers:        new(headersList),
+		timeout:        defaultTimeout,
+		method:         "GET",
+		cookieFilePath: cookieFile,
+		clientType:     clientType,
+		format:         knownFormat("plain-text"),
+	})
+	if e != nil {
+		t.Error(e)
+		return
+	}
+	b.disableOutput()
+	b.bombard()
+	if sessions == 0 || sessions > numConns {
+		t.Errorf("expected at most one session per connection, but got %v",
+			sessions)
+	}
+	if sessions+withSession != numReqs {
+		t.Errorf("expected %v requests, but got %v", numReqs,
+			sessions+withSession)
+	}
+}
+
+func TestBombardierFollowRedirects(t *testing.T) {
+	testAllClients(t, testBombardierFollowRedirects)
+}
+
+func testBombardierFollowRedirects(clientType clientTyp, t *testing.T) {
+	s := httptest.NewServer(
+		http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+			switch r.URL.Path {
+			case "/start":
+				http.Redirect(rw, r, "/middle", http.StatusFound)
+			case "/middle":
+				http.Redirect(rw, r, "/end", http.StatusSeeOther)
+			case "/end":
+				rw.WriteHeader(http.StatusOK)
+			case "/loop":
+				http.Redirect(rw, r, "/loop-back", http.StatusFound)
+			case "/loop-back":
+				http.Redirect(rw, r, "/loop", http.StatusFound)
+			}
+		}),
+	)
+	defer s.Close()
+	numReqs := uint64(50)
+	newBombardierFor := func(path string, maxRedirects uint64) *bombardier {
+		b, e := newBombardier(config{
+			numConns:     defaultNumberOfConns,
+			numReqs:      &numReqs,
+			url:          ParseURLOrPanic(s.URL + path),
+			headers:      new(headersList),
+			timeout:      defaultTimeout,
+			method:       "POST",
+			body:         "body",
+			maxRedirects: maxRedirects,
+			clientType:   clientType,
+			format:       knownFormat("json"),
+		})
+		if e != nil {
+			t.Fatal(e)
+		}
+		b.disableOutput()
+		b.bombard()
+		return b
+	}
+
+	b := newBombardierFor("/start", defaultMaxRedirects)
+	if b.req2xx != numReqs {
+		t.Errorf("expected %v 2xx responses, but got %v (3xx: %v, errors: %v)",
+			numReqs, b.req2xx, b.req3xx, b.errors.byFrequency())
+	}
+	if e, a := numReqs, b.redirects.Get(2); e != a {
+		t.Errorf("expected %v requests with 2 hops, but got %v", e, a)
+	}
+	out := new(bytes.Buffer)
+	b.redirectOutputTo(out)
+	b.printStats()
+	var res struct {
+		Result struct {
+			Redirects struct {
+				Total      uint64            `json:"total"`
+				Redirected uint64            `json:"redirected"`
+				Hops       map[string]uint64 `json:"hops"`
+			} `json:"redirects"`
+		} `json:"result"`
+	}
+	if err := json.Unmarshal(out.Bytes(), &res); err != nil {
+		t.Fatalf("invalid JSON output %q: %v", out.String(), err)
+	}
+	if r := res.Result.Redirects; r.Total != 2*numReqs ||
+		r.Redirected != numReqs || r.Hops["2"] != numReqs {
+		t.Errorf("unexpected redirects in JSON output: %+v", r)
+	}
+
+	b = newBombardierFor("/start", 1)
+	if b.req3xx != numReqs {
+		t.Errorf("expected %v 3xx responses, but got %v", numReqs, b.req3xx)
+	}
+	if e, a := numReqs, b.redirects.Get(1); e != a {
+		t.Errorf("expected %v requests with 1 hop, but got %v", e, a)
+	}
+
+	b = newBombardierFor("/loop", defaultMaxRedirects)
+	if b.others != numReqs {
+		t.Errorf("expected %v errors, but got %v", numReqs, b.others)
+	}
+	if e, a := numReqs, b.errors.sum(); e != a {
+		t.Errorf("expected %v errors, but got %v", e, a)
+	}
+	for _, ewc := range b.errors.byFrequency() {
+		if ewc.error != errRedirectLoop.Error() {
+			t.Errorf("unexpected error: %v", ewc.error)
+		}
+	}
+}
+
+func TestBombardierRedirectCookies(t *testing.T) {
+	testAllClients(t, testBombardierRedirectCookies)
+}
+
+func testBombardierRedirectCookies(clientType clientTyp, t *testing.T) {
+	s := httptest.NewServer(
+		http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+			switch r.URL.Path {
+			case "/login":
+				http.SetCookie(rw, &http.Cookie{
+					Name: "sid", Value: "logged-in", Path: "/",
+				})
+				http.Redirect(rw, r, "/home", http.StatusFound)
+			case "/home":
+				c, err := r.Cookie("sid")
+				if err != nil || c.Value != "logged-in" {
+					rw.WriteHeader(http.StatusUnauthorized)
+					return
+				}
+				http.SetCookie(rw, &http.Cookie{
+					Name: "sid", Value: "visited", Path: "/",
+				})
+				http.Redirect(rw, r, "/profile", http.StatusFound)
+			case "/profile":
+				c, err := r.Cookie("sid")
+				if err != nil || c.Value != "visited" {
+					rw.WriteHeader(http.StatusUnauthorized)
+				}
+			}
+		}),
+	)
+	defer s.Close()
+	numReqs := uint64(50)
+	b, e := newBombardier(config{
+		numConns:     defaultNumberOfConns,
+		numReqs:      &numReqs,
+		url:          ParseURLOrPanic(s.URL + "/login"),
+		headers:      new(headersList),
+		timeout:      defaultTimeout,
+		method:       "GET",
+		cookies:      true,
+		maxRedirects: defaultMaxRedirects,
+		clientType:   clientType,
+		format:       knownFormat("plain-text"),
+	})
+	if e != nil {
+		t.Fatal(e)
+	}
+	b.disableOutput()
+	b.bombard()
+	if b.req2xx != numReqs {
+		t.Errorf("expected %v 2xx responses, but got %v (4xx: %v, errors: %v)",
+			numReqs, b.req2xx, b.req4xx, b.errors.byFrequency())
+	}
+}
+
+func TestBombardierCrossHostRedirectHeaders(t *testing.T) {
+	testAllClients(t, testBombardierCrossHostRedirectHeaders)
+}
+
+func testBombardierCrossHostRedirectHeaders(clientType clientTyp, t *testing.T) {
+	var leaked, redirected uint64
+	target := httptest.NewServer(
+		http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+			atomic.AddUint64(&redirected, 1)
+			for _, h := range []string{"Authorization", "Cookie", "X-Signature", "X-Timestamp"} {
+				if r.Header.Get(h) != "" {
+					atomic.AddUint64(&leaked, 1)
+				}
+			}
+		}),
+	)
+	defer target.Close()
+	var authorized uint64
+	s := httptest.NewServer(
+		http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+			if _, _, ok := r.BasicAuth(); ok && r.Header.Get("Cookie") != "" {
+				atomic.AddUint64(&authorized, 1)
+			}
+			http.Redirect(rw, r, target.URL+"/target", http.StatusFound)
+		}),
+	)
+	defer s.Close()
+	numReqs := uint64(10)
+	headers := headersList([]header{{"Cookie", "sid=secret"}})
+	b, e := newBombardier(config{
+		numConns:     defaultNumberOfConns,
+		numReqs:      &numReqs,
+		url:          ParseURLOrPanic(s.URL),
+		headers:      &headers,
+		timeout:      defaultTimeout,
+		method:       "GET",
+		basicAuth:    "user:password",
+		hmacSecret:   "secret",
+		maxRedirects: defaultMaxRedirects,
+		clientType:   clientType,
+		format:       knownFormat("plain-text"),
+	})
+	if e != nil {
+		t.Fatal(e)
+	}
+	b.disableOutput()
+	b.bombard()
+	if authorized != numReqs || redirected != numReqs {
+		t.Errorf("expected %v authorized and redirected requests, but got "+
+			"%v and %v (errors: %v)", numReqs, authorized, redirected,
+			b.errors.byFrequency())
+	}
+	if leaked != 0 {
+		t.Errorf("%v credential headers were sent to another host", leaked)
+	}
+}
+
+func TestBombardierRequestSigning(t *testing.T) {
+	testAllClients(t, testBombardierRequestSigning)
+}
+
+func testBombardierRequestSigning(clientType clientTyp, t *testing.T) {
+	var valid, invalid uint64
+	s := httptest.NewServer(
+		http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+			body, err := ioutil.ReadAll(r.Body)
+			if err != nil {
+				t.Error(err)
+			}
+			mac := hmac.New(sha256.New, []byte("secret"))
+			mac.Write([]byte(r.Method + "\n" + r.URL.RequestURI() + "\n" +
+				r.Header.Get("X-Timestamp") + "\n" + sha256Hex(body)))
+			signature := hex.EncodeToString(mac.Sum(nil))
+			if r.Header.Get("X-Signature") != signature ||
+				r.Header.Get("Authorization") != "Bearer token" {
+				atomic.AddUint64(&invalid, 1)
+				rw.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			atomic.AddUint64(&valid, 1)
+		}),
+	)
+	defer s.Close()
+	numReqs := uint64(20)
+	body := "{{ .Counter }}"
+	b, e := newBombardier(config{
+		numConns:         defaultNumberOfConns,
+		numReqs:          &numReqs,
+		url:              ParseURLOrPanic(s.URL + "/items/{{ .Counter }}?q=1"),
+		headers:          new(headersList),
+		timeout:          defaultTimeout,
+		method:           "POST",
+		body:             body,
+		requestTemplates: true,
+		bearerTokenFile:  writeDataFile(t, "token", "token\n"),
+		hmacSecret:       "secret",
+		clientType:       clientType,
+		format:           knownFormat("plain-text"),
+	})
+	if e != nil {
+		t.Fatal(e)
+	}
+	b.disableOutput()
+	b.bombard()
+	if valid != numReqs || invalid != 0 {
+		t.Errorf("expected %v valid signatures, but got %v (invalid: %v)",
+			numReqs, valid, invalid)
+	}
+}
+
+func TestBombardierOAuth2(t *testing.T) {
+	testAllClients(t, testBombardierOAuth2)
+}
+
+func testBombardierOAuth2(clientType clientTyp, t *testing.T) {
+	ts := newTokenServer(t, 3600)
+	s := httptest.NewServer(
+		http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+			if r.Header.Get("Authorization") != "Bearer token-1" {
+				rw.WriteHeader(http.StatusUnauthorized)
+			}
+		}),
+	)
+	defer s.Close()
+	numReqs := uint64(20)
+	creds := ts.credentials()
+	b, e := newBombardier(config{
+		numConns:           defaultNumberOfConns,
+		numReqs:            &numReqs,
+		url:                ParseURLOrPanic(s.URL),
+		headers:            new(headersList),
+		timeout:            defaultTimeout,
+		method:             "GET",
+		oauth2TokenURL:     creds.tokenURL,
+		oauth2ClientID:     creds.clientID,
+		oauth2ClientSecret: creds.clientSecret,
+		clientType:         clientType,
+		format:             knownFormat("json"),
+	})
+	if e != nil {
+		t.Fatal(e)
+	}
+	b.disableOutput()
+	b.bombard()
+	if b.req2xx != numReqs {
+		t.Errorf("expected %v authorized requests, but got %v",
+			numReqs, b.req2xx)
+	}
+	if info := b.gatherInfo(); info.Result.TokenFailures != 0 {
+		t.Errorf("unexpected token failures: %v", info.Result.TokenFailures)
+	}
+}
+
+func TestBombardierForm(t *testing.T) {
+	testAllClients(t, testBombardierForm)
+}
+
+func testBombardierForm(clientType clientTyp, t *testing.T) {
+	var valid, invalid uint64
+	s := httptest.NewServer(
+		http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+			if err := r.ParseMultipartForm(1 << 20); err != nil {
+				t.Error(err)
+			}
+			file, _, err := r.FormFile("upload")
+			if err != nil {
+				atomic.AddUint64(&invalid, 1)
+				return
+			}
+			defer file.Close()
+			contents, _ := ioutil.ReadAll(file)
+			if r.FormValue("name") != "value" ||
+				string(contents) != "file contents" {
+				atomic.AddUint64(&invalid, 1)
+				return
+			}
+			atomic.AddUint64(&valid, 1)
+		}),
+	)
+	defer s.Close()
+	path := writeDataFile(t, "upload.txt", "file contents")
+	for _, stream := range []bool{false, true} {
+		valid, invalid = 0, 0
+		numReqs := uint64(10)
+		b, e := newBombardier(config{
+			numConns: defaultNumberOfConns,
+			numReqs:  &numReqs,
+			url:      ParseURLOrPanic(s.URL),
+			headers:  &headersList{{"Content-Type", "text/plain"}},
+			timeout:  defaultTimeout,
+			method:   "POST",
+			form: &formFields{
+				{name: "name", value: "value"},
+				{name: "upload", value: path, file: true},
+			},
+			stream:     stream,
+			clientType: clientType,
+			format:     knownFormat("plain-text"),
+		})
+		if e != nil {
+			t.Fatal(e)
+		}
+		b.disableOutput()
+		b.bombard()
+		if valid != numReqs || invalid != 0 {
+			t.Errorf("stream: %v, expected %v valid forms, but got %v "+
+				"(invalid: %v)", stream, numReqs, valid, invalid)
+		}
+	}
+}
+
+func TestBombardierCompression(t *testing.T) {
+	testAllClients(t, testBombardierCompression)
+}
+
+func testBombardierCompression(clientType clientTyp, t *testing.T) {
+	body := strings.Repeat("request body ", 50)
+	payload := strings.Repeat("response body ", 100)
+	compressedPayload, err := compressBytes("gzip", []byte(payload))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var valid, invalid uint64
+	s := httptest.NewServer(
+		http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+			encoding := r.Header.Get("Content-Encoding")
+			compressed, _ := ioutil.ReadAll(r.Body)
+			n, err := decodedLength(encoding, compressed)
+			if encoding == "" || err != nil || n != int64(len(body)) {
+				atomic.AddUint64(&invalid, 1)
+			} else {
+				atomic.AddUint64(&valid, 1)
+			}
+			if r.Header.Get("Accept-Encoding") == "gzip" {
+				rw.Header().Set("Content-Encoding", "gzip")
+				_, _ = rw.Write(compressedPayload)
+			}
+		}),
+	)
+	defer s.Close()
+	for _, encoding := range bodyEncodings {
+		for _, stream := range []bool{false, true} {
+			valid, invalid = 0, 0
+			numReqs := uint64(10)
+			b, e := newBombardier(config{
+				numConns:       defaultNumberOfConns,
+				numReqs:        &numReqs,
+				url:            ParseURLOrPanic(s.URL),
+				headers:        new(headersList),
+				timeout:        defaultTimeout,
+				method:         "POST",
+				body:           body,
+				stream:         stream,
+				compressBody:   encoding,
+				acceptEncoding: "gzip",
+				clientType:     clientType,
+				format:         knownFormat("plain-text"),
+			})
+			if e != nil {
+				t.Fatal(e)
+			}
+			b.disableOutput()
+			b.bombard()
+			if valid != numReqs || invalid != 0 {
+				t.Errorf("%v, stream: %v, expected %v valid bodies, but got "+
+					"%v (invalid: %v)", encoding, stream, numReqs, valid, invalid)
+			}
+			rb := b.gatherInfo().Result.ResponseBodies
+			if rb == nil ||
+				rb.Compressed != numReqs*uint64(len(compressedPayload)) ||
+				rb.Decompressed != numReqs*uint64(len(payload)) {
+				t.Errorf("unexpected response bodies stats: %+v", rb)
+			}
+		}
+	}
+}
+
+func TestBombardierCompressionOfTemplatedBody(t *testing.T) {
+	testAllClients(t, testBombardierCompressionOfTemplatedBody)
+}
+
+func testBombardierCompressionOfTemplatedBody(clientType clientTyp, t *testing.T) {
+	var compressed, empty, invalid uint64
+	s := httptest.NewServer(
+		http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+			encoding := r.Header.Get("Content-Encoding")
+			body, _ := ioutil.ReadAll(r.Body)
+			switch {
+			case len(body) == 0 && encoding == "":
+				atomic.AddUint64(&empty, 1)
+			case len(body) == 0:
+				atomic.AddUint64(&invalid, 1)
+			default:
+				n, err := decodedLength(encoding, body)
+				if encoding != "gzip" || err != nil || n != int64(len("payload")) {
+					atomic.AddUint64(&invalid, 1)
+					return
+				}
+				atomic.AddUint64(&compressed, 1)
+			}
+		}),
+	)
+	defer s.Close()
+	numReqs := uint64(4)
+	// only the first request has a body
+	body := "{{ if eq .Counter 1 }}payload{{ end }}"
+	b, e := newBombardier(config{
+		numConns:         1,
+		numReqs:          &numReqs,
+		url:              ParseURLOrPanic(s.URL),
+		headers:          new(headersList),
+		timeout:          defaultTimeout,
+		method:           "POST",
+		body:             body,
+		requestTemplates: true,
+		compressBody:     "gzip",
+		clientType:       clientType,
+		format:           knownFormat("plain-text"),
+	})
+	if e != nil {
+		t.Fatal(e)
+	}
+	b.disableOutput()
+	b.bombard()
+	if compressed != 1 || empty != numReqs-1 || invalid != 0 {
+		t.Errorf("expected 1 compressed and %v empty bodies, but got %v "+
+			"and %v (invalid: %v)", numReqs-1, compressed, empty, invalid)
+	}
+}
+
+func TestBombardierSampleResponses(t *testing.T) {
+	testAllClients(t, testBombardierSampleResponses)
+}
+
+func testBombardierSampleResponses(clientType clientTyp, t *testing.T) {
+	var counter uint64
+	s := httptest.NewServer(
+		http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+			if atomic.AddUint64(&counter, 1)%2 == 0 {
+				rw.Header().Set("X-Failure", "yes")
+				rw.WriteHeader(http.StatusInternalServerError)
+				_, _ = rw.Write([]byte("boom"))
+				return
+			}
+			_, _ = rw.Write([]byte("fine, thanks"))
+		}),
+	)
+	defer s.Close()
+	dir := filepath.Join(t.TempDir(), "samples")
+	numReqs := uint64(20)
+	b, e := newBombardier(config{
+		numConns:        defaultNumberOfConns,
+		numReqs:         &numReqs,
+		url:             ParseURLOrPanic(s.URL),
+		headers:         new(headersList),
+		timeout:         defaultTimeout,
+		method:          "GET",
+		sampleResponses: 3,
+		samplesDir:      dir,
+		clientType:      clientType,
+		format:          knownFormat("plain-text"),
+	})
+	if e != nil {
+		t.Fatal(e)
+	}
+	b.disableOutput()
+	b.bombard()
+
+	info := b.gatherInfo()
+	sizes := info.Result.ResponseSizesStats(nil)
+	if sizes == nil || sizes.Max != float64(len("fine, thanks")) ||
+		sizes.Mean != float64(len("fine, thanks")+len("boom"))/2 {
+		t.Errorf("unexpected response sizes: %+v", sizes)
+	}
+	if rs := info.Result.ResponseSamples; rs == nil || rs.Saved != 6 {
+		t.Errorf("expected 6 saved samples, but got %+v", rs)
+	}
+	files, err := filepath.Glob(filepath.Join(dir, "*.http"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 6 {
+		t.Errorf("expected 6 samples, but got %v", files)
+	}
+	sample, err := ioutil.ReadFile(filepath.Join(dir, "500-3.http"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.HasPrefix(sample, []byte("HTTP/1.1 500")) &&
+		!bytes.HasPrefix(sample, []byte("HTTP/2.0 500")) {
+		t.Errorf("sample doesn't start with status line: %q", sample)
+	}
+	if !bytes.Contains(sample, []byte("X-Failure: yes")) ||
+		!bytes.HasSuffix(sample, []byte("\r\n\r\nboom")) {
+		t.Errorf("unexpected sample: %q", sample)
+	}
+}
+
+func TestBombardierLogRequests(t *testing.T) {
+	testAllClients(t, testBombardierLogRequests)
+}
+
+func testBombardierLogRequests(clientType clientTyp, t *testing.T) {
+	s := httptest.NewServer(
+		http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+			_, _ = rw.Write([]byte("hello"))
+		}),
+	)
+	defer s.Close()
+	path := filepath.Join(t.TempDir(), "requests.jsonl")
+	numReqs := uint64(15)
+	b, e := newBombardier(config{
+		numConns:        3,
+		numReqs:         &numReqs,
+		url:             ParseURLOrPanic(s.URL + "/path?q=1"),
+		headers:         new(headersList),
+		timeout:         defaultTimeout,
+		method:          "GET",
+		logRequestsPath: path,
+		logSample:       1,
+		clientType:      clientType,
+		format:          knownFormat("plain-text"),
+	})
+	if e != nil {
+		t.Fatal(e)
+	}
+	b.disableOutput()
+	b.bombard()
+
+	records := readRequestLog(t, path)
+	if uint64(len(records)) != numReqs {
+		t.Fatalf("expected %v records, but got %v", numReqs, len(records))
+	}
+	for _, r := range records {
+		if r.Method != "GET" || r.URL != s.URL+"/path?q=1" ||
+			r.Status != http.StatusOK || r.Bytes != 5 ||
+			r.Worker >= 3 || r.Time.IsZero() || r.Error != "" {
+			t.Errorf("unexpected record: %+v", r)
+		}
+	}
+	rl := b.gatherInfo().Result.RequestLog
+	if rl == nil || rl.Written != numReqs || rl.Path != path {
+		t.Errorf("unexpected request log stats: %+v", rl)
+	}
+}
+
+func TestBombardierReplay(t *testing.T) {
+	testAllClients(t, testBombardierReplay)
+}
+
+func testBombardierReplay(clientType clientTyp, t *testing.T) {
+	var (
+		mu       sync.Mutex
+		requests = make(map[string]int)
+	)
+	s := httptest.NewServer(
+		http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+			body, _ := ioutil.ReadAll(r.Body)
+			mu.Lock()
+			requests[fmt.Sprintf("%v %v %v %v %v", r.Method, r.URL.RequestURI(),
+				r.Header.Get("Accept"), r.Header.Get("Cookie"), string(body))]++
+			mu.Unlock()
+		}),
+	)
+	defer s.Close()
+	path := writeDataFile(t, "requests.har", testHAR)
+	numReqs := uint64(10)
+	b, e := newBombardier(config{
+		numConns:      2,
+		numReqs:       &numReqs,
+		url:           ParseURLOrPanic(s.URL),
+		headers:       new(headersList),
+		timeout:       defaultTimeout,
+		method:        "GET",
+		replayPath:    path,
+		replayHeaders: "accept",
+		clientType:    clientType,
+		format:        knownFormat("plain-text"),
+	})
+	if e != nil {
+		t.Fatal(e)
+	}
+	b.disableOutput()
+	b.bombard()
+
+	expected := map[string]int{
+		"GET /items?page=2 application/json  ": 5,
+		`POST /cart   {"id":1}`:                5,
+	}
+	if !reflect.DeepEqual(requests, expected) {
+		t.Errorf("expected %v, but got %v", expected, requests)
+	}
+}
+
+func TestBombardierMix(t *testing.T) {
+	testAllClients(t, testBombardierMix)
+}
+
+func testBombardierMix(clientType clientTyp, t *testing.T) {
+	s := httptest.NewServer(
+		http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+			if r.URL.Path == "/cart" {
+				if r.Method != "POST" {
+					rw.WriteHeader(http.StatusMethodNotAllowed)
+				}
+				return
+			}
+			if r.URL.Path != "/items" {
+				rw.WriteHeader(http.StatusNotFound)
+			}
+		}),
+	)
+	defer s.Close()
+	path := writeDataFile(t, "mix.json", `{"requests": [
+		{"name": "items", "path": "/items", "weight": 3},
+		{"name": "cart", "method": "POST", "path": "/cart", "weight": 1},
+		{"name": "missing", "path": "/missing", "weight": 1}
+	]}`)
+	numReqs := uint64(500)
+	b, e := newBombardier(config{
+		numConns:   defaultNumberOfConns,
+		numReqs:    &numReqs,
+		url:        ParseURLOrPanic(s.URL),
+		headers:    new(headersList),
+		timeout:    defaultTimeout,
+		method:     "GET",
+		mixPath:    path,
+		clientType: clientType,
+		format:     knownFormat("plain-text"),
+	})
+	if e != nil {
+		t.Fatal(e)
+	}
+	b.disableOutput()
+	b.bombard()
+
+	entries := b.gatherInfo().Result.Mix
+	if len(entries) != 3 {
+		t.Fatalf("expected 3 entries, but got %v", entries)
+	}
+	total := uint64(0)
+	for i, expected := range []float64{0.6, 0.2, 0.2} {
+		entry := entries[i]
+		total += entry.Requests
+		if entry.Expected != expected ||
+			math.Abs(entry.Actual-expected) > 0.1 {
+			t.Errorf("%v: expected share %v, but got %v (expected %v)",
+				entry.Name, expected, entry.Actual, entry.Expected)
+		}
+	}
+	if total != numReqs {
+		t.Errorf("expected %v requests in total, but got %v", numReqs, total)
+	}
+	if entries[0].Errors != 0 || entries[1].Errors != 0 ||
+		entries[2].Errors != entries[2].Requests {
+		t.Errorf("unexpected errors: %+v", entries)
+	}
+	if b.req4xx != entries[2].Requests {
+		t.Errorf("expected %v 4xx, but got %v", entries[2].Requests, b.req4xx)
+	}
+}
+
+func TestBombardierThinkTime(t *testing.T) {
+	s := httptest.NewServer(
+		http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {}),
+	)
+	defer s.Close()
+	numReqs := uint64(10)
+	b, e := newBombardier(config{
+		numConns:   2,
+		numReqs:    &numReqs,
+		url:        ParseURLOrPanic(s.URL),
+		headers:    new(headersList),
+		timeout:    defaultTimeout,
+		method:     "GET",
+		thinkTime:  "50ms",
+		clientType: fhttp,
+		format:     knownFormat("plain-text"),
+	})
+	if e != nil {
+		t.Fatal(e)
+	}
+	b.disableOutput()
+	b.bombard()
+
+	// each connection makes 5 requests with 4 pauses in between
+	if b.timeTaken < 200*time.Millisecond {
+		t.Errorf("expected test to take at least 200ms, but took %v",
+			b.timeTaken)
+	}
+	info := b.gatherInfo()
+	tt := info.Result.ThinkTime
+	if tt == nil || tt.Pauses < 8 || tt.Mean() < 50*time.Millisecond {
+		t.Errorf("unexpected think time: %+v", tt)
+	}
+	if c := info.Result.EffectiveConcurrency(); c <= 0 || c >= 2 {
+		t.Errorf("expected effective concurrency between 0 and 2, got %v", c)
+	}
+}
+
+func TestBombardierCustomPercentiles(t *testing.T) {
+	s := httptest.NewServer(
+		http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {}),
+	)
+	defer s.Close()
+	numReqs := uint64(10)
+	b, e := newBombardier(config{
+		numConns:        2,
+		numReqs:         &numReqs,
+		url:             ParseURLOrPanic(s.URL),
+		headers:         new(headersList),
+		timeout:         defaultTimeout,
+		method:          "GET",
+		printLatencies:  true,
+		percentiles:     &percentileList{0.5, 0.999},
+		histogramDigits: 2,
+		clientType:      fhttp,
+		format:          knownFormat("json"),
+	})
+	if e != nil {
+		t.Fatal(e)
+	}
+	b.disableOutput()
+	b.bombard()
+	out := new(bytes.Buffer)
+	b.redirectOutputTo(out)
+	b.printStats()
+	var res struct {
+		Result struct {
+			Latency struct {
+				Percentiles map[string]float64 `json:"percentiles"`
+			} `json:"latency"`
+		} `json:"result"`
+	}
+	if err := json.Unmarshal(out.Bytes(), &res); err != nil {
+		t.Fatalf("invalid JSON output %q: %v", out.String(), err)
+	}
+	pcs := res.Result.Latency.Percentiles
+	if len(pcs) != 2 || pcs["50"] == 0 || pcs["99.9"] < pcs["50"] {
+		t.Errorf("unexpected latency percentiles: %v", pcs)
+	}
+}
+
+func TestBombardierHDRLog(t *testing.T) {
+	s := httptest.NewServer(
+		http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {}),
+	)
+	defer s.Close()
+	dir := t.TempDir()
+	logPath := filepath.Join(dir, "latencies.hlog")
+	hgrmPath := filepath.Join(dir, "latencies.hgrm")
+	duration := time.Second
+	b, e := newBombardier(config{
+		numConns:       2,
+		duration:       &duration,
+		url:            ParseURLOrPanic(s.URL),
+		headers:        new(headersList),
+		timeout:        defaultTimeout,
+		method:         "GET",
+		hdrLogPath:     logPath,
+		hdrLogInterval: 300 * time.Millisecond,
+		hgrmPath:       hgrmPath,
+		clientType:     fhttp,
+		format:         knownFormat("plain-text"),
+	})
+	if e != nil {
+		t.Fatal(e)
+	}
+	b.disableOutput()
+	b.bombard()
+
+	contents, err := os.ReadFile(logPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var total uint64
+	intervals := 0
+	for _, line := range strings.Split(string(contents), "\n") {
+		if line == "" || strings.HasPrefix(line, "#") ||
+			strings.HasPrefix(line, `"`) {
+			continue
+		}
+		fields := strings.Split(line, ",")
+		if len(fields) != 4 {
+			t.Fatalf("unexpected interval line: %q", line)
+		}
+		_, counts := decodeHDR(t, fields[3])
+		for _, c := range counts {
+			total += c
+		}
+		intervals++
+	}
+	if intervals < 3 {
+		t.Errorf("expected at least 3 intervals, but got %v", intervals)
+	}
+	reqs := b.req2xx + b.others
+	if total != reqs {
+		t.Errorf("expected %v latencies in the log, but got %v", reqs, total)
+	}
+
+	hgrm, err := os.ReadFile(hgrmPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(hgrm), fmt.Sprintf("Total count    = %12d", reqs)) {
+		t.Errorf("unexpected percentile distribution:\n%s", hgrm)
+	}
+}
+
+func TestBombardierHTMLReport(t *testing.T) {
+	s := httptest.NewServer(
+		http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+			if r.URL.Query().Get("fail") != "" {
+				rw.WriteHeader(http.StatusInternalServerError)
+			}
+		}),
+	)
+	defer s.Close()
+	numReqs := uint64(20)
+	b, e := newBombardier(config{
+		numConns:   2,
+		numReqs:    &numReqs,
+		url:        ParseURLOrPanic(s.URL + "/?fail=1&b=2"),
+		headers:    new(headersList),
+		timeout:    defaultTimeout,
+		method:     "GET",
+		clientType: fhttp,
+		format:     knownFormat("html"),
+	})
+	if e != nil {
+		t.Fatal(e)
+	}
+	b.disableOutput()
+	b.bombard()
+	out := new(bytes.Buffer)
+	b.redirectOutputTo(out)
+	b.printStats()
+	report := out.String()
+
+	if !strings.Contains(report, "/?fail=1&amp;b=2</p>") {
+		t.Error("expected URL to be escaped")
+	}
+	const dataStart = `<script id="data" type="application/json">`
+	i := strings.Index(report, dataStart)
+	j := strings.Index(report[i:], "</script>")
+	if i < 0 || j < 0 {
+		t.Fatalf("no data in the report:\n%v", report)
+	}
+	var data struct {
+		Latencies []struct {
+			Value, Count uint64
+		}
+		Timeline struct {
+			Interval float64
+			RPS      []float64
+		}
+		Codes [][2]interface{}
+	}
+	if err := json.Unmarshal(
+		[]byte(report[i+len(dataStart):i+j]), &data,
+	); err != nil {
+		t.Fatalf("invalid data in the report: %v", err)
+	}
+	latencies := uint64(0)
+	for _, l := range data.Latencies {
+		latencies += l.Count
+	}
+	if latencies != numReqs {
+		t.Errorf("expected %v latencies, but got %v", numReqs, latencies)
+	}
+	if data.Timeline.Interval <= 0 || len(data.Timeline.RPS) == 0 {
+		t.Errorf("unexpected timeline: %+v", data.Timeline)
+	}
+	if len(data.Codes) != 6 || data.Codes[4][0] != "5xx" ||
+		data.Codes[4][1] != float64(numReqs) {
+		t.Errorf("unexpected codes: %v", data.Codes)
+	}
+}
+
+func TestBombardierOutputs(t *testing.T) {
+	s := httptest.NewServer(
+		http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {}),
+	)
+	defer s.Close()
+	jsonPath := filepath.Join(t.TempDir(), "result.json")
+	csvPath := writeDataFile(t, "results.csv", "previous run\n")
+	numReqs := uint64(10)
+	b, e := newBombardier(config{
+		numConns:   2,
+		numReqs:    &numReqs,
+		url:        ParseURLOrPanic(s.URL),
+		headers:    new(headersList),
+		timeout:    defaultTimeout,
+		method:     "GET",
+		clientType: fhttp,
+		format:     knownFormat("plain-text"),
+		outputs: &outputList{
+			{format: knownFormat("json"), path: jsonPath},
+			{
+				format:   knownFormat("csv"),
+				path:     csvPath,
+				append:   true,
+				noHeader: true,
+			},
+		},
+	})
+	if e != nil {
+		t.Fatal(e)
+	}
+	b.disableOutput()
+	b.bombard()
+
+	contents, err := os.ReadFile(jsonPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var res jsonResults
+	if err := json.Unmarshal(contents, &res); err != nil {
+		t.Fatalf("invalid json output: %v\n%s", err, contents)
+	}
+	if res.requests() != numReqs {
+		t.Errorf("expected %v requests in json output, but got %v",
+			numReqs, res.requests())
+	}
+
+	contents, err = os.ReadFile(csvPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSuffix(string(contents), "\n"), "\n")
+	if len(lines) != 2 || lines[0] != "previous run" ||
+		!strings.HasPrefix(lines[1], s.URL+",GET,2,") {
+		t.Errorf("expected csv row to be appended, but got:\n%s", contents)
+	}
+}
+
+func TestBombardierReports(t *testing.T) {
+	s := httptest.NewServer(
+		http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {}),
+	)
+	defer s.Close()
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	duration := time.Second
+	b, e := newBombardier(config{
+		numConns:       2,
+		duration:       &duration,
+		url:            ParseURLOrPanic(s.URL),
+		headers:        new(headersList),
+		timeout:        defaultTimeout,
+		method:         "GET",
+		clientType:     fhttp,
+		format:         knownFormat("plain-text"),
+		reports:        &reportList{{"statsd", conn.LocalAddr().String()}},
+		reportInterval: 300 * time.Millisecond,
+		reportTags:     &tagList{{"test", "reports"}},
+	})
+	if e != nil {
+		t.Fatal(e)
+	}
+	b.disableOutput()
+	b.bombard()
+
+	reports, total := 0, uint64(0)
+	buf := make([]byte, statsdMaxPacket)
+	conn.SetReadDeadline(time.Now().Add(time.Second))
+	for total < b.req2xx {
+		n, _, err := conn.ReadFrom(buf)
+		if err != nil {
+			t.Fatalf("got %v of %v requests reported: %v",
+				total, b.req2xx, err)
+		}
+		for _, line := range strings.Split(string(buf[:n]), "\n") {
+			if strings.HasPrefix(line, "bombardier.rps:") {
+				reports++
+			}
+			const prefix = "bombardier.requests.2xx:"
+			if !strings.HasPrefix(line, prefix) {
+				continue
+			}
+			if !strings.HasSuffix(line, "|c|#test:reports") {
+				t.Errorf("unexpected counter line: %q", line)
+			}
+			value := strings.TrimPrefix(line, prefix)
+			value = value[:strings.Index(value, "|")]
+			n, err := strconv.ParseUint(value, 10, 64)
+			if err != nil {
+				t.Fatal(err)
+			}
+			total += n
+		}
+	}
+	if reports < 3 {
+		t.Errorf("expected at least 3 reports, but got %v", reports)
+	}
+}
+
+func TestBombardierPropagatesTraceContext(t *testing.T) {
+	testAllClients(t, testBombardierPropagatesTraceContext)
+}
+
+func testBombardierPropagatesTraceContext(clientType clientTyp, t *testing.T) {
+	var (
+		mu       sync.Mutex
+		traceIDs = make(map[string]bool)
+	)
+	s := httptest.NewServer(
+		http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+			parts := strings.Split(r.Header.Get(traceparentHeader), "-")
+			if len(parts) != 4 {
+				t.Errorf("invalid traceparent: %q",
+					r.Header.Get(traceparentHeader))
+				return
+			}
+			mu.Lock()
+			traceIDs[parts[1]] = true
+			mu.Unlock()
+		}),
+	)
+	defer s.Close()
+	numReqs := uint64(50)
+	b, e := newBombardier(config{
+		numConns:     5,
+		numReqs:      &numReqs,
+		url:          ParseURLOrPanic(s.URL),
+		headers:      new(headersList),
+		timeout:      defaultTimeout,
+		method:       "GET",
+		clientType:   clientType,
+		format:       knownFormat("plain-text"),
+		traceparent:  true,
+		traceSlowest: 3,
+	})
+	if e != nil {
+		t.Fatal(e)
+	}
+	b.disableOutput()
+	b.bombard()
+	if len(traceIDs) != int(numReqs) {
+		t.Errorf("expected %v unique trace ids, but got %v",
+			numReqs, len(traceIDs))
+	}
+	tracing := b.gatherInfo().Result.Tracing
+	if tracing == nil || len(tracing.Slowest) != 3 {
+		t.Fatalf("expected 3 slowest requests, but got %+v", tracing)
+	}
+	for i, r := range tracing.Slowest {
+		if !traceIDs[r.TraceID] {
+			t.Errorf("unknown trace id %q", r.TraceID)
+		}
+		if r.Method != "GET" || r.Code != http.StatusOK ||
+			!strings.HasPrefix(r.URL, s.URL) {
+			t.Errorf("unexpected slowest request: %+v", r)
+		}
+		if i > 0 && r.Latency > tracing.Slowest[i-1].Latency {
+			t.Errorf("slowest requests aren't sorted: %+v", tracing.Slowest)
+		}
+	}
+}
+
+func TestBombardierDoesNotTraceUnsentRequests(t *testing.T) {
+	testAllClients(t, testBombardierDoesNotTraceUnsentRequests)
+}
+
+func testBombardierDoesNotTraceUnsentRequests(clientType clientTyp, t *testing.T) {
+	var received uint64
+	s := httptest.NewServer(
+		http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+			atomic.AddUint64(&received, 1)
+		}),
+	)
+	defer s.Close()
+	collector := httptest.NewServer(
+		http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {}),
+	)
+	defer collector.Close()
+	numReqs := uint64(10)
+	headers := headersList([]header{
+		{"X-Line", `{{ RandomLine "/does/not/exist.txt" }}`},
+	})
+	b, e := newBombardier(config{
+		numConns:         2,
+		numReqs:          &numReqs,
+		url:              ParseURLOrPanic(s.URL),
+		headers:          &headers,
+		timeout:          defaultTimeout,
+		method:           "GET",
+		requestTemplates: true,
+		clientType:       clientType,
+		format:           knownFormat("plain-text"),
+		traceparent:      true,
+		traceSlowest:     3,
+		otlpEndpoint:     collector.URL,
+	})
+	if e != nil {
+		t.Fatal(e)
+	}
+	b.disableOutput()
+	b.bombard()
+	if received != 0 || b.others != numReqs {
+		t.Fatalf("expected all requests to fail before being sent, but "+
+			"%v were received and %v failed", received, b.others)
+	}
+	tracing := b.gatherInfo().Result.Tracing
+	if tracing == nil || len(tracing.Slowest) != 0 ||
+		tracing.Exported != 0 || tracing.Dropped != 0 {
+		t.Errorf("expected no traced requests, but got %+v", tracing)
+	}
+}
+
+func TestBombardierConnectionStats(t *testing.T) {
+	testAllClients(t, testBombardierConnectionStats)
+}
+
+func testBombardierConnectionStats(clientType clientTyp, t *testing.T) {
+	s := httptest.NewServer(
+		http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {}),
+	)
+	defer s.Close()
+	numReqs := uint64(100)
+	b, e := newBombardier(config{
+		numConns:   4,
+		numReqs:    &numReqs,
+		url:        ParseURLOrPanic(s.URL),
+		headers:    new(headersList),
+		timeout:    defaultTimeout,
+		method:     "GET",
+		connStats:  true,
+		clientType: clientType,
+		format:     knownFormat("plain-text"),
+	})
+	if e != nil {
+		t.Fatal(e)
+	}
+	b.disableOutput()
+	b.bombard()
+	conns := b.gatherInfo().Result.Connections
+	if conns == nil {
+		t.Fatal("expected connection stats")
+	}
+	if conns.Opened == 0 || conns.Opened > 4 || conns.Peak > 4 {
+		t.Errorf("expected at most 4 connections, but got %+v", conns)
+	}
+	if conns.Opened+conns.Reused != numReqs {
+		t.Errorf("expected %v requests over %v connections, but got %v reused",
+			numReqs, conns.Opened, conns.Reused)
+	}
+	if conns.Open != conns.Opened ||
+		conns.ClosedByServer+conns.ClosedByClient != 0 {
+		t.Errorf("expected connections to be kept alive, but got %+v", conns)
+	}
+	stats := conns.RequestsStats(nil)
+	if stats == nil || uint64(stats.Mean*float64(conns.Opened)+0.5) != numReqs {
+		t.Errorf("unexpected requests per connection: %+v", stats)
+	}
+}
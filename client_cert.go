@@ -35,5 +35,14 @@ func generateTLSConfig(c config) (*tls.Config, error) {
 		InsecureSkipVerify: c.insecure,
 		Certificates:       certs,
 	}
+	// In TLS handshake benchmarking mode session resumption must be
+	// explicitly requested, otherwise every handshake is a full one.
+	if c.tlsHandshake {
+		if c.tlsResumption {
+			tlsConfig.ClientSessionCache = tls.NewLRUClientSessionCache(0)
+		} else {
+			tlsConfig.SessionTicketsDisabled = true
+		}
+	}
 	return tlsConfig, nil
 }
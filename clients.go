@@ -1,23 +1,38 @@
 package main
 
 import (
+	"bytes"
+	"context"
 	"crypto/tls"
+	"errors"
+	"fmt"
 	"io"
 	"io/ioutil"
 	"net/http"
+	"net/http/httptrace"
 	"net/url"
 	"strings"
 	"time"
 
+	uhist "github.com/codesenberg/concurrent/uint64/histogram"
 	"github.com/valyala/fasthttp"
 )
 
 type client interface {
-	do() (code int, usTaken uint64, err error)
+	do(s *session) (code int, usTaken uint64, err error)
+	// closeIdleConnections closes connections, which aren't in use,
+	// e.g. those left to be closed in the background.
+	closeIdleConnections()
 }
 
 type bodyStreamProducer func() (io.ReadCloser, error)
 
+// requestPreparer prepares parts of the request, which differ from
+// request to request.
+type requestPreparer interface {
+	prepare(s *session) (*renderedRequest, error)
+}
+
 type clientOpts struct {
 	HTTP2 bool
 
@@ -26,6 +41,10 @@ type clientOpts struct {
 	tlsConfig         *tls.Config
 	disableKeepAlives bool
 
+	// tlsHandshakes is non-nil only in TLS handshake benchmarking
+	// mode, in which case clients perform handshakes themselves.
+	tlsHandshakes *handshakeStats
+
 	requestURL *url.URL
 	headers    *headersList
 	method     string
@@ -33,7 +52,32 @@ type clientOpts struct {
 	body    *string
 	bodProd bodyStreamProducer
 
+	// preparer is nil, unless some parts of the request have
+	// to be prepared anew for each request.
+	preparer requestPreparer
+
+	// maxRedirects is the number of redirects to follow, number of
+	// hops taken by each request is recorded into redirects.
+	maxRedirects uint64
+	redirects    *uhist.Histogram
+
+	// authorizer is nil, unless requests need authentication
+	// headers computed anew for each request.
+	authorizer requestAuthorizer
+
+	// bodyStats is non-nil, if sizes of response bodies before and
+	// after decompression have to be recorded.
+	bodyStats *bodyStats
+
+	// responseSizes records sizes of response bodies, sampler is
+	// nil, unless responses have to be saved.
+	responseSizes *hdrHistogram
+	sampler       *responseSampler
+
 	bytesRead, bytesWritten *int64
+
+	// conns is nil, unless connections have to be tracked.
+	conns *connStats
 }
 
 type fasthttpClient struct {
@@ -45,6 +89,24 @@ type fasthttpClient struct {
 
 	body    *string
 	bodProd bodyStreamProducer
+
+	preparer requestPreparer
+
+	disableKeepAlives bool
+
+	maxRedirects uint64
+	redirects    *uhist.Histogram
+	// crossHostHeaders aren't sent, when redirected to another host.
+	crossHostHeaders []string
+
+	authorizer requestAuthorizer
+
+	bodyStats *bodyStats
+
+	responseSizes *hdrHistogram
+	sampler       *responseSampler
+
+	conns *connStats
 }
 
 func newFastHTTPClient(opts *clientOpts) client {
@@ -66,16 +128,34 @@ func newFastHTTPClient(opts *clientOpts) client {
 		TLSConfig:                     opts.tlsConfig,
 		Dial: fasthttpDialFunc(
 			opts.bytesRead, opts.bytesWritten,
-			opts.timeout,
+			opts.timeout, opts.conns,
 		),
 	}
+	if opts.tlsHandshakes != nil {
+		c.client.Dial = fasthttpTLSDialFunc(
+			opts.bytesRead, opts.bytesWritten,
+			opts.timeout, opts.tlsConfig, opts.tlsHandshakes, opts.conns,
+		)
+	}
 	c.headers = headersToFastHTTPHeaders(opts.headers)
 	c.method, c.body = opts.method, opts.body
 	c.bodProd = opts.bodProd
+	c.disableKeepAlives = opts.disableKeepAlives
+	c.preparer = opts.preparer
+	c.maxRedirects, c.redirects = opts.maxRedirects, opts.redirects
+	c.crossHostHeaders = crossHostHeaders(opts.authorizer)
+	c.authorizer = opts.authorizer
+	c.bodyStats = opts.bodyStats
+	c.responseSizes, c.sampler = opts.responseSizes, opts.sampler
+	c.conns = opts.conns
 	return client(c)
 }
 
-func (c *fasthttpClient) do() (
+func (c *fasthttpClient) closeIdleConnections() {
+	c.client.CloseIdleConnections()
+}
+
+func (c *fasthttpClient) do(s *session) (
 	code int, usTaken uint64, err error,
 ) {
 	// prepare the request
@@ -87,7 +167,45 @@ func (c *fasthttpClient) do() (
 	req.Header.SetMethod(c.method)
 	req.SetURI(c.uri)
 	req.UseHostHeader = true
-	if c.body != nil {
+	if c.disableKeepAlives {
+		req.SetConnectionClose()
+	}
+	var rr *renderedRequest
+	if c.preparer != nil {
+		rr, err = c.preparer.prepare(s)
+		if err != nil {
+			fasthttp.ReleaseRequest(req)
+			fasthttp.ReleaseResponse(resp)
+			return 0, 0, err
+		}
+		if rr.method != "" {
+			req.Header.SetMethod(rr.method)
+		}
+		if rr.hasPath {
+			req.URI().SetPath(rr.path)
+		}
+		if rr.hasQuery {
+			req.URI().SetQueryString(rr.query)
+		}
+		for _, h := range rr.headers {
+			req.Header.Set(h.key, h.value)
+		}
+	}
+	var (
+		cookieURL *url.URL
+		cookies   []*http.Cookie
+	)
+	if s.jar != nil {
+		cookieURL, cookies, err = addJarCookies(s.jar, req)
+		if err != nil {
+			fasthttp.ReleaseRequest(req)
+			fasthttp.ReleaseResponse(resp)
+			return 0, 0, err
+		}
+	}
+	if rr != nil && rr.hasBody {
+		req.SetBodyRaw(rr.body)
+	} else if c.body != nil {
 		req.SetBodyString(*c.body)
 	} else {
 		bs, bserr := c.bodProd()
@@ -96,10 +214,31 @@ func (c *fasthttpClient) do() (
 		}
 		req.SetBodyStream(bs, -1)
 	}
+	if s.trace != nil {
+		req.Header.Set(traceparentHeader, s.trace.traceparent())
+	}
+	if c.authorizer != nil {
+		if err = c.authorize(req); err != nil {
+			fasthttp.ReleaseRequest(req)
+			fasthttp.ReleaseResponse(resp)
+			return 0, 0, err
+		}
+	}
 
 	// fire the request
 	start := time.Now()
 	err = c.client.Do(req, resp)
+	c.conns.served(resp.LocalAddr())
+	if err == nil && s.jar != nil {
+		storeSetCookies(s.jar, cookieURL, resp)
+	}
+	if err == nil && c.maxRedirects > 0 {
+		var hops uint64
+		hops, err = c.followRedirects(s.jar, cookies, req, resp)
+		if err == nil {
+			c.redirects.Increment(hops)
+		}
+	}
 	if err != nil {
 		code = -1
 	} else {
@@ -107,6 +246,34 @@ func (c *fasthttpClient) do() (
 	}
 	usTaken = uint64(time.Since(start).Nanoseconds() / 1000)
 
+	if err == nil && c.responseSizes != nil {
+		c.responseSizes.Increment(uint64(len(resp.Body())))
+	}
+	if err == nil && c.sampler != nil {
+		if n, ok := c.sampler.take(code); ok {
+			c.sampler.save(code, n, resp.Header.Header(), resp.Body())
+		}
+	}
+	if err == nil && c.bodyStats != nil {
+		c.bodyStats.record(
+			string(resp.Header.ContentEncoding()), resp.Body(),
+		)
+	}
+	if err == nil && rr != nil && rr.onResponse != nil {
+		err = rr.onResponse(code, func(key string) string {
+			return string(resp.Header.Peek(key))
+		}, resp.Body())
+	}
+	if s.record != nil {
+		s.record.fill(
+			string(req.Header.Method()), req.URI().String(),
+			int64(len(resp.Body())),
+		)
+	}
+	if s.trace != nil {
+		s.trace.fill(string(req.Header.Method()), req.URI().String())
+	}
+
 	// release resources
 	fasthttp.ReleaseRequest(req)
 	fasthttp.ReleaseResponse(resp)
@@ -114,6 +281,104 @@ func (c *fasthttpClient) do() (
 	return
 }
 
+func (c *fasthttpClient) authorize(req *fasthttp.Request) error {
+	u, err := url.Parse(req.URI().String())
+	if err != nil {
+		return err
+	}
+	ar := &authRequest{
+		method: string(req.Header.Method()),
+		host:   string(req.Header.Host()),
+		url:    u,
+	}
+	if ar.host == "" {
+		ar.host = u.Host
+	}
+	if !req.IsBodyStream() {
+		ar.body = req.Body()
+	}
+	headers, err := c.authorizer.authorize(ar)
+	if err != nil {
+		return err
+	}
+	for _, h := range headers {
+		req.Header.Set(h.key, h.value)
+	}
+	return nil
+}
+
+// followRedirects follows up to c.maxRedirects redirects starting
+// with resp, reusing req for the subsequent requests. If jar is not
+// nil, each hop is sent with the cookies from it instead of cookies,
+// which were taken from it for req, and cookies set by the responses
+// are stored in it.
+func (c *fasthttpClient) followRedirects(
+	jar http.CookieJar, cookies []*http.Cookie,
+	req *fasthttp.Request, resp *fasthttp.Response,
+) (hops uint64, err error) {
+	var visited []string
+	for ; hops < c.maxRedirects; hops++ {
+		code := resp.StatusCode()
+		location := resp.Header.Peek("Location")
+		if !fasthttp.StatusCodeIsRedirect(code) || len(location) == 0 {
+			return hops, nil
+		}
+		method, keepBody := redirectMethod(code, string(req.Header.Method()))
+		if keepBody && req.IsBodyStream() {
+			// streamed body was already consumed and can't be resent
+			return hops, nil
+		}
+		if visited == nil {
+			visited = []string{redirectKey(
+				string(req.Header.Method()), req.URI().String(),
+			)}
+		}
+
+		host := string(req.URI().Host())
+		req.URI().UpdateBytes(location)
+		if newHost := req.URI().Host(); string(newHost) != host {
+			req.Header.SetHostBytes(newHost)
+			delFastHTTPHeaders(&req.Header, c.crossHostHeaders)
+		}
+		req.Header.SetMethod(method)
+		if !keepBody {
+			req.ResetBody()
+			req.Header.SetContentLength(0)
+			req.Header.Del("Content-Type")
+		}
+
+		key := redirectKey(method, req.URI().String())
+		for _, prev := range visited {
+			if prev == key {
+				return hops, errRedirectLoop
+			}
+		}
+		visited = append(visited, key)
+
+		var cookieURL *url.URL
+		if jar != nil {
+			for _, cookie := range cookies {
+				req.Header.DelCookie(cookie.Name)
+			}
+			cookieURL, cookies, err = addJarCookies(jar, req)
+			if err != nil {
+				return hops, err
+			}
+		}
+
+		resp.Reset()
+		err := c.client.Do(req, resp)
+		c.conns.served(resp.LocalAddr())
+		if err != nil {
+			return hops, err
+		}
+		if jar != nil {
+			storeSetCookies(jar, cookieURL, resp)
+		}
+	}
+	return hops, nil
+}
+
 type httpClient struct {
 	client *http.Client
 
@@ -123,6 +388,21 @@ type httpClient struct {
 
 	body    *string
 	bodProd bodyStreamProducer
+
+	preparer requestPreparer
+
+	redirects *uhist.Histogram
+
+	authorizer requestAuthorizer
+
+	bodyStats *bodyStats
+
+	responseSizes *hdrHistogram
+	sampler       *responseSampler
+
+	// ctx is nil, unless connections are tracked, in which case it
+	// carries the trace telling which connection a request got.
+	ctx context.Context
 }
 
 func newHTTPClient(opts *clientOpts) client {
@@ -132,7 +412,17 @@ func newHTTPClient(opts *clientOpts) client {
 		MaxIdleConnsPerHost: int(opts.maxConns),
 		DisableKeepAlives:   opts.disableKeepAlives,
 		ForceAttemptHTTP2:   opts.HTTP2,
-		DialContext:         httpDialContextFunc(opts.bytesRead, opts.bytesWritten, opts.timeout),
+		DialContext:         httpDialContextFunc(opts.bytesRead, opts.bytesWritten, opts.timeout, opts.conns),
+	}
+	if opts.tlsHandshakes != nil {
+		tlsConfig := opts.tlsConfig.Clone()
+		if opts.HTTP2 {
+			tlsConfig.NextProtos = []string{"h2", "http/1.1"}
+		}
+		tr.DialTLSContext = httpTLSDialContextFunc(
+			opts.bytesRead, opts.bytesWritten,
+			opts.timeout, tlsConfig, opts.tlsHandshakes, opts.conns,
+		)
 	}
 
 	cl := &http.Client{
@@ -142,16 +432,38 @@ func newHTTPClient(opts *clientOpts) client {
 			return http.ErrUseLastResponse
 		},
 	}
+	if opts.maxRedirects > 0 {
+		cl.CheckRedirect = checkRedirectFunc(
+			opts.maxRedirects, crossHostHeaders(opts.authorizer),
+		)
+		c.redirects = opts.redirects
+	}
 	c.client = cl
 
 	c.headers = headersToHTTPHeaders(opts.headers)
 	c.method, c.body, c.bodProd = opts.method, opts.body, opts.bodProd
 	c.url = opts.requestURL
+	c.preparer = opts.preparer
+	c.authorizer = opts.authorizer
+	c.bodyStats = opts.bodyStats
+	c.responseSizes, c.sampler = opts.responseSizes, opts.sampler
+	if conns := opts.conns; conns != nil {
+		c.ctx = httptrace.WithClientTrace(context.Background(),
+			&httptrace.ClientTrace{
+				GotConn: func(info httptrace.GotConnInfo) {
+					conns.served(info.Conn.LocalAddr())
+				},
+			})
+	}
 
 	return client(c)
 }
 
-func (c *httpClient) do() (
+func (c *httpClient) closeIdleConnections() {
+	c.client.CloseIdleConnections()
+}
+
+func (c *httpClient) do(s *session) (
 	code int, usTaken uint64, err error,
 ) {
 	req := &http.Request{}
@@ -160,14 +472,69 @@ func (c *httpClient) do() (
 	req.Method = c.method
 	req.URL = c.url
 
+	var rr *renderedRequest
+	if c.preparer != nil {
+		rr, err = c.preparer.prepare(s)
+		if err != nil {
+			return 0, 0, err
+		}
+		if rr.method != "" {
+			req.Method = rr.method
+		}
+		if rr.hasPath || rr.hasQuery {
+			u := *c.url
+			if rr.hasPath {
+				u.Path, u.RawPath = rr.path, ""
+			}
+			if rr.hasQuery {
+				u.RawQuery = rr.query
+			}
+			req.URL = &u
+		}
+		if len(rr.headers) > 0 {
+			req.Header = c.headers.Clone()
+			for _, h := range rr.headers {
+				req.Header[h.key] = []string{h.value}
+			}
+		}
+	}
+
+	if s.jar != nil {
+		// client adds cookies from the jar to the headers
+		req.Header = req.Header.Clone()
+	}
+
 	if host := req.Header.Get("Host"); host != "" {
 		req.Host = host
 	}
 
-	if c.body != nil {
-		br := strings.NewReader(*c.body)
-		req.ContentLength = int64(len(*c.body))
-		req.Body = ioutil.NopCloser(br)
+	if s.trace != nil {
+		req.Header = req.Header.Clone()
+		req.Header.Set(traceparentHeader, s.trace.traceparent())
+	}
+
+	if c.authorizer != nil {
+		if err = c.authorize(req, rr); err != nil {
+			return 0, 0, err
+		}
+	}
+
+	// GetBody lets the transport resend the request over another
+	// connection, e.g. if HTTP/2 connection was closed meanwhile
+	if rr != nil && rr.hasBody {
+		body := rr.body
+		req.ContentLength = int64(len(body))
+		req.GetBody = func() (io.ReadCloser, error) {
+			return ioutil.NopCloser(bytes.NewReader(body)), nil
+		}
+		req.Body, _ = req.GetBody()
+	} else if c.body != nil {
+		body := *c.body
+		req.ContentLength = int64(len(body))
+		req.GetBody = func() (io.ReadCloser, error) {
+			return ioutil.NopCloser(strings.NewReader(body)), nil
+		}
+		req.Body, _ = req.GetBody()
 	} else {
 		bs, bserr := c.bodProd()
 		if bserr != nil {
@@ -176,16 +543,51 @@ func (c *httpClient) do() (
 		req.Body = bs
 	}
 
+	if c.ctx != nil {
+		req = req.WithContext(c.ctx)
+	}
+
+	client := c.client
+	if s.jar != nil {
+		// a copy of the client with the session's jar, so that cookies
+		// are sent and stored on redirects too
+		withJar := *c.client
+		withJar.Jar = s.jar
+		client = &withJar
+	}
+
 	start := time.Now()
-	resp, err := c.client.Do(req)
+	resp, err := client.Do(req)
+	var (
+		body     []byte
+		sample   uint64
+		sampled  bool
+		bodySize int64
+	)
 	if err != nil {
 		code = -1
+		if errors.Is(err, errRedirectLoop) {
+			err = errRedirectLoop
+		}
 	} else {
 		code = resp.StatusCode
+		if c.redirects != nil {
+			c.redirects.Increment(redirectHops(resp))
+		}
 
-		_, berr := io.Copy(ioutil.Discard, resp.Body)
+		if c.sampler != nil {
+			sample, sampled = c.sampler.take(code)
+		}
+		var berr error
+		if sampled || c.bodyStats != nil ||
+			(rr != nil && rr.onResponse != nil) {
+			body, berr = ioutil.ReadAll(resp.Body)
+			bodySize = int64(len(body))
+		} else {
+			bodySize, berr = io.Copy(ioutil.Discard, resp.Body)
+		}
 		if berr != nil {
-			err = berr
+			err = &bodyReadError{berr}
 		}
 
 		if cerr := resp.Body.Close(); cerr != nil {
@@ -194,9 +596,65 @@ func (c *httpClient) do() (
 	}
 	usTaken = uint64(time.Since(start).Nanoseconds() / 1000)
 
+	if err == nil && c.responseSizes != nil {
+		c.responseSizes.Increment(uint64(bodySize))
+	}
+	if err == nil && sampled {
+		c.sampler.save(code, sample, responseHead(resp), body)
+	}
+	if err == nil && c.bodyStats != nil {
+		c.bodyStats.record(resp.Header.Get("Content-Encoding"), body)
+	}
+	if err == nil && rr != nil && rr.onResponse != nil {
+		err = rr.onResponse(code, resp.Header.Get, body)
+	}
+	if s.record != nil {
+		s.record.fill(req.Method, req.URL.String(), bodySize)
+	}
+	if s.trace != nil {
+		s.trace.fill(req.Method, req.URL.String())
+	}
+
 	return
 }
 
+func (c *httpClient) authorize(
+	req *http.Request, rr *renderedRequest,
+) error {
+	ar := &authRequest{
+		method: req.Method,
+		host:   req.Host,
+		url:    req.URL,
+	}
+	if ar.host == "" {
+		ar.host = req.URL.Host
+	}
+	if rr != nil && rr.hasBody {
+		ar.body = rr.body
+	} else if c.body != nil {
+		ar.body = []byte(*c.body)
+	}
+	headers, err := c.authorizer.authorize(ar)
+	if err != nil {
+		return err
+	}
+	req.Header = req.Header.Clone()
+	for _, h := range headers {
+		req.Header.Set(h.key, h.value)
+	}
+	return nil
+}
+
+// responseHead formats the status line and headers of the response
+// the way they are sent over HTTP/1.x.
+func responseHead(resp *http.Response) []byte {
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "%v %v\r\n", resp.Proto, resp.Status)
+	_ = resp.Header.Write(&buf)
+	buf.WriteString("\r\n")
+	return buf.Bytes()
+}
+
 func headersToFastHTTPHeaders(h *headersList) *fasthttp.RequestHeader {
 	if len(*h) == 0 {
 		return nil
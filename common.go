@@ -27,6 +27,8 @@ var (
 	defaultTestDuration  = 10 * time.Second
 	defaultNumberOfConns = uint64(125)
 	defaultTimeout       = 2 * time.Second
+	defaultMaxRedirects  = uint64(10)
+	defaultPercentiles   = percentileList{0.5, 0.75, 0.9, 0.95, 0.99}
 
 	httpMethods = []string{
 		"GET", "POST", "PUT", "DELETE", "HEAD", "OPTIONS",
@@ -53,6 +55,106 @@ var (
 		"rate can't be less than 1")
 	errBodyProvidedTwice = errors.New("use either --body or --body-file")
 
+	errHandshakeWithoutTLS = errors.New(
+		"TLS handshake benchmarking requires https URL")
+	errResumptionWithoutHandshake = errors.New(
+		"session resumption can only be used with --tls-handshake")
+	errTemplatedStream = errors.New(
+		"streamed body can't be templated")
+
+	errDataExhausted       = errors.New("data file exhausted")
+	errEmptyDataFile       = errors.New("data file has no rows")
+	errNotEnoughUniqueRows = errors.New(
+		"unique data feeding requires at least one row per connection")
+	errUnusedData = errors.New(
+		"data file rows are only available to templates, use --data " +
+			"with --request-templates, --scenario or --mix")
+
+	errEmptyScenario    = errors.New("scenario has no steps")
+	errAbsoluteStepURL  = errors.New("step path must be relative to the URL")
+	errNoExtractVar     = errors.New("extracted variable must have a name")
+	errScenarioWithBody = errors.New(
+		"scenario steps have their own bodies, don't use --body, " +
+			"--body-file, --form or --stream with --scenario")
+
+	errEmptyReplay    = errors.New("no requests to replay")
+	errReplayWithBody = errors.New(
+		"replayed requests have their own bodies, don't use --body, " +
+			"--body-file, --form, --stream, --data or --scenario " +
+			"with --replay")
+	errInvalidReplaySpeed = errors.New("--replay-speed must be positive")
+
+	errTemplatesWithRequestSource = errors.New(
+		"scenario steps and requests of the mix are always templates and " +
+			"replayed requests are never templated, don't use " +
+			"--request-templates with --scenario, --mix or --replay")
+
+	errEmptyMix         = errors.New("request mix has no requests")
+	errInvalidMixWeight = errors.New("weight must be positive")
+	errMixWithBody      = errors.New(
+		"requests of the mix have their own bodies, don't use --body, " +
+			"--body-file, --form, --stream, --scenario or --replay " +
+			"with --mix")
+
+	errInvalidThinkTime = errors.New(
+		"think time must be a duration, a range of durations " +
+			"(e.g. 100ms-2s), exp:<mean> or file:<path>")
+
+	errInvalidPercentile = errors.New(
+		"percentiles must be comma-separated numbers in (0, 100] range")
+	errInvalidHistogramDigits = errors.New(
+		"histogram digits must be between 1 and 4")
+	errInvalidHDRLogInterval = errors.New(
+		"--hdr-log-interval must not be negative")
+
+	errInvalidReportSpec = errors.New(
+		"report must be statsd:<host:port>, graphite:<host:port> or " +
+			"influxdb:<URL>")
+	errInvalidReportTag      = errors.New("report tag must be in key=value format")
+	errInvalidReportInterval = errors.New(
+		"--report-interval must not be negative")
+
+	errInvalidOTLPEndpoint = errors.New(
+		"--otlp-endpoint must be an http(s) URL of OTLP/HTTP collector")
+
+	errNegativeTolerance   = errors.New("tolerances must not be negative")
+	errInvalidSignificance = errors.New(
+		"--significance must be greater than 0 and less than 1")
+
+	errRedirectLoop = errors.New("redirect loop detected")
+
+	errMultipleAuthMethods = errors.New(
+		"use only one of --basic-auth, --bearer-token-file, " +
+			"--bearer-token-cmd, --oauth2-token-url and --aws-sigv4")
+	errInvalidBasicAuth = errors.New(
+		"basic auth credentials must be in user:password format")
+	errSigningStream = errors.New(
+		"streamed body can't be signed, don't use --stream with " +
+			"--hmac-secret or --aws-sigv4")
+	errInvalidAWSSigV4 = errors.New(
+		"AWS Signature V4 spec must be in region:service format")
+	errNoAWSCredentials = errors.New(
+		"AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY must be set " +
+			"for AWS Signature V4")
+	errEmptyToken       = errors.New("bearer token is empty")
+	errIncompleteOAuth2 = errors.New(
+		"--oauth2-token-url requires --oauth2-client-id and " +
+			"--oauth2-client-secret")
+
+	errInvalidFormFieldFormat = errors.New(
+		"form field must be in key=value or key=@path format")
+	errFormWithBody = errors.New(
+		"use either --form or --body/--body-file")
+
+	errUnknownEncoding = errors.New("unknown content encoding")
+
+	errInvalidLogSample = errors.New(
+		"--log-sample must be greater than 0 and not greater than 1")
+
+	errInvalidOutputFormat = errors.New(
+		"output must be in format=<spec>,file=<path> format, " +
+			"optionally followed by ,append=<bool> and ,header=<bool>")
+
 	errInvalidHeaderFormat = errors.New("invalid header format")
 	errEmptyPrintSpec      = errors.New(
 		"empty print spec is not a valid print spec")
@@ -0,0 +1,370 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math"
+	"sort"
+	"strconv"
+
+	"github.com/alecthomas/kingpin"
+)
+
+const (
+	compareCommand = "compare"
+
+	// exitRegression is the exit code of compare, when regressions
+	// are found, to tell them apart from failures.
+	exitRegression = 2
+
+	defaultRPSTolerance        = 5.0
+	defaultLatencyTolerance    = 10.0
+	defaultThroughputTolerance = 5.0
+	defaultErrorTolerance      = 1.0
+	defaultSignificance        = 0.05
+)
+
+// compareConfig holds tolerances in percents, except errorTolerance,
+// which is in percentage points of the error rate.
+type compareConfig struct {
+	baselinePath, currentPath string
+
+	rpsTolerance        float64
+	latencyTolerance    float64
+	throughputTolerance float64
+	errorTolerance      float64
+	significance        float64
+}
+
+func parseCompareArgs(args []string) (compareConfig, error) {
+	c := compareConfig{
+		rpsTolerance:        defaultRPSTolerance,
+		latencyTolerance:    defaultLatencyTolerance,
+		throughputTolerance: defaultThroughputTolerance,
+		errorTolerance:      defaultErrorTolerance,
+		significance:        defaultSignificance,
+	}
+	app := kingpin.New(args[0]+" "+compareCommand,
+		"Compare two results produced by the json format and detect "+
+			"regressions")
+	app.Flag("rps-tolerance", "Allowed decrease of requests per "+
+		"second, in percents").
+		PlaceHolder(strconv.FormatFloat(defaultRPSTolerance, 'f', -1, 64)).
+		Float64Var(&c.rpsTolerance)
+	app.Flag("latency-tolerance", "Allowed increase of average "+
+		"latency and latency percentiles, in percents").
+		PlaceHolder(strconv.FormatFloat(defaultLatencyTolerance, 'f', -1, 64)).
+		Float64Var(&c.latencyTolerance)
+	app.Flag("throughput-tolerance", "Allowed decrease of "+
+		"throughput, in percents").
+		PlaceHolder(strconv.FormatFloat(defaultThroughputTolerance, 'f', -1, 64)).
+		Float64Var(&c.throughputTolerance)
+	app.Flag("error-tolerance", "Allowed increase of error rate, "+
+		"in percentage points").
+		PlaceHolder(strconv.FormatFloat(defaultErrorTolerance, 'f', -1, 64)).
+		Float64Var(&c.errorTolerance)
+	app.Flag("significance", "Significance level of tests, which "+
+		"tell regressions from noise, where possible").
+		PlaceHolder(strconv.FormatFloat(defaultSignificance, 'f', -1, 64)).
+		Float64Var(&c.significance)
+	app.Arg("baseline", "Results to compare against").
+		Required().
+		StringVar(&c.baselinePath)
+	app.Arg("current", "Results to check for regressions").
+		Required().
+		StringVar(&c.currentPath)
+	if _, err := app.Parse(args[2:]); err != nil {
+		return c, err
+	}
+	if c.rpsTolerance < 0 || c.latencyTolerance < 0 ||
+		c.throughputTolerance < 0 || c.errorTolerance < 0 {
+		return c, errNegativeTolerance
+	}
+	if c.significance <= 0 || c.significance >= 1 {
+		return c, errInvalidSignificance
+	}
+	return c, nil
+}
+
+// jsonResults is the part of the json format output compare uses.
+type jsonResults struct {
+	Result struct {
+		BytesRead        int64   `json:"bytesRead"`
+		BytesWritten     int64   `json:"bytesWritten"`
+		TimeTakenSeconds float64 `json:"timeTakenSeconds"`
+		Req1xx           uint64  `json:"req1xx"`
+		Req2xx           uint64  `json:"req2xx"`
+		Req3xx           uint64  `json:"req3xx"`
+		Req4xx           uint64  `json:"req4xx"`
+		Req5xx           uint64  `json:"req5xx"`
+		Others           uint64  `json:"others"`
+		Latency          *struct {
+			Mean        float64            `json:"mean"`
+			Stddev      float64            `json:"stddev"`
+			Percentiles map[string]float64 `json:"percentiles"`
+		} `json:"latency"`
+		RPS *struct {
+			Mean float64 `json:"mean"`
+		} `json:"rps"`
+	} `json:"result"`
+}
+
+func readJSONResults(path string) (*jsonResults, error) {
+	bytes, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	res := new(jsonResults)
+	if err := json.Unmarshal(bytes, res); err != nil {
+		return nil, fmt.Errorf("invalid results %q: %v", path, err)
+	}
+	return res, nil
+}
+
+func (r *jsonResults) requests() uint64 {
+	res := r.Result
+	return res.Req1xx + res.Req2xx + res.Req3xx + res.Req4xx + res.Req5xx +
+		res.Others
+}
+
+// errors are responses with 4xx and 5xx codes and requests, which
+// got no response at all.
+func (r *jsonResults) errors() uint64 {
+	return r.Result.Req4xx + r.Result.Req5xx + r.Result.Others
+}
+
+func (r *jsonResults) errorRate() float64 {
+	if r.requests() == 0 {
+		return 0
+	}
+	return 100 * float64(r.errors()) / float64(r.requests())
+}
+
+func (r *jsonResults) throughput() float64 {
+	if r.Result.TimeTakenSeconds == 0 {
+		return 0
+	}
+	return float64(r.Result.BytesRead+r.Result.BytesWritten) /
+		r.Result.TimeTakenSeconds
+}
+
+// metricComparison is a single line of the comparison.
+type metricComparison struct {
+	name              string
+	baseline, current float64
+	format            func(float64) string
+
+	higherIsBetter bool
+	// tolerance is in percentage points, if delta is absolute,
+	// and in percents otherwise.
+	absolute  bool
+	tolerance float64
+
+	// pValue of the one-sided test of the change being for the worse
+	// or -1, if there is no test for the metric.
+	pValue float64
+}
+
+func (m *metricComparison) delta() float64 {
+	if m.absolute {
+		return m.current - m.baseline
+	}
+	if m.baseline == 0 {
+		if m.current == 0 {
+			return 0
+		}
+		return math.Inf(int(math.Copysign(1, m.current)))
+	}
+	return 100 * (m.current - m.baseline) / math.Abs(m.baseline)
+}
+
+func (m *metricComparison) formatDelta() string {
+	d := m.delta()
+	if m.absolute {
+		return fmt.Sprintf("%+.2fpp", d)
+	}
+	if math.IsInf(d, 0) {
+		return "n/a"
+	}
+	return fmt.Sprintf("%+.2f%%", d)
+}
+
+func (m *metricComparison) regression(significance float64) bool {
+	worsening := m.delta()
+	if m.higherIsBetter {
+		worsening = -worsening
+	}
+	if worsening <= m.tolerance {
+		return false
+	}
+	return m.pValue < 0 || m.pValue < significance
+}
+
+func compareResults(
+	c compareConfig, baseline, current *jsonResults,
+) []*metricComparison {
+	var res []*metricComparison
+	if baseline.Result.RPS != nil && current.Result.RPS != nil {
+		res = append(res, &metricComparison{
+			name:           "Reqs/sec",
+			baseline:       baseline.Result.RPS.Mean,
+			current:        current.Result.RPS.Mean,
+			format:         formatRPS,
+			higherIsBetter: true,
+			tolerance:      c.rpsTolerance,
+			pValue:         -1,
+		})
+	}
+	bl, cl := baseline.Result.Latency, current.Result.Latency
+	if bl != nil && cl != nil {
+		res = append(res, &metricComparison{
+			name:      "Latency",
+			baseline:  bl.Mean,
+			current:   cl.Mean,
+			format:    formatTimeUs,
+			tolerance: c.latencyTolerance,
+			pValue: meansPValue(
+				bl.Mean, bl.Stddev, baseline.requests(),
+				cl.Mean, cl.Stddev, current.requests(),
+			),
+		})
+		var pcs []string
+		for k := range bl.Percentiles {
+			if _, ok := cl.Percentiles[k]; ok {
+				pcs = append(pcs, k)
+			}
+		}
+		sort.Slice(pcs, func(i, j int) bool {
+			pi, _ := strconv.ParseFloat(pcs[i], 64)
+			pj, _ := strconv.ParseFloat(pcs[j], 64)
+			return pi < pj
+		})
+		for _, k := range pcs {
+			res = append(res, &metricComparison{
+				name:      "Latency " + k + "%",
+				baseline:  bl.Percentiles[k],
+				current:   cl.Percentiles[k],
+				format:    formatTimeUs,
+				tolerance: c.latencyTolerance,
+				pValue:    -1,
+			})
+		}
+	}
+	res = append(res, &metricComparison{
+		name:      "Errors",
+		baseline:  baseline.errorRate(),
+		current:   current.errorRate(),
+		format:    formatRate,
+		absolute:  true,
+		tolerance: c.errorTolerance,
+		pValue: proportionsPValue(
+			baseline.errors(), baseline.requests(),
+			current.errors(), current.requests(),
+		),
+	}, &metricComparison{
+		name:           "Throughput",
+		baseline:       baseline.throughput(),
+		current:        current.throughput(),
+		format:         formatThroughput,
+		higherIsBetter: true,
+		tolerance:      c.throughputTolerance,
+		pValue:         -1,
+	})
+	return res
+}
+
+// meansPValue is the p-value of the one-sided z-test of the second
+// mean being greater than the first one, number of requests is large
+// enough for z-test to be used instead of t-test.
+func meansPValue(m1, s1 float64, n1 uint64, m2, s2 float64, n2 uint64) float64 {
+	if n1 < 2 || n2 < 2 {
+		return -1
+	}
+	se := math.Sqrt(s1*s1/float64(n1) + s2*s2/float64(n2))
+	return zPValue(m2-m1, se)
+}
+
+// proportionsPValue is the p-value of the one-sided z-test of the
+// second proportion being greater than the first one.
+func proportionsPValue(k1, n1, k2, n2 uint64) float64 {
+	if n1 == 0 || n2 == 0 {
+		return -1
+	}
+	p1, p2 := float64(k1)/float64(n1), float64(k2)/float64(n2)
+	p := float64(k1+k2) / float64(n1+n2)
+	se := math.Sqrt(p * (1 - p) * (1/float64(n1) + 1/float64(n2)))
+	return zPValue(p2-p1, se)
+}
+
+func zPValue(diff, se float64) float64 {
+	if se == 0 {
+		if diff > 0 {
+			return 0
+		}
+		return 1
+	}
+	return 0.5 * math.Erfc(diff/se/math.Sqrt2)
+}
+
+func formatRPS(rps float64) string {
+	return strconv.FormatFloat(rps, 'f', 2, 64)
+}
+
+func formatRate(rate float64) string {
+	return strconv.FormatFloat(rate, 'f', 2, 64) + "%"
+}
+
+func formatThroughput(bps float64) string {
+	return formatBinary(bps) + "/s"
+}
+
+// printComparison returns the number of regressions found.
+func printComparison(
+	out io.Writer, c compareConfig, comparisons []*metricComparison,
+) int {
+	regressions := 0
+	fmt.Fprintf(out, "%-16v %12v %12v %10v\n",
+		"Comparison", "Baseline", "Current", "Delta")
+	for _, m := range comparisons {
+		fmt.Fprintf(out, "  %-14v %12v %12v %10v",
+			m.name, m.format(m.baseline), m.format(m.current),
+			m.formatDelta())
+		if m.regression(c.significance) {
+			regressions++
+			fmt.Fprint(out, "  regression")
+		}
+		fmt.Fprintln(out)
+	}
+	if regressions == 0 {
+		fmt.Fprintln(out, "No regressions found.")
+	} else {
+		fmt.Fprintf(out, "%v regression(s) found.\n", regressions)
+	}
+	return regressions
+}
+
+// runCompare implements the compare command and returns the exit
+// code.
+func runCompare(args []string, out io.Writer) int {
+	c, err := parseCompareArgs(args)
+	if err != nil {
+		fmt.Fprintln(out, "Error parsing the arguments:", err)
+		return exitFailure
+	}
+	baseline, err := readJSONResults(c.baselinePath)
+	if err != nil {
+		fmt.Fprintln(out, "Error reading the results:", err)
+		return exitFailure
+	}
+	current, err := readJSONResults(c.currentPath)
+	if err != nil {
+		fmt.Fprintln(out, "Error reading the results:", err)
+		return exitFailure
+	}
+	if printComparison(out, c, compareResults(c, baseline, current)) > 0 {
+		return exitRegression
+	}
+	return 0
+}
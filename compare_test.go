@@ -0,0 +1,168 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+const testBaselineResults = `{"spec":{"numberOfConnections":125},` +
+	`"result":{"bytesRead":1000000,"bytesWritten":500000,` +
+	`"timeTakenSeconds":10,"req1xx":0,"req2xx":9990,"req3xx":0,` +
+	`"req4xx":0,"req5xx":10,"others":0,` +
+	`"latency":{"mean":1000,"stddev":100,"max":5000,` +
+	`"percentiles":{"50":900,"99":2000,"99.9":3000}},` +
+	`"rps":{"mean":1000,"stddev":10,"max":1100,` +
+	`"percentiles":{"50":1000.000000}}}}`
+
+func testResults(t *testing.T, patch func(*jsonResults)) *jsonResults {
+	res := new(jsonResults)
+	if err := json.Unmarshal([]byte(testBaselineResults), res); err != nil {
+		t.Fatal(err)
+	}
+	if patch != nil {
+		patch(res)
+	}
+	return res
+}
+
+func TestParseCompareArgs(t *testing.T) {
+	c, err := parseCompareArgs([]string{
+		programName, compareCommand, "--latency-tolerance", "20",
+		"--error-tolerance=0.5", "old.json", "new.json",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	exp := compareConfig{
+		baselinePath:        "old.json",
+		currentPath:         "new.json",
+		rpsTolerance:        defaultRPSTolerance,
+		latencyTolerance:    20,
+		throughputTolerance: defaultThroughputTolerance,
+		errorTolerance:      0.5,
+		significance:        defaultSignificance,
+	}
+	if c != exp {
+		t.Errorf("expected %+v, but got %+v", exp, c)
+	}
+	invalid := []struct {
+		args []string
+		err  error
+	}{
+		{[]string{"--rps-tolerance=-1"}, errNegativeTolerance},
+		{[]string{"--significance=0"}, errInvalidSignificance},
+		{[]string{"--significance=1"}, errInvalidSignificance},
+	}
+	for _, i := range invalid {
+		args := append([]string{programName, compareCommand}, i.args...)
+		args = append(args, "old.json", "new.json")
+		if _, err := parseCompareArgs(args); err != i.err {
+			t.Errorf("%v: expected %v, but got %v", i.args, i.err, err)
+		}
+	}
+	if _, err := parseCompareArgs(
+		[]string{programName, compareCommand, "old.json"},
+	); err == nil {
+		t.Error("should fail without current results")
+	}
+}
+
+func TestCompareResults(t *testing.T) {
+	c := compareConfig{
+		rpsTolerance:        defaultRPSTolerance,
+		latencyTolerance:    defaultLatencyTolerance,
+		throughputTolerance: defaultThroughputTolerance,
+		errorTolerance:      defaultErrorTolerance,
+		significance:        defaultSignificance,
+	}
+	baseline := testResults(t, nil)
+	current := testResults(t, func(r *jsonResults) {
+		r.Result.RPS.Mean = 960
+		r.Result.Latency.Mean = 1200
+		r.Result.Latency.Percentiles["99"] = 2100
+		delete(r.Result.Latency.Percentiles, "99.9")
+		r.Result.Req2xx -= 300
+		r.Result.Req5xx += 300
+		r.Result.BytesRead = 500000
+	})
+	comparisons := compareResults(c, baseline, current)
+	expected := []struct {
+		name       string
+		delta      float64
+		regression bool
+	}{
+		{"Reqs/sec", -4, false},
+		{"Latency", 20, true},
+		{"Latency 50%", 0, false},
+		{"Latency 99%", 5, false},
+		{"Errors", 3, true},
+		{"Throughput", -100.0 / 3, true},
+	}
+	if len(comparisons) != len(expected) {
+		t.Fatalf("expected %v comparisons, but got %v",
+			len(expected), len(comparisons))
+	}
+	for i, e := range expected {
+		m := comparisons[i]
+		if m.name != e.name {
+			t.Errorf("expected %q, but got %q", e.name, m.name)
+		}
+		if d := m.delta(); d < e.delta-1e-9 || d > e.delta+1e-9 {
+			t.Errorf("%v: expected delta %v, but got %v", m.name, e.delta, d)
+		}
+		if r := m.regression(c.significance); r != e.regression {
+			t.Errorf("%v: expected regression to be %v, but got %v",
+				m.name, e.regression, r)
+		}
+	}
+}
+
+func TestCompareIgnoresNoise(t *testing.T) {
+	c := compareConfig{latencyTolerance: 10, significance: 0.05}
+	baseline := testResults(t, func(r *jsonResults) {
+		r.Result.Latency.Stddev = 100000
+	})
+	current := testResults(t, func(r *jsonResults) {
+		r.Result.Latency.Mean = 1500
+		r.Result.Latency.Stddev = 100000
+	})
+	latency := compareResults(c, baseline, current)[1]
+	if latency.name != "Latency" || latency.regression(c.significance) {
+		t.Errorf("increase within noise shouldn't be a regression: %+v",
+			latency)
+	}
+}
+
+func TestRunCompare(t *testing.T) {
+	baseline := writeDataFile(t, "baseline.json", testBaselineResults)
+	slower := writeDataFile(t, "slower.json", strings.Replace(
+		testBaselineResults, `"rps":{"mean":1000`, `"rps":{"mean":800`, 1,
+	))
+	expectations := []struct {
+		args []string
+		code int
+		out  string
+	}{
+		{[]string{baseline, baseline}, 0, "No regressions found."},
+		{[]string{baseline, slower}, exitRegression, "1 regression(s) found."},
+		{
+			[]string{"--rps-tolerance=25", baseline, slower}, 0,
+			"No regressions found.",
+		},
+		{[]string{baseline, "missing.json"}, exitFailure, "Error reading"},
+	}
+	for _, e := range expectations {
+		out := new(bytes.Buffer)
+		args := append([]string{programName, compareCommand}, e.args...)
+		if code := runCompare(args, out); code != e.code {
+			t.Errorf("%v: expected exit code %v, but got %v",
+				e.args, e.code, code)
+		}
+		if !strings.Contains(out.String(), e.out) {
+			t.Errorf("%v: expected output to contain %q, but got:\n%v",
+				e.args, e.out, out)
+		}
+	}
+}
@@ -0,0 +1,175 @@
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"io"
+	"io/ioutil"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/andybalholm/brotli"
+	"github.com/klauspost/compress/zstd"
+)
+
+var bodyEncodings = []string{"gzip", "zstd", "br"}
+
+func isKnownBodyEncoding(encoding string) bool {
+	for _, e := range bodyEncodings {
+		if e == encoding {
+			return true
+		}
+	}
+	return false
+}
+
+// encoder is a compressing writer, which can be reused.
+type encoder interface {
+	io.WriteCloser
+	Reset(w io.Writer)
+}
+
+// encoders keep the writers of each encoding for reuse, since setting
+// them up (especially zstd ones) is expensive. zstd writers encode
+// synchronously and thus don't need to be closed.
+var encoders = map[string]*sync.Pool{
+	"gzip": {New: func() interface{} {
+		return gzip.NewWriter(nil)
+	}},
+	"zstd": {New: func() interface{} {
+		enc, _ := zstd.NewWriter(nil, zstd.WithEncoderConcurrency(1))
+		return enc
+	}},
+	"br": {New: func() interface{} {
+		return brotli.NewWriter(nil)
+	}},
+}
+
+// compress writes everything read from r into w compressed using
+// encoding.
+func compress(encoding string, w io.Writer, r io.Reader) error {
+	pool, ok := encoders[encoding]
+	if !ok {
+		return errUnknownEncoding
+	}
+	enc := pool.Get().(encoder)
+	defer pool.Put(enc)
+	enc.Reset(w)
+	_, err := io.Copy(enc, r)
+	if cerr := enc.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+func compressBytes(encoding string, data []byte) ([]byte, error) {
+	buf := new(bytes.Buffer)
+	if err := compress(encoding, buf, bytes.NewReader(data)); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// compressStream wraps bsp, so that produced bodies are compressed on
+// the fly.
+func compressStream(encoding string, bsp bodyStreamProducer) bodyStreamProducer {
+	return func() (io.ReadCloser, error) {
+		body, err := bsp()
+		if err != nil {
+			return nil, err
+		}
+		pr, pw := io.Pipe()
+		go func() {
+			defer body.Close()
+			pw.CloseWithError(compress(encoding, pw, body))
+		}()
+		return pr, nil
+	}
+}
+
+// bodyCompressor is implemented by request preparers, which can
+// compress bodies of the requests they prepare. Bodies that are the
+// same for every request are compressed only once.
+type bodyCompressor interface {
+	compressBodies(encoding string) error
+}
+
+// Readers of each encoding are reused the same way writers are.
+var gzipReaders, zlibReaders, brotliReaders, zstdReaders sync.Pool
+
+// decodedLength returns the length of the body after removing
+// Content-Encoding (possibly, more than one) from it.
+func decodedLength(contentEncoding string, body []byte) (int64, error) {
+	var r io.Reader = bytes.NewReader(body)
+	encodings := strings.Split(contentEncoding, ",")
+	for i := len(encodings) - 1; i >= 0; i-- {
+		var err error
+		switch strings.ToLower(strings.TrimSpace(encodings[i])) {
+		case "", "identity":
+		case "gzip", "x-gzip":
+			gr, ok := gzipReaders.Get().(*gzip.Reader)
+			if !ok {
+				gr = new(gzip.Reader)
+			}
+			defer gzipReaders.Put(gr)
+			err = gr.Reset(r)
+			r = gr
+		case "deflate":
+			zr, ok := zlibReaders.Get().(io.ReadCloser)
+			if ok {
+				err = zr.(zlib.Resetter).Reset(r, nil)
+			} else if zr, err = zlib.NewReader(r); err != nil {
+				break
+			}
+			defer zlibReaders.Put(zr)
+			r = zr
+		case "br":
+			br, ok := brotliReaders.Get().(*brotli.Reader)
+			if !ok {
+				br = brotli.NewReader(nil)
+			}
+			defer brotliReaders.Put(br)
+			err = br.Reset(r)
+			r = br
+		case "zstd":
+			zr, ok := zstdReaders.Get().(*zstd.Decoder)
+			if !ok {
+				// decoders with concurrency of 1 don't start any
+				// goroutines, so they don't have to be closed
+				zr, err = zstd.NewReader(nil, zstd.WithDecoderConcurrency(1))
+				if err != nil {
+					break
+				}
+			}
+			defer zstdReaders.Put(zr)
+			err = zr.Reset(r)
+			r = zr
+		default:
+			return 0, errUnknownEncoding
+		}
+		if err != nil {
+			return 0, err
+		}
+	}
+	return io.Copy(ioutil.Discard, r)
+}
+
+// bodyStats accumulates sizes of response bodies as they were received
+// and after decompression. Bodies that couldn't be decompressed are
+// only counted.
+type bodyStats struct {
+	compressed, decompressed uint64
+	failures                 uint64
+}
+
+func (b *bodyStats) record(contentEncoding string, body []byte) {
+	n, err := decodedLength(contentEncoding, body)
+	if err != nil {
+		atomic.AddUint64(&b.failures, 1)
+		return
+	}
+	atomic.AddUint64(&b.compressed, uint64(len(body)))
+	atomic.AddUint64(&b.decompressed, uint64(n))
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"io"
+	"io/ioutil"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestCompressionRoundTrip(t *testing.T) {
+	data := strings.Repeat("abracadabra", 100)
+	for _, encoding := range bodyEncodings {
+		compressed, err := compressBytes(encoding, []byte(data))
+		if err != nil {
+			t.Fatal(encoding, err)
+		}
+		if len(compressed) >= len(data) {
+			t.Errorf("%v: body wasn't compressed", encoding)
+		}
+		n, err := decodedLength(encoding, compressed)
+		if err != nil {
+			t.Fatal(encoding, err)
+		}
+		if n != int64(len(data)) {
+			t.Errorf("%v: expected %v decompressed bytes, but got %v",
+				encoding, len(data), n)
+		}
+
+		stream, err := compressStream(encoding, func() (io.ReadCloser, error) {
+			return ioutil.NopCloser(strings.NewReader(data)), nil
+		})()
+		if err != nil {
+			t.Fatal(encoding, err)
+		}
+		streamed, err := ioutil.ReadAll(stream)
+		if err != nil {
+			t.Fatal(encoding, err)
+		}
+		if n, err := decodedLength(encoding, streamed); err != nil ||
+			n != int64(len(data)) {
+			t.Errorf("%v: invalid compressed stream: %v, %v",
+				encoding, n, err)
+		}
+	}
+}
+
+func TestDecodedLength(t *testing.T) {
+	if n, err := decodedLength("", []byte("plain")); err != nil || n != 5 {
+		t.Errorf("expected identity encoding, got %v, %v", n, err)
+	}
+	if _, err := decodedLength("compress", []byte("data")); err != errUnknownEncoding {
+		t.Errorf("expected %v, but got %v", errUnknownEncoding, err)
+	}
+	if _, err := decodedLength("gzip", []byte("not gzipped")); err == nil {
+		t.Error("expected an error for invalid gzip body")
+	}
+}
+
+func TestBodyStats(t *testing.T) {
+	compressed, err := compressBytes("gzip", []byte("abracadabra"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var b bodyStats
+	b.record("gzip", compressed)
+	b.record("gzip", []byte("not gzipped"))
+	b.record("compress", []byte("data"))
+	if b.compressed != uint64(len(compressed)) ||
+		b.decompressed != uint64(len("abracadabra")) || b.failures != 2 {
+		t.Errorf("unexpected body stats: %+v", b)
+	}
+}
+
+func TestTemplatesCompressBodies(t *testing.T) {
+	u := ParseURLOrPanic("http://localhost:8080")
+	for _, body := range []string{"static body", "body #{{ .Counter }}"} {
+		rt, err := compileRequestTemplates(u, nil, &body, nil, true)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := rt.compressBodies("zstd"); err != nil {
+			t.Fatal(err)
+		}
+		for i := 0; i < 2; i++ {
+			rr, err := rt.prepare(newSession(0))
+			if err != nil {
+				t.Fatal(err)
+			}
+			expected := []header{{"Content-Encoding", "zstd"}}
+			if !reflect.DeepEqual(rr.headers, expected) {
+				t.Errorf("expected headers %v, but got %v", expected, rr.headers)
+			}
+			n, err := decodedLength("zstd", rr.body)
+			if err != nil {
+				t.Fatal(err)
+			}
+			exp := strings.Replace(body, "{{ .Counter }}", strconv.Itoa(i+1), 1)
+			if n != int64(len(exp)) {
+				t.Errorf("%q: expected %v decompressed bytes, but got %v",
+					body, len(exp), n)
+			}
+		}
+	}
+}
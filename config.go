@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"net/url"
 	"sort"
+	"strings"
 	"time"
 )
 
@@ -15,20 +16,91 @@ type config struct {
 	url                       *url.URL
 	method, certPath, keyPath string
 	body, bodyFilePath        string
+	form                      *formFields
 	stream                    bool
+	compressBody              string
+	acceptEncoding            string
+	sampleResponses           uint64
+	samplesDir                string
+	logRequestsPath           string
+	logSample                 float64
 	headers                   *headersList
 	timeout                   time.Duration
-	// TODO(codesenberg): printLatencies should probably be
-	// re(named&maked) into printPercentiles or even let
-	// users provide their own percentiles and not just
-	// calculate for [0.5, 0.75, 0.9, 0.99]
-	printLatencies, insecure bool
-	rate                     *uint64
-	clientType               clientTyp
+	printLatencies, insecure  bool
+	connStats                 bool
+	rate                      *uint64
+	thinkTime                 string
+	clientType                clientTyp
+
+	// percentiles is nil, unless user provided their own, and
+	// histogramDigits is zero, unless precision of histograms was
+	// set explicitly.
+	percentiles     *percentileList
+	histogramDigits uint64
+
+	// hdrLogInterval is zero, unless a histogram has to be logged
+	// for each interval rather than for the whole test.
+	hdrLogPath     string
+	hdrLogInterval time.Duration
+	hgrmPath       string
+
+	// reports is nil, unless metrics have to be sent during the
+	// test, reportInterval is zero, unless set explicitly.
+	reports        *reportList
+	reportInterval time.Duration
+	reportTags     *tagList
+
+	// traceSlowest is zero, unless the number of the slowest traced
+	// requests was set explicitly.
+	traceparent  bool
+	otlpEndpoint string
+	traceSlowest uint64
+
+	tlsHandshake, tlsResumption bool
+
+	requestTemplates bool
+
+	dataFilePath  string
+	dataMode      feedMode
+	dataStopAtEOF bool
+
+	scenarioPath string
+
+	mixPath string
+
+	// replaySpeed is zero, unless the original timing of the
+	// replayed requests has to be kept.
+	replayPath    string
+	replayHeaders string
+	replaySpeed   float64
+
+	cookies        bool
+	cookieFilePath string
+
+	// maxRedirects is the maximum number of redirects to follow,
+	// zero means that redirects aren't followed.
+	maxRedirects uint64
+
+	basicAuth          string
+	bearerTokenFile    string
+	bearerTokenCmd     string
+	bearerTokenRefresh time.Duration
+	hmacSecret         string
+	hmacHeader         string
+	awsSigV4           string
+
+	oauth2TokenURL     string
+	oauth2ClientID     string
+	oauth2ClientSecret string
+	oauth2Scope        string
 
 	printIntro, printProgress, printResult bool
 
 	format format
+	// noHeader omits the header of the csv format.
+	noHeader bool
+	// outputs is nil, unless the result has to be written to files.
+	outputs *outputList
 }
 
 type testTyp int
@@ -56,7 +128,12 @@ func (c *config) checkArgs() error {
 		c.checkRunParameters,
 		c.checkTimeoutDuration,
 		c.checkHTTPParameters,
+		c.checkRequestLogParameters,
+		c.checkRequestSourceParameters,
 		c.checkCertPaths,
+		c.checkTLSHandshakeParameters,
+		c.checkAuthParameters,
+		c.checkOutputParameters,
 	}
 
 	for _, check := range checks {
@@ -122,12 +199,58 @@ func (c *config) checkHTTPParameters() error {
 	if !allowedHTTPMethod(c.method) {
 		return &invalidHTTPMethodError{method: c.method}
 	}
-	if !canHaveBody(c.method) && (c.body != "" || c.bodyFilePath != "") {
+	if !canHaveBody(c.method) &&
+		(c.body != "" || c.bodyFilePath != "" || c.form != nil) {
 		return errBodyNotAllowed
 	}
 	if c.body != "" && c.bodyFilePath != "" {
 		return errBodyProvidedTwice
 	}
+	if c.form != nil && (c.body != "" || c.bodyFilePath != "") {
+		return errFormWithBody
+	}
+	if c.requestTemplates && c.stream && isTemplated(c.body) {
+		return errTemplatedStream
+	}
+	if c.compressBody != "" && !isKnownBodyEncoding(c.compressBody) {
+		return errUnknownEncoding
+	}
+	return nil
+}
+
+func (c *config) checkRequestLogParameters() error {
+	if c.logRequestsPath != "" && (c.logSample <= 0 || c.logSample > 1) {
+		return errInvalidLogSample
+	}
+	return nil
+}
+
+func (c *config) checkRequestSourceParameters() error {
+	if c.scenarioPath != "" &&
+		(c.body != "" || c.bodyFilePath != "" || c.form != nil || c.stream) {
+		return errScenarioWithBody
+	}
+	if c.replayPath != "" &&
+		(c.body != "" || c.bodyFilePath != "" || c.form != nil ||
+			c.stream || c.dataFilePath != "" || c.scenarioPath != "") {
+		return errReplayWithBody
+	}
+	if c.replaySpeed < 0 {
+		return errInvalidReplaySpeed
+	}
+	if c.mixPath != "" &&
+		(c.body != "" || c.bodyFilePath != "" || c.form != nil ||
+			c.stream || c.scenarioPath != "" || c.replayPath != "") {
+		return errMixWithBody
+	}
+	if c.requestTemplates &&
+		(c.scenarioPath != "" || c.mixPath != "" || c.replayPath != "") {
+		return errTemplatesWithRequestSource
+	}
+	if c.dataFilePath != "" && !c.requestTemplates &&
+		c.scenarioPath == "" && c.mixPath == "" {
+		return errUnusedData
+	}
 	return nil
 }
 
@@ -140,6 +263,60 @@ func (c *config) checkCertPaths() error {
 	return nil
 }
 
+func (c *config) checkTLSHandshakeParameters() error {
+	if c.tlsResumption && !c.tlsHandshake {
+		return errResumptionWithoutHandshake
+	}
+	if c.tlsHandshake && c.url.Scheme != "https" {
+		return errHandshakeWithoutTLS
+	}
+	return nil
+}
+
+func (c *config) checkAuthParameters() error {
+	methods := 0
+	for _, m := range []string{
+		c.basicAuth, c.bearerTokenFile, c.bearerTokenCmd,
+		c.oauth2TokenURL, c.awsSigV4,
+	} {
+		if m != "" {
+			methods++
+		}
+	}
+	if methods > 1 {
+		return errMultipleAuthMethods
+	}
+	if c.basicAuth != "" && !strings.Contains(c.basicAuth, ":") {
+		return errInvalidBasicAuth
+	}
+	if c.oauth2TokenURL != "" &&
+		(c.oauth2ClientID == "" || c.oauth2ClientSecret == "") {
+		return errIncompleteOAuth2
+	}
+	if c.stream && (c.hmacSecret != "" || c.awsSigV4 != "") {
+		return errSigningStream
+	}
+	return nil
+}
+
+func (c *config) checkOutputParameters() error {
+	if c.histogramDigits > maxHistogramDigits {
+		return errInvalidHistogramDigits
+	}
+	if c.hdrLogInterval < 0 {
+		return errInvalidHDRLogInterval
+	}
+	if c.reportInterval < 0 {
+		return errInvalidReportInterval
+	}
+	if c.otlpEndpoint != "" {
+		if _, err := otlpURL(c.otlpEndpoint); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 func (c *config) timeoutMillis() uint64 {
 	return uint64(c.timeout.Nanoseconds() / 1000)
 }
@@ -246,6 +246,311 @@ func TestCheckArgs(t *testing.T) {
 			},
 			errBodyProvidedTwice,
 		},
+		{
+			config{
+				numConns:     defaultNumberOfConns,
+				numReqs:      &defaultNumberOfReqs,
+				url:          ParseURLOrPanic("http://localhost:8080"),
+				headers:      noHeaders,
+				timeout:      defaultTimeout,
+				method:       "GET",
+				tlsHandshake: true,
+				format:       knownFormat("plain-text"),
+			},
+			errHandshakeWithoutTLS,
+		},
+		{
+			config{
+				numConns:      defaultNumberOfConns,
+				numReqs:       &defaultNumberOfReqs,
+				url:           ParseURLOrPanic("https://localhost:8080"),
+				headers:       noHeaders,
+				timeout:       defaultTimeout,
+				method:        "GET",
+				tlsResumption: true,
+				format:        knownFormat("plain-text"),
+			},
+			errResumptionWithoutHandshake,
+		},
+		{
+			config{
+				numConns:         defaultNumberOfConns,
+				numReqs:          &defaultNumberOfReqs,
+				url:              ParseURLOrPanic("http://localhost:8080"),
+				headers:          noHeaders,
+				timeout:          defaultTimeout,
+				method:           "POST",
+				body:             "{{ .Counter }}",
+				stream:           true,
+				requestTemplates: true,
+				format:           knownFormat("plain-text"),
+			},
+			errTemplatedStream,
+		},
+		{
+			config{
+				numConns:     defaultNumberOfConns,
+				numReqs:      &defaultNumberOfReqs,
+				url:          ParseURLOrPanic("http://localhost:8080"),
+				headers:      noHeaders,
+				timeout:      defaultTimeout,
+				method:       "POST",
+				body:         "abracadabra",
+				scenarioPath: "scenario.json",
+				format:       knownFormat("plain-text"),
+			},
+			errScenarioWithBody,
+		},
+		{
+			config{
+				numConns:       defaultNumberOfConns,
+				numReqs:        &defaultNumberOfReqs,
+				url:            ParseURLOrPanic("http://localhost:8080"),
+				headers:        noHeaders,
+				timeout:        defaultTimeout,
+				method:         "GET",
+				basicAuth:      "user:password",
+				bearerTokenCmd: "echo token",
+				format:         knownFormat("plain-text"),
+			},
+			errMultipleAuthMethods,
+		},
+		{
+			config{
+				numConns:  defaultNumberOfConns,
+				numReqs:   &defaultNumberOfReqs,
+				url:       ParseURLOrPanic("http://localhost:8080"),
+				headers:   noHeaders,
+				timeout:   defaultTimeout,
+				method:    "GET",
+				basicAuth: "user",
+				format:    knownFormat("plain-text"),
+			},
+			errInvalidBasicAuth,
+		},
+		{
+			config{
+				numConns:   defaultNumberOfConns,
+				numReqs:    &defaultNumberOfReqs,
+				url:        ParseURLOrPanic("http://localhost:8080"),
+				headers:    noHeaders,
+				timeout:    defaultTimeout,
+				method:     "POST",
+				body:       "abracadabra",
+				stream:     true,
+				hmacSecret: "secret",
+				format:     knownFormat("plain-text"),
+			},
+			errSigningStream,
+		},
+		{
+			config{
+				numConns:       defaultNumberOfConns,
+				numReqs:        &defaultNumberOfReqs,
+				url:            ParseURLOrPanic("http://localhost:8080"),
+				headers:        noHeaders,
+				timeout:        defaultTimeout,
+				method:         "GET",
+				oauth2TokenURL: "http://localhost:8081/token",
+				oauth2ClientID: "client",
+				format:         knownFormat("plain-text"),
+			},
+			errIncompleteOAuth2,
+		},
+		{
+			config{
+				numConns: defaultNumberOfConns,
+				numReqs:  &defaultNumberOfReqs,
+				url:      ParseURLOrPanic("http://localhost:8080"),
+				headers:  noHeaders,
+				timeout:  defaultTimeout,
+				method:   "POST",
+				body:     "abracadabra",
+				form:     &formFields{{name: "key", value: "value"}},
+				format:   knownFormat("plain-text"),
+			},
+			errFormWithBody,
+		},
+		{
+			config{
+				numConns:     defaultNumberOfConns,
+				numReqs:      &defaultNumberOfReqs,
+				url:          ParseURLOrPanic("http://localhost:8080"),
+				headers:      noHeaders,
+				timeout:      defaultTimeout,
+				method:       "POST",
+				body:         "abracadabra",
+				compressBody: "compress",
+				format:       knownFormat("plain-text"),
+			},
+			errUnknownEncoding,
+		},
+		{
+			config{
+				numConns:        defaultNumberOfConns,
+				numReqs:         &defaultNumberOfReqs,
+				url:             ParseURLOrPanic("http://localhost:8080"),
+				headers:         noHeaders,
+				timeout:         defaultTimeout,
+				method:          "GET",
+				histogramDigits: 5,
+				format:          knownFormat("plain-text"),
+			},
+			errInvalidHistogramDigits,
+		},
+		{
+			config{
+				numConns:       defaultNumberOfConns,
+				numReqs:        &defaultNumberOfReqs,
+				url:            ParseURLOrPanic("http://localhost:8080"),
+				headers:        noHeaders,
+				timeout:        defaultTimeout,
+				method:         "GET",
+				hdrLogPath:     "latencies.hlog",
+				hdrLogInterval: -time.Second,
+				format:         knownFormat("plain-text"),
+			},
+			errInvalidHDRLogInterval,
+		},
+		{
+			config{
+				numConns:       defaultNumberOfConns,
+				numReqs:        &defaultNumberOfReqs,
+				url:            ParseURLOrPanic("http://localhost:8080"),
+				headers:        noHeaders,
+				timeout:        defaultTimeout,
+				method:         "GET",
+				reports:        &reportList{{"statsd", "localhost:8125"}},
+				reportInterval: -time.Second,
+				format:         knownFormat("plain-text"),
+			},
+			errInvalidReportInterval,
+		},
+		{
+			config{
+				numConns:     defaultNumberOfConns,
+				numReqs:      &defaultNumberOfReqs,
+				url:          ParseURLOrPanic("http://localhost:8080"),
+				headers:      noHeaders,
+				timeout:      defaultTimeout,
+				method:       "GET",
+				traceparent:  true,
+				otlpEndpoint: "localhost:4318",
+				format:       knownFormat("plain-text"),
+			},
+			errInvalidOTLPEndpoint,
+		},
+		{
+			config{
+				numConns:        defaultNumberOfConns,
+				numReqs:         &defaultNumberOfReqs,
+				url:             ParseURLOrPanic("http://localhost:8080"),
+				headers:         noHeaders,
+				timeout:         defaultTimeout,
+				method:          "GET",
+				logRequestsPath: "requests.jsonl",
+				logSample:       1.5,
+				format:          knownFormat("plain-text"),
+			},
+			errInvalidLogSample,
+		},
+		{
+			config{
+				numConns:     defaultNumberOfConns,
+				numReqs:      &defaultNumberOfReqs,
+				url:          ParseURLOrPanic("http://localhost:8080"),
+				headers:      noHeaders,
+				timeout:      defaultTimeout,
+				method:       "GET",
+				replayPath:   "requests.har",
+				scenarioPath: "scenario.json",
+				format:       knownFormat("plain-text"),
+			},
+			errReplayWithBody,
+		},
+		{
+			config{
+				numConns:    defaultNumberOfConns,
+				numReqs:     &defaultNumberOfReqs,
+				url:         ParseURLOrPanic("http://localhost:8080"),
+				headers:     noHeaders,
+				timeout:     defaultTimeout,
+				method:      "GET",
+				replayPath:  "requests.har",
+				replaySpeed: -1,
+				format:      knownFormat("plain-text"),
+			},
+			errInvalidReplaySpeed,
+		},
+		{
+			config{
+				numConns: defaultNumberOfConns,
+				numReqs:  &defaultNumberOfReqs,
+				url:      ParseURLOrPanic("http://localhost:8080"),
+				headers:  noHeaders,
+				timeout:  defaultTimeout,
+				method:   "POST",
+				body:     "abracadabra",
+				mixPath:  "mix.json",
+				format:   knownFormat("plain-text"),
+			},
+			errMixWithBody,
+		},
+		{
+			config{
+				numConns:     defaultNumberOfConns,
+				numReqs:      &defaultNumberOfReqs,
+				url:          ParseURLOrPanic("http://localhost:8080"),
+				headers:      noHeaders,
+				timeout:      defaultTimeout,
+				method:       "GET",
+				dataFilePath: "users.csv",
+				format:       knownFormat("plain-text"),
+			},
+			errUnusedData,
+		},
+		{
+			config{
+				numConns:         defaultNumberOfConns,
+				numReqs:          &defaultNumberOfReqs,
+				url:              ParseURLOrPanic("http://localhost:8080"),
+				headers:          noHeaders,
+				timeout:          defaultTimeout,
+				method:           "GET",
+				requestTemplates: true,
+				scenarioPath:     "scenario.json",
+				format:           knownFormat("plain-text"),
+			},
+			errTemplatesWithRequestSource,
+		},
+		{
+			config{
+				numConns:         defaultNumberOfConns,
+				numReqs:          &defaultNumberOfReqs,
+				url:              ParseURLOrPanic("http://localhost:8080"),
+				headers:          noHeaders,
+				timeout:          defaultTimeout,
+				method:           "GET",
+				requestTemplates: true,
+				mixPath:          "mix.json",
+				format:           knownFormat("plain-text"),
+			},
+			errTemplatesWithRequestSource,
+		},
+		{
+			config{
+				numConns:         defaultNumberOfConns,
+				numReqs:          &defaultNumberOfReqs,
+				url:              ParseURLOrPanic("http://localhost:8080"),
+				headers:          noHeaders,
+				timeout:          defaultTimeout,
+				method:           "GET",
+				requestTemplates: true,
+				replayPath:       "requests.har",
+				format:           knownFormat("plain-text"),
+			},
+			errTemplatesWithRequestSource,
+		},
 	}
 	for _, e := range expectations {
 		if r := e.in.checkArgs(); r != e.out {
@@ -259,6 +564,35 @@ func TestCheckArgs(t *testing.T) {
 	}
 }
 
+func TestCheckArgsLogSample(t *testing.T) {
+	expectations := []struct {
+		in  float64
+		out error
+	}{
+		{0, errInvalidLogSample},
+		{0.01, nil},
+		{1, nil},
+		{-0.01, errInvalidLogSample},
+		{1.01, errInvalidLogSample},
+	}
+	for _, e := range expectations {
+		c := config{
+			numConns:        defaultNumberOfConns,
+			numReqs:         &defaultNumberOfReqs,
+			url:             ParseURLOrPanic("http://localhost:8080"),
+			headers:         new(headersList),
+			timeout:         defaultTimeout,
+			method:          "GET",
+			logRequestsPath: "requests.jsonl",
+			logSample:       e.in,
+			format:          knownFormat("plain-text"),
+		}
+		if r := c.checkArgs(); r != e.out {
+			t.Errorf("expected %v for --log-sample %v, but got %v", e.out, e.in, r)
+		}
+	}
+}
+
 func TestCheckArgsUnsupportedURLScheme(t *testing.T) {
 	c := config{
 		numConns: defaultNumberOfConns,
@@ -0,0 +1,135 @@
+package main
+
+import (
+	"errors"
+	"io"
+	"net"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+)
+
+// connStats accumulates information about connections opened by
+// clients during the test.
+type connStats struct {
+	opened, reused                 uint64
+	closedByServer, closedByClient uint64
+	dialFailures                   uint64
+
+	// leftOpen is the number of connections left open at the end of
+	// the test, active and peak are the current and the largest numbers
+	// of open connections.
+	leftOpen     uint64
+	active, peak int64
+
+	// conns are the open connections by their local addresses, which
+	// clients use to tell which connection a request was sent over.
+	conns sync.Map
+
+	// requests is the number of requests sent over each connection,
+	// lifetimes are in microseconds.
+	requests, lifetimes *hdrHistogram
+
+	// stopped is set at the end of the test, connections closed after
+	// it aren't recorded.
+	stopped uint32
+}
+
+func newConnStats(digits uint64) *connStats {
+	return &connStats{
+		requests:  newHDRHistogram(digits),
+		lifetimes: newHDRHistogram(digits),
+	}
+}
+
+func (s *connStats) open(cc *countingConn) {
+	if s == nil {
+		return
+	}
+	cc.stats, cc.opened = s, time.Now()
+	s.conns.Store(cc.LocalAddr(), cc)
+	atomic.AddUint64(&s.opened, 1)
+	active := atomic.AddInt64(&s.active, 1)
+	for {
+		peak := atomic.LoadInt64(&s.peak)
+		if active <= peak ||
+			atomic.CompareAndSwapInt64(&s.peak, peak, active) {
+			break
+		}
+	}
+}
+
+func (s *connStats) dialFailed() {
+	if s != nil {
+		atomic.AddUint64(&s.dialFailures, 1)
+	}
+}
+
+// served records a request sent over the connection with the local
+// address addr.
+func (s *connStats) served(addr net.Addr) {
+	if s == nil || addr == nil {
+		return
+	}
+	v, ok := s.conns.Load(addr)
+	if !ok {
+		return
+	}
+	if atomic.AddUint64(&v.(*countingConn).requests, 1) > 1 {
+		atomic.AddUint64(&s.reused, 1)
+	}
+}
+
+// closed records the connection as closed, unless it was already
+// recorded as such or as left open by stop. Connections closed after
+// stop are left for it to record as open.
+func (s *connStats) closed(cc *countingConn, byServer bool) {
+	if atomic.LoadUint32(&s.stopped) == 1 ||
+		!atomic.CompareAndSwapUint32(&cc.closed, 0, 1) {
+		return
+	}
+	s.conns.Delete(cc.LocalAddr())
+	atomic.AddInt64(&s.active, -1)
+	if byServer {
+		atomic.AddUint64(&s.closedByServer, 1)
+	} else {
+		atomic.AddUint64(&s.closedByClient, 1)
+	}
+	s.record(cc, time.Now())
+}
+
+func (s *connStats) record(cc *countingConn, now time.Time) {
+	// connections are dialed for a request, which fasthttp might
+	// close the connection after, before telling that it was sent
+	requests := atomic.LoadUint64(&cc.requests)
+	if requests == 0 {
+		requests = 1
+	}
+	s.requests.Increment(requests)
+	s.lifetimes.Increment(uint64(now.Sub(cc.opened).Nanoseconds() / 1000))
+}
+
+// stop records the connections left open, as if they were closed at
+// the end of the test. Connections are marked closed the same way
+// closed does, so that the ones being closed meanwhile are recorded
+// either as closed or as left open, but not both.
+func (s *connStats) stop(now time.Time) {
+	if !atomic.CompareAndSwapUint32(&s.stopped, 0, 1) {
+		return
+	}
+	s.conns.Range(func(_, v interface{}) bool {
+		cc := v.(*countingConn)
+		if atomic.CompareAndSwapUint32(&cc.closed, 0, 1) {
+			atomic.AddUint64(&s.leftOpen, 1)
+			s.record(cc, now)
+		}
+		return true
+	})
+}
+
+// closedByServer tells whether err returned by Read means that the
+// server closed the connection.
+func closedByServer(err error) bool {
+	return errors.Is(err, io.EOF) || errors.Is(err, syscall.ECONNRESET)
+}
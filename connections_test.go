@@ -0,0 +1,134 @@
+package main
+
+import (
+	"net"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestConnStats(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	accepted := make(chan net.Conn, 3)
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			accepted <- conn
+		}
+	}()
+	s := newConnStats(defaultHistogramDigits)
+	var bytesRead, bytesWritten int64
+	dial := fasthttpDialFunc(&bytesRead, &bytesWritten, defaultTimeout, s)
+	dialAccepted := func() (net.Conn, net.Conn) {
+		conn, err := dial(ln.Addr().String())
+		if err != nil {
+			t.Fatal(err)
+		}
+		return conn, <-accepted
+	}
+
+	first, server := dialAccepted()
+	second, _ := dialAccepted()
+	for i := 0; i < 3; i++ {
+		s.served(first.LocalAddr())
+	}
+	s.served(second.LocalAddr())
+	server.Close()
+	if _, err := first.Read(make([]byte, 1)); err == nil {
+		t.Fatal("expected the connection to be closed by server")
+	}
+	first.Close()
+	second.Close()
+	third, _ := dialAccepted()
+	defer third.Close()
+	if _, err := dial(ln.Addr().String() + "0"); err == nil {
+		t.Error("expected dial to fail")
+	}
+	s.stop(time.Now())
+	third.Close()
+
+	expectations := []struct {
+		name     string
+		expected uint64
+		actual   uint64
+	}{
+		{"opened", 3, s.opened},
+		{"reused", 2, s.reused},
+		{"closed by server", 1, s.closedByServer},
+		{"closed by client", 1, s.closedByClient},
+		{"left open", 1, s.leftOpen},
+		{"dial failures", 1, s.dialFailures},
+		{"peak", 2, uint64(s.peak)},
+		{"connections with 3 requests", 1, s.requests.Get(3)},
+		{"connections with 1 request", 2, s.requests.Get(1)},
+	}
+	for _, e := range expectations {
+		if e.actual != e.expected {
+			t.Errorf("expected %v %v, but got %v", e.expected, e.name, e.actual)
+		}
+	}
+	lifetimes := uint64(0)
+	s.lifetimes.VisitAll(func(_, c uint64) bool {
+		lifetimes += c
+		return true
+	})
+	if lifetimes != 3 {
+		t.Errorf("expected 3 lifetimes recorded, but got %v", lifetimes)
+	}
+}
+
+func TestConnStatsStopWhileClosing(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			defer conn.Close()
+		}
+	}()
+	s := newConnStats(defaultHistogramDigits)
+	var bytesRead, bytesWritten int64
+	dial := fasthttpDialFunc(&bytesRead, &bytesWritten, defaultTimeout, s)
+	numConns := 50
+	conns := make([]net.Conn, numConns)
+	for i := range conns {
+		if conns[i], err = dial(ln.Addr().String()); err != nil {
+			t.Fatal(err)
+		}
+	}
+	var wg sync.WaitGroup
+	for _, conn := range conns {
+		wg.Add(1)
+		go func(conn net.Conn) {
+			defer wg.Done()
+			conn.Close()
+		}(conn)
+	}
+	s.stop(time.Now())
+	wg.Wait()
+
+	recorded := s.closedByServer + s.closedByClient + s.leftOpen
+	if recorded != uint64(numConns) {
+		t.Errorf("expected %v connections recorded, but got %v "+
+			"(closed by server: %v, by client: %v, left open: %v)",
+			numConns, recorded,
+			s.closedByServer, s.closedByClient, s.leftOpen)
+	}
+	if c := s.requests.Get(1); c != uint64(numConns) {
+		t.Errorf("expected %v connections in distribution, but got %v",
+			numConns, c)
+	}
+}
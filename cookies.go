@@ -0,0 +1,132 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"net/http"
+	"net/http/cookiejar"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/valyala/fasthttp"
+)
+
+const httpOnlyPrefix = "#HttpOnly_"
+
+// seedCookie is a cookie read from the cookie file alongside with the
+// URL it was set for.
+type seedCookie struct {
+	url    *url.URL
+	cookie *http.Cookie
+}
+
+// readNetscapeCookies reads cookies from the file in Netscape (curl,
+// wget) format.
+func readNetscapeCookies(path string) ([]seedCookie, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var cookies []seedCookie
+	sc := bufio.NewScanner(f)
+	lineNo := 0
+	for sc.Scan() {
+		lineNo++
+		line := strings.TrimSpace(sc.Text())
+		httpOnly := strings.HasPrefix(line, httpOnlyPrefix)
+		if httpOnly {
+			line = line[len(httpOnlyPrefix):]
+		}
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fields := strings.Split(line, "\t")
+		if len(fields) != 7 {
+			return nil, fmt.Errorf(
+				"%v:%v: expected 7 tab-separated fields, but got %v",
+				path, lineNo, len(fields),
+			)
+		}
+		expires, err := strconv.ParseInt(fields[4], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("%v:%v: invalid expiration time: %v",
+				path, lineNo, err)
+		}
+		host := strings.TrimPrefix(fields[0], ".")
+		secure := strings.EqualFold(fields[3], "TRUE")
+		scheme := "http"
+		if secure {
+			scheme = "https"
+		}
+		c := &http.Cookie{
+			Name:     fields[5],
+			Value:    fields[6],
+			Path:     fields[2],
+			Secure:   secure,
+			HttpOnly: httpOnly,
+		}
+		if strings.EqualFold(fields[1], "TRUE") {
+			c.Domain = host
+		}
+		if expires != 0 {
+			c.Expires = time.Unix(expires, 0)
+		}
+		cookies = append(cookies, seedCookie{
+			url:    &url.URL{Scheme: scheme, Host: host, Path: fields[2]},
+			cookie: c,
+		})
+	}
+	return cookies, sc.Err()
+}
+
+// newCookieJar creates a cookie jar with seed cookies in it.
+func newCookieJar(seed []seedCookie) http.CookieJar {
+	// cookiejar.New never returns an error
+	jar, _ := cookiejar.New(nil)
+	for _, sc := range seed {
+		jar.SetCookies(sc.url, []*http.Cookie{sc.cookie})
+	}
+	return jar
+}
+
+// addJarCookies sets the cookies from jar, which match the URI of req,
+// on req. It returns the URL they were looked up by and the cookies
+// set, so that they can be removed before the request is redirected.
+func addJarCookies(
+	jar http.CookieJar, req *fasthttp.Request,
+) (*url.URL, []*http.Cookie, error) {
+	u, err := url.Parse(req.URI().String())
+	if err != nil {
+		return nil, nil, err
+	}
+	cookies := jar.Cookies(u)
+	for _, cookie := range cookies {
+		req.Header.SetCookie(cookie.Name, cookie.Value)
+	}
+	return u, cookies, nil
+}
+
+// storeSetCookies stores cookies set by resp to the request with URL u
+// in jar.
+func storeSetCookies(
+	jar http.CookieJar, u *url.URL, resp *fasthttp.Response,
+) {
+	var setCookies []string
+	resp.Header.VisitAllCookie(func(_, value []byte) {
+		setCookies = append(setCookies, string(value))
+	})
+	if len(setCookies) > 0 {
+		jar.SetCookies(u, parseSetCookies(setCookies))
+	}
+}
+
+// parseSetCookies parses values of Set-Cookie headers.
+func parseSetCookies(values []string) []*http.Cookie {
+	resp := http.Response{Header: http.Header{"Set-Cookie": values}}
+	return resp.Cookies()
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"net/http"
+	"reflect"
+	"testing"
+	"time"
+)
+
+const testCookieFile = "# Netscape HTTP Cookie File\n" +
+	"\n" +
+	".example.com\tTRUE\t/\tFALSE\t0\tdomain\tall\n" +
+	"#HttpOnly_example.com\tFALSE\t/api\tTRUE\t4102444800\tsecret\ts3cr3t\n"
+
+func TestReadNetscapeCookies(t *testing.T) {
+	path := writeDataFile(t, "cookies.txt", testCookieFile)
+	cookies, err := readNetscapeCookies(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	exp := []seedCookie{
+		{
+			ParseURLOrPanic("http://example.com/"),
+			&http.Cookie{Name: "domain", Value: "all", Path: "/", Domain: "example.com"},
+		},
+		{
+			ParseURLOrPanic("https://example.com/api"),
+			&http.Cookie{
+				Name: "secret", Value: "s3cr3t", Path: "/api",
+				Secure: true, HttpOnly: true, Expires: time.Unix(4102444800, 0),
+			},
+		},
+	}
+	if len(cookies) != len(exp) {
+		t.Fatalf("expected %v cookies, but got %v", len(exp), len(cookies))
+	}
+	for i := range exp {
+		if cookies[i].url.String() != exp[i].url.String() ||
+			!reflect.DeepEqual(cookies[i].cookie, exp[i].cookie) {
+			t.Errorf("expected %v %+v, but got %v %+v",
+				exp[i].url, exp[i].cookie, cookies[i].url, cookies[i].cookie)
+		}
+	}
+
+	jar := newCookieJar(cookies)
+	if c := jar.Cookies(ParseURLOrPanic("http://sub.example.com/api")); len(c) != 1 {
+		t.Errorf("expected only domain cookie, but got %v", c)
+	}
+	if c := jar.Cookies(ParseURLOrPanic("https://example.com/api/x")); len(c) != 2 {
+		t.Errorf("expected both cookies, but got %v", c)
+	}
+}
+
+func TestReadInvalidNetscapeCookies(t *testing.T) {
+	invalid := []string{
+		"example.com\tFALSE\t/\tFALSE\t0\tname\n",
+		"example.com\tFALSE\t/\tFALSE\tnever\tname\tvalue\n",
+	}
+	for _, contents := range invalid {
+		path := writeDataFile(t, "cookies.txt", contents)
+		if _, err := readNetscapeCookies(path); err == nil {
+			t.Errorf("invalid cookie file %q parsed successfully", contents)
+		}
+	}
+}
+
+func TestParseSetCookies(t *testing.T) {
+	cookies := parseSetCookies([]string{"a=1; Path=/", "b=2; HttpOnly"})
+	if len(cookies) != 2 || cookies[0].Name != "a" || cookies[1].Value != "2" {
+		t.Errorf("unexpected cookies %v", cookies)
+	}
+}
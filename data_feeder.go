@@ -0,0 +1,308 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+type feedMode int
+
+const (
+	// feedSequential hands out rows in file order to whichever
+	// worker asks first.
+	feedSequential feedMode = iota
+	// feedRandom hands out random rows.
+	feedRandom
+	// feedUnique partitions rows between workers, so that no two
+	// workers ever use the same row.
+	feedUnique
+)
+
+func (m feedMode) String() string {
+	switch m {
+	case feedSequential:
+		return "sequential"
+	case feedRandom:
+		return "random"
+	case feedUnique:
+		return "unique"
+	}
+	return "unknown"
+}
+
+func feedModeFromString(s string) (feedMode, error) {
+	switch s {
+	case "sequential":
+		return feedSequential, nil
+	case "random":
+		return feedRandom, nil
+	case "unique":
+		return feedUnique, nil
+	}
+	return feedSequential, fmt.Errorf("unknown data feeding mode %q", s)
+}
+
+// dataFeeder supplies rows of a CSV (with a header) or a JSON Lines
+// file to request templates. Rows are read from disk on demand,
+// random and unique modes only keep offsets of the rows in memory.
+type dataFeeder struct {
+	mode     feedMode
+	wrap     bool
+	sessions uint64
+
+	jsonl   bool
+	columns []string
+
+	// Used in sequential mode, CSV files are read by records rather
+	// than by lines, since quoted fields might contain line breaks.
+	mu     sync.Mutex
+	file   *os.File
+	reader *bufio.Reader
+	csv    *csv.Reader
+	header int64
+
+	// Used in random and unique modes.
+	offsets []int64
+	size    int64
+}
+
+func isJSONLines(path string) bool {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".jsonl", ".ndjson":
+		return true
+	}
+	return false
+}
+
+func newDataFeeder(
+	path string, mode feedMode, wrap bool, sessions uint64,
+) (*dataFeeder, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	d := &dataFeeder{
+		mode:     mode,
+		wrap:     wrap,
+		sessions: sessions,
+		jsonl:    isJSONLines(path),
+		file:     f,
+	}
+	if d.jsonl {
+		d.reader = bufio.NewReader(f)
+	} else {
+		d.csv = newCSVReader(f)
+		d.columns, err = d.csv.Read()
+		if err != nil {
+			_ = f.Close()
+			return nil, fmt.Errorf("invalid CSV header: %v", err)
+		}
+		d.header = d.csv.InputOffset()
+	}
+	if mode != feedSequential {
+		if err := d.indexRows(); err != nil {
+			_ = f.Close()
+			return nil, err
+		}
+		if mode == feedUnique && uint64(len(d.offsets)) < sessions {
+			_ = f.Close()
+			return nil, errNotEnoughUniqueRows
+		}
+	}
+	return d, nil
+}
+
+// indexRows remembers where every non-empty row starts.
+func (d *dataFeeder) indexRows() error {
+	if !d.jsonl {
+		return d.indexCSVRecords()
+	}
+	offset := d.header
+	for {
+		line, err := d.reader.ReadSlice('\n')
+		if err == bufio.ErrBufferFull {
+			// Long line, keep reading until its end.
+			rest, rerr := d.reader.ReadBytes('\n')
+			line = append(append([]byte(nil), line...), rest...)
+			err = rerr
+		}
+		if len(bytes.TrimSpace(line)) > 0 {
+			d.offsets = append(d.offsets, offset)
+		}
+		offset += int64(len(line))
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+	}
+	d.size = offset
+	if len(d.offsets) == 0 {
+		return errEmptyDataFile
+	}
+	return nil
+}
+
+// indexCSVRecords remembers where every record starts, which might
+// span several lines.
+func (d *dataFeeder) indexCSVRecords() error {
+	for {
+		offset := d.csv.InputOffset()
+		_, err := d.csv.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		d.offsets = append(d.offsets, offset)
+	}
+	d.size = d.csv.InputOffset()
+	if len(d.offsets) == 0 {
+		return errEmptyDataFile
+	}
+	return nil
+}
+
+func (d *dataFeeder) close() error {
+	return d.file.Close()
+}
+
+// next returns the row that has to be used for the next request
+// made by s.
+func (d *dataFeeder) next(s *session) (map[string]string, error) {
+	switch d.mode {
+	case feedRandom:
+		return d.rowAt(rand.Intn(len(d.offsets)))
+	case feedUnique:
+		i := s.id + s.dataRow*d.sessions
+		if i >= uint64(len(d.offsets)) {
+			if !d.wrap {
+				return nil, errDataExhausted
+			}
+			s.dataRow, i = 0, s.id
+		}
+		s.dataRow++
+		return d.rowAt(int(i))
+	}
+	return d.nextSequential()
+}
+
+func (d *dataFeeder) nextSequential() (map[string]string, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if !d.jsonl {
+		return d.nextCSVRecord()
+	}
+	wrapped := false
+	for {
+		line, err := d.reader.ReadString('\n')
+		if strings.TrimSpace(line) != "" {
+			return d.parse(line)
+		}
+		if err == io.EOF {
+			if !d.wrap || wrapped {
+				return nil, errDataExhausted
+			}
+			if _, err = d.file.Seek(d.header, io.SeekStart); err != nil {
+				return nil, err
+			}
+			d.reader.Reset(d.file)
+			wrapped = true
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+}
+
+func (d *dataFeeder) nextCSVRecord() (map[string]string, error) {
+	values, err := d.csv.Read()
+	if err == io.EOF && d.wrap {
+		if _, err = d.file.Seek(d.header, io.SeekStart); err != nil {
+			return nil, err
+		}
+		d.csv = newCSVReader(d.file)
+		values, err = d.csv.Read()
+	}
+	if err == io.EOF {
+		return nil, errDataExhausted
+	}
+	if err != nil {
+		return nil, err
+	}
+	return d.csvRow(values)
+}
+
+func (d *dataFeeder) rowAt(i int) (map[string]string, error) {
+	end := d.size
+	if i+1 < len(d.offsets) {
+		end = d.offsets[i+1]
+	}
+	buf := make([]byte, end-d.offsets[i])
+	if _, err := d.file.ReadAt(buf, d.offsets[i]); err != nil && err != io.EOF {
+		return nil, err
+	}
+	return d.parse(string(buf))
+}
+
+func (d *dataFeeder) parse(line string) (map[string]string, error) {
+	if d.jsonl {
+		return parseJSONLine(line)
+	}
+	values, err := newCSVReader(strings.NewReader(line)).Read()
+	if err != nil {
+		return nil, err
+	}
+	return d.csvRow(values)
+}
+
+func (d *dataFeeder) csvRow(values []string) (map[string]string, error) {
+	if len(values) != len(d.columns) {
+		return nil, fmt.Errorf(
+			"expected %v values in CSV row, but got %v",
+			len(d.columns), len(values),
+		)
+	}
+	row := make(map[string]string, len(values))
+	for i, v := range values {
+		row[d.columns[i]] = v
+	}
+	return row, nil
+}
+
+// newCSVReader returns a reader, which leaves checking the number of
+// values in records to the feeder.
+func newCSVReader(r io.Reader) *csv.Reader {
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = -1
+	return cr
+}
+
+func parseJSONLine(line string) (map[string]string, error) {
+	var values map[string]json.RawMessage
+	if err := json.Unmarshal([]byte(line), &values); err != nil {
+		return nil, err
+	}
+	row := make(map[string]string, len(values))
+	for k, v := range values {
+		var s string
+		if err := json.Unmarshal(v, &s); err == nil {
+			row[k] = s
+		} else {
+			row[k] = string(v)
+		}
+	}
+	return row, nil
+}
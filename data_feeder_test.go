@@ -0,0 +1,161 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func writeDataFile(t *testing.T, name, contents string) string {
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(contents), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+const (
+	testCSVData = "id,name\n1,alice\n\n2,\"bob, jr.\"\n3,carol"
+	testJSONL   = `{"id":1,"name":"alice"}` + "\n" +
+		`{"id":2,"name":"bob","tags":["a"]}` + "\n"
+)
+
+func TestDataFeederSequential(t *testing.T) {
+	path := writeDataFile(t, "users.csv", testCSVData)
+	for _, wrap := range []bool{false, true} {
+		d, err := newDataFeeder(path, feedSequential, wrap, 1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		s := newSession(0)
+		exp := []map[string]string{
+			{"id": "1", "name": "alice"},
+			{"id": "2", "name": "bob, jr."},
+			{"id": "3", "name": "carol"},
+		}
+		for i := 0; i < 2*len(exp); i++ {
+			row, err := d.next(s)
+			if i >= len(exp) && !wrap {
+				if err != errDataExhausted {
+					t.Errorf("expected %v, but got %v, %v", errDataExhausted, row, err)
+				}
+				continue
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if e := exp[i%len(exp)]; !reflect.DeepEqual(row, e) {
+				t.Errorf("expected row %v, but got %v", e, row)
+			}
+		}
+		if err := d.close(); err != nil {
+			t.Error(err)
+		}
+	}
+}
+
+func TestDataFeederJSONLines(t *testing.T) {
+	path := writeDataFile(t, "users.jsonl", testJSONL)
+	d, err := newDataFeeder(path, feedRandom, true, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer d.close()
+	valid := map[string]bool{"alice": true, "bob": true}
+	for i := 0; i < 10; i++ {
+		row, err := d.next(newSession(0))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !valid[row["name"]] {
+			t.Errorf("unexpected row %v", row)
+		}
+		if row["name"] == "bob" && row["tags"] != `["a"]` {
+			t.Errorf("expected raw JSON for non-string values, but got %v", row)
+		}
+	}
+}
+
+func TestDataFeederUnique(t *testing.T) {
+	path := writeDataFile(t, "users.csv", testCSVData)
+	d, err := newDataFeeder(path, feedUnique, false, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer d.close()
+	first, second := newSession(0), newSession(1)
+	expectations := []struct {
+		s   *session
+		id  string
+		err error
+	}{
+		{first, "1", nil},
+		{second, "2", nil},
+		{first, "3", nil},
+		{second, "", errDataExhausted},
+		{first, "", errDataExhausted},
+	}
+	for _, e := range expectations {
+		row, err := d.next(e.s)
+		if err != e.err || row["id"] != e.id {
+			t.Errorf("expected %q, %v for session %v, but got %v, %v",
+				e.id, e.err, e.s.id, row, err)
+		}
+	}
+}
+
+func TestDataFeederMultilineCSVFields(t *testing.T) {
+	path := writeDataFile(t, "notes.csv",
+		"id,note\n1,\"first\nline\"\n\n2,\"a, \"\"quoted\"\"\r\nnote\"\n3,plain\n")
+	exp := []map[string]string{
+		{"id": "1", "note": "first\nline"},
+		// line breaks in quoted fields are normalized by encoding/csv
+		{"id": "2", "note": "a, \"quoted\"\nnote"},
+		{"id": "3", "note": "plain"},
+	}
+	for _, mode := range []feedMode{feedSequential, feedUnique} {
+		d, err := newDataFeeder(path, mode, true, 1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		s := newSession(0)
+		for i := 0; i < 2*len(exp); i++ {
+			row, err := d.next(s)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if e := exp[i%len(exp)]; !reflect.DeepEqual(row, e) {
+				t.Errorf("%v: expected row %q, but got %q", mode, e, row)
+			}
+		}
+		if err := d.close(); err != nil {
+			t.Error(err)
+		}
+	}
+}
+
+func TestDataFeederErrors(t *testing.T) {
+	path := writeDataFile(t, "users.csv", testCSVData)
+	if _, err := newDataFeeder(path, feedUnique, true, 4); err != errNotEnoughUniqueRows {
+		t.Errorf("expected %v, but got %v", errNotEnoughUniqueRows, err)
+	}
+	empty := writeDataFile(t, "empty.csv", "id,name\n")
+	if _, err := newDataFeeder(empty, feedRandom, true, 1); err != errEmptyDataFile {
+		t.Errorf("expected %v, but got %v", errEmptyDataFile, err)
+	}
+	if _, err := newDataFeeder("/does/not/exist.csv", feedSequential, true, 1); err == nil {
+		t.Error("expected an error for a missing file")
+	}
+}
+
+func TestFeedModeFromString(t *testing.T) {
+	for _, m := range []feedMode{feedSequential, feedRandom, feedUnique} {
+		if a, err := feedModeFromString(m.String()); err != nil || a != m {
+			t.Errorf("expected %v, but got %v, %v", m, a, err)
+		}
+	}
+	if _, err := feedModeFromString("unknown"); err == nil {
+		t.Error("unknown mode parsed successfully")
+	}
+}
@@ -2,6 +2,7 @@ package main
 
 import (
 	"context"
+	"crypto/tls"
 	"net"
 	"sync/atomic"
 	"time"
@@ -10,6 +11,13 @@ import (
 type countingConn struct {
 	net.Conn
 	bytesRead, bytesWritten *int64
+
+	// stats is nil, unless connections are tracked, in which case
+	// requests is the number of requests sent over the connection.
+	stats    *connStats
+	opened   time.Time
+	requests uint64
+	closed   uint32
 }
 
 func (cc *countingConn) Read(b []byte) (n int, err error) {
@@ -17,6 +25,8 @@ func (cc *countingConn) Read(b []byte) (n int, err error) {
 
 	if err == nil {
 		atomic.AddInt64(cc.bytesRead, int64(n))
+	} else if cc.stats != nil && closedByServer(err) {
+		cc.stats.closed(cc, true)
 	}
 
 	return
@@ -32,13 +42,22 @@ func (cc *countingConn) Write(b []byte) (n int, err error) {
 	return
 }
 
+func (cc *countingConn) Close() error {
+	if cc.stats != nil {
+		cc.stats.closed(cc, false)
+	}
+	return cc.Conn.Close()
+}
+
 var fasthttpDialFunc = func(
 	bytesRead, bytesWritten *int64,
 	dialTimeout time.Duration,
+	stats *connStats,
 ) func(string) (net.Conn, error) {
 	return func(address string) (net.Conn, error) {
 		conn, err := net.DialTimeout("tcp", address, dialTimeout)
 		if err != nil {
+			stats.dialFailed()
 			return nil, err
 		}
 
@@ -47,6 +66,7 @@ var fasthttpDialFunc = func(
 			bytesRead:    bytesRead,
 			bytesWritten: bytesWritten,
 		}
+		stats.open(wrappedConn)
 
 		return wrappedConn, nil
 	}
@@ -55,11 +75,13 @@ var fasthttpDialFunc = func(
 var httpDialContextFunc = func(
 	bytesRead, bytesWritten *int64,
 	dialTimeout time.Duration,
+	stats *connStats,
 ) func(context.Context, string, string) (net.Conn, error) {
 	dialer := &net.Dialer{Timeout: dialTimeout}
 	return func(ctx context.Context, network, address string) (net.Conn, error) {
 		conn, err := dialer.DialContext(ctx, network, address)
 		if err != nil {
+			stats.dialFailed()
 			return nil, err
 		}
 
@@ -68,7 +90,65 @@ var httpDialContextFunc = func(
 			bytesRead:    bytesRead,
 			bytesWritten: bytesWritten,
 		}
+		stats.open(wrappedConn)
 
 		return wrappedConn, nil
 	}
 }
+
+var httpTLSDialContextFunc = func(
+	bytesRead, bytesWritten *int64,
+	dialTimeout time.Duration,
+	tlsConfig *tls.Config,
+	stats *handshakeStats,
+	conns *connStats,
+) func(context.Context, string, string) (net.Conn, error) {
+	dial := httpDialContextFunc(bytesRead, bytesWritten, dialTimeout, conns)
+	return func(ctx context.Context, network, address string) (net.Conn, error) {
+		conn, err := dial(ctx, network, address)
+		if err != nil {
+			return nil, err
+		}
+
+		config := tlsConfig.Clone()
+		if config.ServerName == "" {
+			host, _, serr := net.SplitHostPort(address)
+			if serr != nil {
+				host = address
+			}
+			config.ServerName = host
+		}
+		tlsConn := tls.Client(conn, config)
+
+		if dialTimeout > 0 {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeout(ctx, dialTimeout)
+			defer cancel()
+		}
+		start := time.Now()
+		if err := tlsConn.HandshakeContext(ctx); err != nil {
+			_ = conn.Close()
+			conns.dialFailed()
+			return nil, err
+		}
+		usTaken := uint64(time.Since(start).Nanoseconds() / 1000)
+		stats.record(tlsConn.ConnectionState().DidResume, usTaken)
+
+		return tlsConn, nil
+	}
+}
+
+var fasthttpTLSDialFunc = func(
+	bytesRead, bytesWritten *int64,
+	dialTimeout time.Duration,
+	tlsConfig *tls.Config,
+	stats *handshakeStats,
+	conns *connStats,
+) func(string) (net.Conn, error) {
+	dial := httpTLSDialContextFunc(
+		bytesRead, bytesWritten, dialTimeout, tlsConfig, stats, conns,
+	)
+	return func(address string) (net.Conn, error) {
+		return dial(context.Background(), "tcp", address)
+	}
+}
@@ -1,25 +1,163 @@
 package main
 
 import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"io"
+	"net"
+	"regexp"
 	"sort"
 	"strconv"
+	"strings"
 	"sync"
 	"sync/atomic"
+	"syscall"
+
+	"github.com/valyala/fasthttp"
+)
+
+// Categories of errors, see classifyError.
+const (
+	categoryDialTimeout  = "dial timeout"
+	categoryReadTimeout  = "read timeout"
+	categoryWriteTimeout = "write timeout"
+	categoryTimeout      = "timeout"
+	categoryConnRefused  = "connection refused"
+	categoryConnReset    = "connection reset"
+	categoryConnClosed   = "connection closed"
+	categoryTLS          = "tls"
+	categoryDNS          = "dns"
+	categoryBodyRead     = "body read"
+	categoryRedirects    = "redirect loop"
+	categoryOther        = "other"
+)
+
+// bodyReadError marks errors which occurred while reading response
+// body, so they can be told apart from the others of the same kind.
+type bodyReadError struct {
+	err error
+}
+
+func (e *bodyReadError) Error() string {
+	return e.err.Error()
+}
+
+func (e *bodyReadError) Unwrap() error {
+	return e.err
+}
+
+// classifyError returns the category of err, looking at the errors
+// it wraps rather than at its message, where possible.
+func classifyError(err error) string {
+	var (
+		bodyErr  *bodyReadError
+		dnsErr   *net.DNSError
+		opErr    *net.OpError
+		timeout  interface{ Timeout() bool }
+		tlsAlert tls.AlertError
+		tlsHdr   tls.RecordHeaderError
+		tlsCert  *tls.CertificateVerificationError
+	)
+	switch {
+	case errors.Is(err, errRedirectLoop),
+		errors.Is(err, fasthttp.ErrTooManyRedirects):
+		return categoryRedirects
+	case errors.As(err, &dnsErr):
+		return categoryDNS
+	case errors.As(err, &tlsAlert), errors.As(err, &tlsHdr),
+		errors.As(err, &tlsCert),
+		errors.Is(err, fasthttp.ErrTLSHandshakeTimeout),
+		strings.Contains(err.Error(), "tls: "):
+		return categoryTLS
+	case errors.As(err, &bodyErr):
+		return categoryBodyRead
+	case errors.Is(err, fasthttp.ErrDialTimeout):
+		return categoryDialTimeout
+	case errors.As(err, &opErr) && opErr.Timeout():
+		switch opErr.Op {
+		case "dial":
+			return categoryDialTimeout
+		case "read":
+			return categoryReadTimeout
+		case "write":
+			return categoryWriteTimeout
+		}
+		return categoryTimeout
+	case errors.As(err, &timeout) && timeout.Timeout(),
+		errors.Is(err, context.DeadlineExceeded):
+		return categoryTimeout
+	case errors.Is(err, syscall.ECONNREFUSED):
+		return categoryConnRefused
+	case errors.Is(err, syscall.ECONNRESET),
+		errors.Is(err, syscall.EPIPE):
+		return categoryConnReset
+	case errors.Is(err, fasthttp.ErrConnectionClosed),
+		errors.Is(err, io.EOF), errors.Is(err, io.ErrUnexpectedEOF):
+		return categoryConnClosed
+	}
+	return categoryOther
+}
+
+var (
+	ipv4Address = regexp.MustCompile(`\b(\d{1,3}\.){3}\d{1,3}(:\d+)?\b`)
+	ipv6Address = regexp.MustCompile(`\[[0-9A-Fa-f:.]+(%\w+)?\](:\d+)?`)
+	quotedURL   = regexp.MustCompile(`"https?://[^"]*"`)
 )
 
+// normalizeError replaces the parts of error messages, which vary
+// between otherwise the same errors, i.e. addresses, ports and URLs
+// (which differ with templates and data feeding).
+func normalizeError(s string) string {
+	s = quotedURL.ReplaceAllString(s, `"<url>"`)
+	s = ipv6Address.ReplaceAllString(s, "<address>")
+	return ipv4Address.ReplaceAllString(s, "<address>")
+}
+
+// maxNormalizedErrors is the maximum number of original messages, for
+// which normalized ones are cached.
+const maxNormalizedErrors = 4096
+
+// errorMap counts errors by their normalized messages, keeping the
+// first of the original messages as an example.
 type errorMap struct {
 	mu sync.RWMutex
-	m  map[string]*uint64
+	m  map[string]*errorEntry
+
+	// normalized caches normalized messages by the original ones, so
+	// that errors occurring over and over again aren't matched
+	// against regular expressions each time. The cache is limited,
+	// since messages with addresses of both ends are mostly unique.
+	normalized sync.Map
+	cached     int64
+}
+
+type errorEntry struct {
+	count             uint64
+	category, example string
 }
 
 func newErrorMap() *errorMap {
 	em := new(errorMap)
-	em.m = make(map[string]*uint64)
+	em.m = make(map[string]*errorEntry)
 	return em
 }
 
+// normalize returns the normalized message of the error, msg.
+func (e *errorMap) normalize(msg string) string {
+	if s, ok := e.normalized.Load(msg); ok {
+		return s.(string)
+	}
+	s := normalizeError(msg)
+	if atomic.AddInt64(&e.cached, 1) <= maxNormalizedErrors {
+		e.normalized.Store(msg, s)
+	}
+	return s
+}
+
 func (e *errorMap) add(err error) {
-	s := err.Error()
+	msg := err.Error()
+	s := e.normalize(msg)
 	e.mu.RLock()
 	c, ok := e.m[s]
 	e.mu.RUnlock()
@@ -27,23 +165,26 @@ func (e *errorMap) add(err error) {
 		e.mu.Lock()
 		c, ok = e.m[s]
 		if !ok {
-			c = new(uint64)
+			c = &errorEntry{
+				category: classifyError(err),
+				example:  msg,
+			}
 			e.m[s] = c
 		}
 		e.mu.Unlock()
 	}
-	atomic.AddUint64(c, 1)
+	atomic.AddUint64(&c.count, 1)
 }
 
 func (e *errorMap) get(err error) uint64 {
-	s := err.Error()
+	s := e.normalize(err.Error())
 	e.mu.RLock()
 	defer e.mu.RUnlock()
 	c := e.m[s]
 	if c == nil {
 		return uint64(0)
 	}
-	return *c
+	return atomic.LoadUint64(&c.count)
 }
 
 func (e *errorMap) sum() uint64 {
@@ -51,7 +192,7 @@ func (e *errorMap) sum() uint64 {
 	defer e.mu.RUnlock()
 	sum := uint64(0)
 	for _, v := range e.m {
-		sum += *v
+		sum += atomic.LoadUint64(&v.count)
 	}
 	return sum
 }
@@ -59,6 +200,8 @@ func (e *errorMap) sum() uint64 {
 type errorWithCount struct {
 	error string
 	count uint64
+
+	category, example string
 }
 
 func (ewc *errorWithCount) String() string {
@@ -83,8 +226,13 @@ func (ebf errorsByFrequency) Swap(i, j int) {
 func (e *errorMap) byFrequency() errorsByFrequency {
 	e.mu.RLock()
 	byFreq := make(errorsByFrequency, 0, len(e.m))
-	for err, count := range e.m {
-		byFreq = append(byFreq, &errorWithCount{err, *count})
+	for err, c := range e.m {
+		byFreq = append(byFreq, &errorWithCount{
+			error:    err,
+			count:    atomic.LoadUint64(&c.count),
+			category: c.category,
+			example:  c.example,
+		})
 	}
 	e.mu.RUnlock()
 	sort.Sort(byFreq)
@@ -1,9 +1,18 @@
 package main
 
 import (
+	"context"
+	"crypto/tls"
 	"errors"
+	"io"
+	"net"
+	"net/url"
+	"os"
 	"reflect"
+	"syscall"
 	"testing"
+
+	"github.com/valyala/fasthttp"
 )
 
 func TestErrorMapAdd(t *testing.T) {
@@ -35,9 +44,9 @@ func TestByFrequency(t *testing.T) {
 	m.add(b)
 	m.add(c)
 	e := errorsByFrequency{
-		{"B", 3},
-		{"A", 2},
-		{"C", 1},
+		{error: "B", count: 3, category: categoryOther, example: "B"},
+		{error: "A", count: 2, category: categoryOther, example: "A"},
+		{error: "C", count: 1, category: categoryOther, example: "C"},
 	}
 	if a := m.byFrequency(); !reflect.DeepEqual(a, e) {
 		t.Logf("Expected: %+v", e)
@@ -46,8 +55,123 @@ func TestByFrequency(t *testing.T) {
 	}
 }
 
+func TestErrorMapNormalizesErrors(t *testing.T) {
+	m := newErrorMap()
+	for _, port := range []string{"50001", "50002", "50003"} {
+		m.add(errors.New("read tcp 127.0.0.1:" + port +
+			"->127.0.0.1:8080: read: connection reset by peer"))
+	}
+	m.add(errors.New(`Get "http://[::1]:8080/users/1": EOF`))
+	m.add(errors.New(`Get "http://[::1]:8080/users/2": EOF`))
+	e := errorsByFrequency{
+		{
+			error:    "read tcp <address>-><address>: read: connection reset by peer",
+			count:    3,
+			category: categoryOther,
+			example:  "read tcp 127.0.0.1:50001->127.0.0.1:8080: read: connection reset by peer",
+		},
+		{
+			error:    `Get "<url>": EOF`,
+			count:    2,
+			category: categoryOther,
+			example:  `Get "http://[::1]:8080/users/1": EOF`,
+		},
+	}
+	if a := m.byFrequency(); !reflect.DeepEqual(a, e) {
+		t.Errorf("expected %+v, but got %+v", e, a)
+	}
+}
+
+func TestErrorMapCachesNormalizedErrors(t *testing.T) {
+	m := newErrorMap()
+	msg := "dial tcp 127.0.0.1:8080: connect: connection refused"
+	for i := 0; i < 3; i++ {
+		if s := m.normalize(msg); s != "dial tcp <address>: connect: connection refused" {
+			t.Errorf("unexpected normalized message: %q", s)
+		}
+	}
+	if m.cached != 1 {
+		t.Errorf("expected the message to be normalized once, but got %v", m.cached)
+	}
+
+	m.cached = maxNormalizedErrors
+	m.normalize("dial tcp 127.0.0.1:8081: connect: connection refused")
+	if _, ok := m.normalized.Load("dial tcp 127.0.0.1:8081: connect: connection refused"); ok {
+		t.Error("expected the cache to be limited")
+	}
+}
+
+func TestNormalizeError(t *testing.T) {
+	expectations := []struct {
+		in, out string
+	}{
+		{
+			"dial tcp 10.0.0.1:8080: connect: connection refused",
+			"dial tcp <address>: connect: connection refused",
+		},
+		{
+			"dial tcp [fe80::1%eth0]:443: i/o timeout",
+			"dial tcp <address>: i/o timeout",
+		},
+		{
+			`Post "https://example.com/?id=42": context deadline exceeded`,
+			`Post "<url>": context deadline exceeded`,
+		},
+		{"timeout", "timeout"},
+	}
+	for _, e := range expectations {
+		if out := normalizeError(e.in); out != e.out {
+			t.Errorf("expected %q for %q, but got %q", e.out, e.in, out)
+		}
+	}
+}
+
+func TestClassifyError(t *testing.T) {
+	opErr := func(op string, err error) error {
+		return &url.Error{Op: "Get", URL: "http://localhost", Err: &net.OpError{
+			Op: op, Net: "tcp", Err: err,
+		}}
+	}
+	expectations := []struct {
+		err      error
+		category string
+	}{
+		{errRedirectLoop, categoryRedirects},
+		{&net.DNSError{Err: "no such host", Name: "nohost"}, categoryDNS},
+		{tls.RecordHeaderError{Msg: "not a TLS handshake"}, categoryTLS},
+		{fasthttp.ErrTLSHandshakeTimeout, categoryTLS},
+		{&bodyReadError{io.ErrUnexpectedEOF}, categoryBodyRead},
+		{fasthttp.ErrDialTimeout, categoryDialTimeout},
+		{opErr("dial", timeoutError{}), categoryDialTimeout},
+		{opErr("read", timeoutError{}), categoryReadTimeout},
+		{opErr("write", timeoutError{}), categoryWriteTimeout},
+		{fasthttp.ErrTimeout, categoryTimeout},
+		{context.DeadlineExceeded, categoryTimeout},
+		{opErr("dial", os.NewSyscallError("connect", syscall.ECONNREFUSED)),
+			categoryConnRefused},
+		{opErr("read", os.NewSyscallError("read", syscall.ECONNRESET)),
+			categoryConnReset},
+		{opErr("write", os.NewSyscallError("write", syscall.EPIPE)),
+			categoryConnReset},
+		{fasthttp.ErrConnectionClosed, categoryConnClosed},
+		{io.EOF, categoryConnClosed},
+		{errors.New("something else"), categoryOther},
+	}
+	for _, e := range expectations {
+		if c := classifyError(e.err); c != e.category {
+			t.Errorf("expected %q for %v, but got %q", e.category, e.err, c)
+		}
+	}
+}
+
+type timeoutError struct{}
+
+func (timeoutError) Error() string   { return "i/o timeout" }
+func (timeoutError) Timeout() bool   { return true }
+func (timeoutError) Temporary() bool { return true }
+
 func TestErrorWithCountToStringConversion(t *testing.T) {
-	ewc := errorWithCount{"A", 1}
+	ewc := errorWithCount{error: "A", count: 1}
 	exp := "<A:1>"
 	if act := ewc.String(); act != exp {
 		t.Logf("Expected: %+v", exp)
@@ -1,7 +1,10 @@
 package main
 
 import (
+	"math"
+	"sort"
 	"strconv"
+	"strings"
 	"time"
 )
 
@@ -66,3 +69,34 @@ func (n *nullableString) Set(value string) error {
 	*n.val = value
 	return nil
 }
+
+// percentileList holds percentiles as fractions, but is set from the
+// comma-separated list of percents, e.g. 50,90,99.9.
+type percentileList []float64
+
+func (p *percentileList) String() string {
+	strs := make([]string, len(*p))
+	for i, pc := range *p {
+		strs[i] = formatPercentile(pc)
+	}
+	return strings.Join(strs, ",")
+}
+
+func (p *percentileList) Set(value string) error {
+	var res percentileList
+	seen := make(map[float64]bool)
+	for _, s := range strings.Split(value, ",") {
+		pc, err := strconv.ParseFloat(strings.TrimSpace(s), 64)
+		if err != nil || pc <= 0 || pc > 100 {
+			return errInvalidPercentile
+		}
+		if !seen[pc] {
+			seen[pc] = true
+			// rounded, so that 99.9 becomes exactly 0.999
+			res = append(res, math.Round(pc*1e7)/1e9)
+		}
+	}
+	sort.Float64s(res)
+	*p = res
+	return nil
+}
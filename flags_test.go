@@ -3,6 +3,7 @@ package main
 import (
 	"math"
 	"math/big"
+	"reflect"
 	"strconv"
 	"testing"
 	"time"
@@ -78,3 +79,28 @@ func TestNullableStringConversionToString(t *testing.T) {
 		t.Errorf("Expected %q, but got %q", someVal, act)
 	}
 }
+
+func TestPercentileListConversionToString(t *testing.T) {
+	pl := percentileList{0.5, 0.999}
+	if act := pl.String(); act != "50,99.9" {
+		t.Errorf("Expected \"50,99.9\", but got \"%v\"", act)
+	}
+}
+
+func TestPercentileListParsing(t *testing.T) {
+	invalid := []string{"", "0", "-1", "100.1", "50,", "fifty"}
+	for _, in := range invalid {
+		pl := new(percentileList)
+		if err := pl.Set(in); err != errInvalidPercentile {
+			t.Errorf("%q: expected %v, but got %v", in, errInvalidPercentile, err)
+		}
+	}
+	pl := new(percentileList)
+	if err := pl.Set("99.9, 50,99"); err != nil {
+		t.Error(err)
+	}
+	exp := percentileList{0.5, 0.99, 0.999}
+	if !reflect.DeepEqual(*pl, exp) {
+		t.Errorf("Expected %v, but got %v", exp, *pl)
+	}
+}
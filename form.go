@@ -0,0 +1,149 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"mime/multipart"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+type formField struct {
+	name, value string
+	// file is true, if value is the path to the file to upload.
+	file bool
+}
+
+type formFields []formField
+
+func (f *formFields) String() string {
+	return fmt.Sprint(*f)
+}
+
+func (f *formFields) IsCumulative() bool {
+	return true
+}
+
+func (f *formFields) Set(value string) error {
+	res := strings.SplitN(value, "=", 2)
+	if len(res) != 2 || res[0] == "" {
+		return errInvalidFormFieldFormat
+	}
+	field := formField{name: res[0], value: res[1]}
+	if strings.HasPrefix(field.value, "@") {
+		field.value, field.file = field.value[1:], true
+	}
+	*f = append(*f, field)
+	return nil
+}
+
+func (f formFields) hasFiles() bool {
+	for _, field := range f {
+		if field.file {
+			return true
+		}
+	}
+	return false
+}
+
+// formBody builds bodies of the form submissions, which are
+// multipart/form-data encoded, if files are uploaded, and
+// application/x-www-form-urlencoded otherwise.
+type formBody struct {
+	fields   formFields
+	boundary string
+}
+
+func newFormBody(fields formFields) (*formBody, error) {
+	for _, field := range fields {
+		if !field.file {
+			continue
+		}
+		// fail early, instead of on each request
+		fi, err := os.Stat(field.value)
+		if err != nil {
+			return nil, err
+		}
+		if fi.IsDir() {
+			return nil, fmt.Errorf("%v is a directory", field.value)
+		}
+	}
+	f := &formBody{fields: fields}
+	if fields.hasFiles() {
+		// same boundary is used for all requests, so that
+		// Content-Type header stays the same
+		f.boundary = multipart.NewWriter(ioutil.Discard).Boundary()
+	}
+	return f, nil
+}
+
+func (f *formBody) contentType() string {
+	if f.boundary == "" {
+		return "application/x-www-form-urlencoded"
+	}
+	return "multipart/form-data; boundary=" + f.boundary
+}
+
+func (f *formBody) writeTo(w io.Writer) error {
+	if f.boundary == "" {
+		values := make(url.Values)
+		for _, field := range f.fields {
+			values.Add(field.name, field.value)
+		}
+		_, err := io.WriteString(w, values.Encode())
+		return err
+	}
+	mw := multipart.NewWriter(w)
+	if err := mw.SetBoundary(f.boundary); err != nil {
+		return err
+	}
+	for _, field := range f.fields {
+		if !field.file {
+			if err := mw.WriteField(field.name, field.value); err != nil {
+				return err
+			}
+			continue
+		}
+		part, err := mw.CreateFormFile(field.name, filepath.Base(field.value))
+		if err != nil {
+			return err
+		}
+		if err := copyFile(part, field.value); err != nil {
+			return err
+		}
+	}
+	return mw.Close()
+}
+
+func copyFile(w io.Writer, path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	_, err = io.Copy(w, file)
+	return err
+}
+
+// bytes builds the body in memory.
+func (f *formBody) bytes() ([]byte, error) {
+	buf := new(bytes.Buffer)
+	if err := f.writeTo(buf); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// stream builds the body on the fly, reading uploaded files from
+// disk as the body is sent.
+func (f *formBody) stream() (io.ReadCloser, error) {
+	pr, pw := io.Pipe()
+	go func() {
+		pw.CloseWithError(f.writeTo(pw))
+	}()
+	return pr, nil
+}
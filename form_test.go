@@ -0,0 +1,115 @@
+package main
+
+import (
+	"io/ioutil"
+	"mime"
+	"mime/multipart"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestFormFieldsParsing(t *testing.T) {
+	fields := new(formFields)
+	for _, v := range []string{"name=John Doe", "avatar=@me.png", "empty="} {
+		if err := fields.Set(v); err != nil {
+			t.Fatal(err)
+		}
+	}
+	expected := formFields{
+		{name: "name", value: "John Doe"},
+		{name: "avatar", value: "me.png", file: true},
+		{name: "empty", value: ""},
+	}
+	if !reflect.DeepEqual(*fields, expected) {
+		t.Errorf("expected %v, but got %v", expected, *fields)
+	}
+	for _, v := range []string{"novalue", "=value"} {
+		if err := fields.Set(v); err != errInvalidFormFieldFormat {
+			t.Errorf("expected %v for %q, but got %v",
+				errInvalidFormFieldFormat, v, err)
+		}
+	}
+}
+
+func TestURLEncodedFormBody(t *testing.T) {
+	f, err := newFormBody(formFields{
+		{name: "q", value: "a b"},
+		{name: "lang", value: "go&c"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if e, a := "application/x-www-form-urlencoded", f.contentType(); e != a {
+		t.Errorf("expected %q, but got %q", e, a)
+	}
+	body, err := f.bytes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if e, a := "lang=go%26c&q=a+b", string(body); e != a {
+		t.Errorf("expected %q, but got %q", e, a)
+	}
+}
+
+func TestMultipartFormBody(t *testing.T) {
+	path := writeDataFile(t, "upload.txt", "file contents")
+	f, err := newFormBody(formFields{
+		{name: "name", value: "value"},
+		{name: "upload", value: path, file: true},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	inMemory, err := f.bytes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	stream, err := f.stream()
+	if err != nil {
+		t.Fatal(err)
+	}
+	streamed, err := ioutil.ReadAll(stream)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(inMemory) != string(streamed) {
+		t.Errorf("streamed body %q differs from %q", streamed, inMemory)
+	}
+
+	mediaType, params, err := mime.ParseMediaType(f.contentType())
+	if err != nil || mediaType != "multipart/form-data" {
+		t.Fatalf("unexpected content type %q: %v", f.contentType(), err)
+	}
+	form, err := multipart.NewReader(
+		strings.NewReader(string(inMemory)), params["boundary"],
+	).ReadForm(1 << 20)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if e, a := []string{"value"}, form.Value["name"]; !reflect.DeepEqual(e, a) {
+		t.Errorf("expected %v, but got %v", e, a)
+	}
+	files := form.File["upload"]
+	if len(files) != 1 || files[0].Filename != "upload.txt" {
+		t.Fatalf("unexpected files: %+v", files)
+	}
+	file, err := files[0].Open()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	contents, _ := ioutil.ReadAll(file)
+	if e, a := "file contents", string(contents); e != a {
+		t.Errorf("expected %q, but got %q", e, a)
+	}
+}
+
+func TestFormBodyMissingFile(t *testing.T) {
+	_, err := newFormBody(formFields{
+		{name: "upload", value: "/does/not/exist.forreal", file: true},
+	})
+	if err == nil {
+		t.Error("expected an error for missing file")
+	}
+}
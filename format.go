@@ -2,6 +2,9 @@ package main
 
 import (
 	"fmt"
+	"math"
+	"strconv"
+	"strings"
 )
 
 type units struct {
@@ -53,3 +56,30 @@ func formatTimeUs(n float64) string {
 	}
 	return formatUnits(n, units, 2)
 }
+
+// formatPercentile formats fraction as percents without trailing zeros
+// and floating point noise, e.g. 0.999 as 99.9.
+func formatPercentile(pc float64) string {
+	return strconv.FormatFloat(math.Round(pc*100*1e9)/1e9, 'f', -1, 64)
+}
+
+// formatCSVField quotes s, if it has to be quoted to be a field of CSV
+// record, as described in RFC 4180.
+func formatCSVField(s string) string {
+	if !strings.ContainsAny(s, ",\"\r\n") {
+		return s
+	}
+	return `"` + strings.Replace(s, `"`, `""`, -1) + `"`
+}
+
+var markdownCellReplacer = strings.NewReplacer(
+	`\`, `\\`, "|", `\|`, "`", "\\`", "*", `\*`, "_", `\_`, "~", `\~`,
+	"[", `\[`, "]", `\]`, "<", `\<`, ">", `\>`, "&", `\&`,
+	"\r\n", " ", "\n", " ",
+)
+
+// formatMarkdownCell escapes s, so it fits into a single cell of
+// Markdown table and is shown as is.
+func formatMarkdownCell(s string) string {
+	return markdownCellReplacer.Replace(s)
+}
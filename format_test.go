@@ -63,3 +63,62 @@ func TestShouldFormatUs(t *testing.T) {
 		}
 	}
 }
+
+func TestShouldFormatPercentile(t *testing.T) {
+	expectations := []struct {
+		in  float64
+		out string
+	}{
+		{0.5, "50"},
+		{0.999, "99.9"},
+		{0.9999, "99.99"},
+		{0.07, "7"},
+		{1, "100"},
+	}
+	for _, e := range expectations {
+		actual := formatPercentile(e.in)
+		expected := e.out
+		if expected != actual {
+			t.Errorf("Expected \"%v\", but got \"%v\"", expected, actual)
+		}
+	}
+}
+
+func TestShouldFormatCSVField(t *testing.T) {
+	expectations := []struct {
+		in  string
+		out string
+	}{
+		{"GET", "GET"},
+		{"http://localhost/?a=b,c", `"http://localhost/?a=b,c"`},
+		{`say "hi"`, `"say ""hi"""`},
+		{"two\nlines", "\"two\nlines\""},
+	}
+	for _, e := range expectations {
+		actual := formatCSVField(e.in)
+		expected := e.out
+		if expected != actual {
+			t.Errorf("Expected \"%v\", but got \"%v\"", expected, actual)
+		}
+	}
+}
+
+func TestShouldFormatMarkdownCell(t *testing.T) {
+	expectations := []struct {
+		in  string
+		out string
+	}{
+		{"GET", "GET"},
+		{"a|b", `a\|b`},
+		{`<b>*bold*</b>`, `\<b\>\*bold\*\</b\>`},
+		{"two\nlines", "two lines"},
+		{`C:\tmp`, `C:\\tmp`},
+	}
+	for _, e := range expectations {
+		actual := formatMarkdownCell(e.in)
+		expected := e.out
+		if expected != actual {
+			t.Errorf("Expected \"%v\", but got \"%v\"", expected, actual)
+		}
+	}
+}
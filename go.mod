@@ -6,10 +6,12 @@ toolchain go1.24.0
 
 require (
 	github.com/alecthomas/kingpin v2.2.6+incompatible
+	github.com/andybalholm/brotli v1.1.1
 	github.com/cheggaaa/pb v1.0.29
 	github.com/codesenberg/concurrent v0.0.0-20180531114123-64560cfcf964
 	github.com/goware/urlx v0.3.2
 	github.com/juju/ratelimit v1.0.2
+	github.com/klauspost/compress v1.18.0
 	github.com/satori/go.uuid v1.2.0
 	github.com/valyala/fasthttp v1.59.0
 )
@@ -18,8 +20,6 @@ require (
 	github.com/PuerkitoBio/purell v1.2.1 // indirect
 	github.com/alecthomas/template v0.0.0-20190718012654-fb15b899a751 // indirect
 	github.com/alecthomas/units v0.0.0-20240927000941-0f3dac36c52b // indirect
-	github.com/andybalholm/brotli v1.1.1 // indirect
-	github.com/klauspost/compress v1.18.0 // indirect
 	github.com/mattn/go-runewidth v0.0.16 // indirect
 	github.com/rivo/uniseg v0.4.7 // indirect
 	github.com/valyala/bytebufferpool v1.0.0 // indirect
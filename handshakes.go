@@ -0,0 +1,27 @@
+package main
+
+import (
+	"sync/atomic"
+)
+
+// handshakeStats accumulates information about TLS handshakes
+// performed in TLS handshake benchmarking mode.
+type handshakeStats struct {
+	full, resumed uint64
+	latencies     *hdrHistogram
+}
+
+func newHandshakeStats(digits uint64) *handshakeStats {
+	return &handshakeStats{
+		latencies: newHDRHistogram(digits),
+	}
+}
+
+func (h *handshakeStats) record(resumed bool, usTaken uint64) {
+	h.latencies.Increment(usTaken)
+	if resumed {
+		atomic.AddUint64(&h.resumed, 1)
+	} else {
+		atomic.AddUint64(&h.full, 1)
+	}
+}
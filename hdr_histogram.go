@@ -0,0 +1,143 @@
+package main
+
+import (
+	"math"
+	"math/bits"
+	"sync/atomic"
+)
+
+const (
+	defaultHistogramDigits = uint64(3)
+
+	// maxHistogramDigits is limited by memory, each digit makes
+	// histograms about ten times larger and with 4 digits a histogram
+	// takes 3.5MB already.
+	maxHistogramDigits = uint64(4)
+
+	// hdrHighestTrackable is about 12 days in microseconds or 1TB in
+	// bytes, larger values are recorded as this one.
+	hdrHighestTrackable = uint64(1) << 40
+)
+
+// hdrHistogram is a concurrent histogram of uint64 values, which, as
+// HdrHistogram does, keeps values with the given number of significant
+// decimal digits in a fixed amount of memory. Values are grouped into
+// buckets covering powers of two, each split into the same number of
+// linearly spaced sub-buckets, so small values are recorded exactly
+// and relative error of large ones stays within 10^-digits.
+type hdrHistogram struct {
+	digits uint64
+
+	subBucketHalfCountMagnitude uint
+	subBucketHalfCount          uint64
+	subBucketMask               uint64
+
+	counts []uint64
+}
+
+func newHDRHistogram(digits uint64) *hdrHistogram {
+	if digits == 0 {
+		digits = defaultHistogramDigits
+	}
+	largestSingleUnit := 2 * uint64(math.Pow10(int(digits)))
+	subBucketCountMagnitude := uint(bits.Len64(largestSingleUnit - 1))
+	subBucketCount := uint64(1) << subBucketCountMagnitude
+
+	bucketCount := 1
+	smallestUntrackable := subBucketCount
+	for smallestUntrackable <= hdrHighestTrackable {
+		smallestUntrackable <<= 1
+		bucketCount++
+	}
+
+	h := &hdrHistogram{
+		digits:                      digits,
+		subBucketHalfCountMagnitude: subBucketCountMagnitude - 1,
+		subBucketHalfCount:          subBucketCount / 2,
+		subBucketMask:               subBucketCount - 1,
+	}
+	h.counts = make([]uint64, uint64(bucketCount+1)*h.subBucketHalfCount)
+	return h
+}
+
+func (h *hdrHistogram) bucketIndex(v uint64) uint {
+	return uint(bits.Len64(v|h.subBucketMask)) - (h.subBucketHalfCountMagnitude + 1)
+}
+
+func (h *hdrHistogram) countsIndex(v uint64) int {
+	if v > hdrHighestTrackable {
+		v = hdrHighestTrackable
+	}
+	bucket := h.bucketIndex(v)
+	subBucket := v >> bucket
+	return int(uint64(bucket+1)<<h.subBucketHalfCountMagnitude +
+		subBucket - h.subBucketHalfCount)
+}
+
+// highestEquivalentValue returns the largest value, which is counted
+// at index i.
+func (h *hdrHistogram) highestEquivalentValue(i int) uint64 {
+	bucket := i>>h.subBucketHalfCountMagnitude - 1
+	subBucket := uint64(i)&(h.subBucketHalfCount-1) + h.subBucketHalfCount
+	if bucket < 0 {
+		subBucket -= h.subBucketHalfCount
+		bucket = 0
+	}
+	return subBucket<<uint(bucket) + (uint64(1) << uint(bucket)) - 1
+}
+
+// Increment records the value.
+func (h *hdrHistogram) Increment(v uint64) {
+	atomic.AddUint64(&h.counts[h.countsIndex(v)], 1)
+}
+
+// Get returns the number of values recorded, which are equivalent to
+// v at the histogram's precision.
+func (h *hdrHistogram) Get(v uint64) uint64 {
+	return atomic.LoadUint64(&h.counts[h.countsIndex(v)])
+}
+
+// VisitAll calls fn with each recorded value (the highest equivalent
+// one) and its count in ascending order of values, until fn returns
+// false.
+func (h *hdrHistogram) VisitAll(fn func(uint64, uint64) bool) {
+	for i := range h.counts {
+		c := atomic.LoadUint64(&h.counts[i])
+		if c == 0 {
+			continue
+		}
+		if !fn(h.highestEquivalentValue(i), c) {
+			return
+		}
+	}
+}
+
+// Count returns the number of distinct recorded values.
+func (h *hdrHistogram) Count() uint64 {
+	n := uint64(0)
+	for i := range h.counts {
+		if atomic.LoadUint64(&h.counts[i]) > 0 {
+			n++
+		}
+	}
+	return n
+}
+
+// snapshot returns a copy of the histogram, which isn't affected by
+// the values recorded later.
+func (h *hdrHistogram) snapshot() *hdrHistogram {
+	c := *h
+	c.counts = make([]uint64, len(h.counts))
+	for i := range h.counts {
+		c.counts[i] = atomic.LoadUint64(&h.counts[i])
+	}
+	return &c
+}
+
+// subtract removes values recorded in the earlier snapshot of the same
+// histogram, so that only values recorded since then are left.
+func (h *hdrHistogram) subtract(earlier *hdrHistogram) {
+	for i := range h.counts {
+		h.counts[i] -= earlier.counts[i]
+	}
+}
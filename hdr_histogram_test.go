@@ -0,0 +1,63 @@
+package main
+
+import (
+	"math/rand"
+	"testing"
+)
+
+func TestHDRHistogramSmallValuesAreExact(t *testing.T) {
+	h := newHDRHistogram(3)
+	for v := uint64(0); v < 2048; v++ {
+		h.Increment(v)
+	}
+	expected := uint64(0)
+	h.VisitAll(func(v, c uint64) bool {
+		if v != expected || c != 1 {
+			t.Fatalf("expected %v once, but got %v %v time(s)", expected, v, c)
+		}
+		expected++
+		return true
+	})
+	if h.Count() != 2048 {
+		t.Errorf("expected 2048 distinct values, but got %v", h.Count())
+	}
+}
+
+func TestHDRHistogramPrecision(t *testing.T) {
+	for digits := uint64(1); digits <= maxHistogramDigits; digits++ {
+		h := newHDRHistogram(digits)
+		maxError := 1.0
+		for i := uint64(0); i < digits; i++ {
+			maxError /= 10
+		}
+		for i := 0; i < 1000; i++ {
+			v := uint64(rand.Int63n(int64(hdrHighestTrackable)))
+			idx := h.countsIndex(v)
+			highest := h.highestEquivalentValue(idx)
+			if highest < v || float64(highest-v) > float64(v)*maxError {
+				t.Fatalf("%v digits: %v is recorded as %v", digits, v, highest)
+			}
+		}
+	}
+}
+
+func TestHDRHistogramCounts(t *testing.T) {
+	h := newHDRHistogram(2)
+	h.Increment(123456)
+	h.Increment(123457)
+	h.Increment(hdrHighestTrackable * 2)
+	if c := h.Get(123456); c != 2 {
+		t.Errorf("expected equivalent values to share the count, got %v", c)
+	}
+	if c := h.Get(hdrHighestTrackable); c != 1 {
+		t.Errorf("expected too large value to be clamped, got %v", c)
+	}
+	total := uint64(0)
+	h.VisitAll(func(_, c uint64) bool {
+		total += c
+		return true
+	})
+	if total != 3 {
+		t.Errorf("expected 3 values in total, but got %v", total)
+	}
+}
@@ -0,0 +1,292 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"compress/zlib"
+	"encoding/base64"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"strconv"
+	"time"
+)
+
+const (
+	// cookies of HdrHistogram's V2 encoding, 0x10 tells that counts
+	// are ZigZag LEB128 encoded
+	hdrEncodingCookie           = 0x1c849303 | 0x10
+	hdrCompressedEncodingCookie = 0x1c849304 | 0x10
+
+	// hdrValueUnitRatio converts recorded microseconds into
+	// milliseconds, which HdrHistogram tools expect in the output.
+	hdrValueUnitRatio = 1000.0
+
+	// hdrPercentileTicks is the number of percentiles reported per
+	// each halving of the distance to 100%, HdrHistogram's default.
+	hdrPercentileTicks = 5
+)
+
+type hdrEncodingHeader struct {
+	Cookie                 int32
+	PayloadLength          int32
+	NormalizingIndexOffset int32
+	Digits                 int32
+	LowestDiscernibleValue int64
+	HighestTrackableValue  int64
+	ConversionRatio        float64
+}
+
+// encode returns the histogram in HdrHistogram's compressed V2
+// encoding as base64, the way histograms are stored in interval logs.
+// It must only be called on snapshots.
+func (h *hdrHistogram) encode() (string, error) {
+	limit := len(h.counts)
+	for limit > 0 && h.counts[limit-1] == 0 {
+		limit--
+	}
+	var payload bytes.Buffer
+	for i := 0; i < limit; {
+		count := h.counts[i]
+		i++
+		if count == 0 {
+			// runs of zeros are encoded as negative lengths
+			zeros := int64(1)
+			for i < limit && h.counts[i] == 0 {
+				zeros++
+				i++
+			}
+			if zeros > 1 {
+				putZigZag(&payload, -zeros)
+				continue
+			}
+		}
+		putZigZag(&payload, int64(count))
+	}
+
+	var compressed bytes.Buffer
+	zw := zlib.NewWriter(&compressed)
+	header := hdrEncodingHeader{
+		Cookie:                 hdrEncodingCookie,
+		PayloadLength:          int32(payload.Len()),
+		Digits:                 int32(h.digits),
+		LowestDiscernibleValue: 1,
+		HighestTrackableValue:  int64(hdrHighestTrackable),
+		ConversionRatio:        1,
+	}
+	if err := binary.Write(zw, binary.BigEndian, &header); err != nil {
+		return "", err
+	}
+	if _, err := payload.WriteTo(zw); err != nil {
+		return "", err
+	}
+	if err := zw.Close(); err != nil {
+		return "", err
+	}
+
+	var encoded bytes.Buffer
+	prefix := [2]int32{hdrCompressedEncodingCookie, int32(compressed.Len())}
+	if err := binary.Write(&encoded, binary.BigEndian, prefix); err != nil {
+		return "", err
+	}
+	compressed.WriteTo(&encoded)
+	return base64.StdEncoding.EncodeToString(encoded.Bytes()), nil
+}
+
+// putZigZag writes v in ZigZag LEB128 encoding, which, unlike the
+// one used by encoding/binary, takes at most 9 bytes.
+func putZigZag(buf *bytes.Buffer, v int64) {
+	u := uint64(v<<1) ^ uint64(v>>63)
+	for i := 0; i < 8 && u >= 0x80; i++ {
+		buf.WriteByte(byte(u) | 0x80)
+		u >>= 7
+	}
+	buf.WriteByte(byte(u))
+}
+
+// max returns the largest recorded value (the highest equivalent one)
+// or zero, if there are none. It must only be called on snapshots.
+func (h *hdrHistogram) max() uint64 {
+	for i := len(h.counts) - 1; i >= 0; i-- {
+		if h.counts[i] > 0 {
+			return h.highestEquivalentValue(i)
+		}
+	}
+	return 0
+}
+
+// writeHgrm writes percentile distribution of latencies in the format
+// of HdrHistogram's outputPercentileDistribution (.hgrm files), which
+// its plotter understands.
+func writeHgrm(w io.Writer, latencies *hdrHistogram) error {
+	h := latencies.snapshot()
+	var total, sum uint64
+	last := -1
+	for i, c := range h.counts {
+		if c > 0 {
+			total += c
+			sum += c * h.highestEquivalentValue(i)
+			last = i
+		}
+	}
+	value := "%12." + strconv.FormatUint(h.digits, decBase) + "f"
+
+	bw := bufio.NewWriter(w)
+	fmt.Fprintf(bw, "%12s %14s %10s %14s\n\n",
+		"Value", "Percentile", "TotalCount", "1/(1-Percentile)")
+	var cumulative uint64
+	level := 0.0
+	for i := 0; i < last; i++ {
+		if h.counts[i] == 0 {
+			continue
+		}
+		cumulative += h.counts[i]
+		v := float64(h.highestEquivalentValue(i)) / hdrValueUnitRatio
+		for 100*float64(cumulative)/float64(total) >= level {
+			fmt.Fprintf(bw, value+" %2.12f %10d %14.2f\n",
+				v, level/100, cumulative, 1/(1-level/100))
+			level = nextPercentileLevel(level)
+		}
+	}
+	if last >= 0 {
+		v := float64(h.highestEquivalentValue(last)) / hdrValueUnitRatio
+		fmt.Fprintf(bw, value+" %2.12f %10d %14.2f\n",
+			v, level/100, total, 1/(1-level/100))
+		fmt.Fprintf(bw, value+" %2.12f %10d\n", v, 1.0, total)
+	}
+
+	mean, stddev := 0.0, 0.0
+	if total > 0 {
+		mean = float64(sum) / float64(total)
+		for i, c := range h.counts {
+			if c > 0 {
+				d := float64(h.highestEquivalentValue(i)) - mean
+				stddev += d * d * float64(c)
+			}
+		}
+		stddev = math.Sqrt(stddev / float64(total))
+	}
+	fmt.Fprintf(bw, "#[Mean    = "+value+", StdDeviation   = "+value+"]\n",
+		mean/hdrValueUnitRatio, stddev/hdrValueUnitRatio)
+	fmt.Fprintf(bw, "#[Max     = "+value+", Total count    = %12d]\n",
+		float64(h.max())/hdrValueUnitRatio, total)
+	subBuckets := 2 * h.subBucketHalfCount
+	fmt.Fprintf(bw, "#[Buckets = %12d, SubBuckets     = %12d]\n",
+		uint64(len(h.counts))/h.subBucketHalfCount-1, subBuckets)
+	return bw.Flush()
+}
+
+// nextPercentileLevel halves the step between reported percentiles
+// each time the distance to 100% halves.
+func nextPercentileLevel(level float64) float64 {
+	halvings := math.Floor(math.Log(100/(100-level)) / math.Log(2))
+	ticks := hdrPercentileTicks * math.Pow(2, halvings+1)
+	return level + 100/ticks
+}
+
+// hdrLog writes latency histograms in HdrHistogram interval log
+// format, one histogram per interval or, if interval is zero, a single
+// one for the whole test. Histograms of intervals are differences
+// between snapshots of latencies taken at their ends.
+type hdrLog struct {
+	interval  time.Duration
+	latencies *hdrHistogram
+
+	file *os.File
+	w    *bufio.Writer
+	err  error
+
+	start    time.Time
+	last     *hdrHistogram
+	lastTime time.Time
+
+	stop, done chan struct{}
+}
+
+func newHDRLog(
+	path string, interval time.Duration, latencies *hdrHistogram,
+) (*hdrLog, error) {
+	file, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+	return &hdrLog{
+		interval:  interval,
+		latencies: latencies,
+		file:      file,
+		w:         bufio.NewWriter(file),
+		stop:      make(chan struct{}),
+		done:      make(chan struct{}),
+	}, nil
+}
+
+// begin writes the header of the log and starts writing intervals
+// counted from start.
+func (l *hdrLog) begin(start time.Time) {
+	l.start, l.lastTime = start, start
+	l.last = l.latencies.snapshot()
+	secs := float64(start.UnixNano()) / 1e9
+	_, l.err = fmt.Fprintf(l.w,
+		"#[Latencies recorded by bombardier, in microseconds]\n"+
+			"#[Histogram log format version 1.3]\n"+
+			"#[StartTime: %.3f (seconds since epoch), %v]\n"+
+			"#[BaseTime: %.3f (seconds since epoch)]\n"+
+			"\"StartTimestamp\",\"Interval_Length\",\"Interval_Max\","+
+			"\"Interval_Compressed_Histogram\"\n",
+		secs, start.Format("Mon Jan 02 15:04:05 MST 2006"), secs,
+	)
+	go l.writer()
+}
+
+func (l *hdrLog) writer() {
+	defer close(l.done)
+	var tick <-chan time.Time
+	if l.interval > 0 {
+		ticker := time.NewTicker(l.interval)
+		defer ticker.Stop()
+		tick = ticker.C
+	}
+	for {
+		select {
+		case now := <-tick:
+			l.writeInterval(now)
+		case <-l.stop:
+			l.writeInterval(time.Now())
+			return
+		}
+	}
+}
+
+func (l *hdrLog) writeInterval(end time.Time) {
+	current := l.latencies.snapshot()
+	interval := current.snapshot()
+	interval.subtract(l.last)
+	from, length := l.lastTime.Sub(l.start), end.Sub(l.lastTime)
+	l.last, l.lastTime = current, end
+	if l.err != nil {
+		return
+	}
+	encoded, err := interval.encode()
+	if err != nil {
+		l.err = err
+		return
+	}
+	_, l.err = fmt.Fprintf(l.w, "%.3f,%.3f,%.3f,%v\n",
+		from.Seconds(), length.Seconds(),
+		float64(interval.max())/hdrValueUnitRatio, encoded)
+}
+
+// close writes the last interval and closes the file.
+func (l *hdrLog) close() error {
+	close(l.stop)
+	<-l.done
+	if err := l.w.Flush(); l.err == nil {
+		l.err = err
+	}
+	if err := l.file.Close(); l.err == nil {
+		l.err = err
+	}
+	return l.err
+}
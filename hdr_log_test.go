@@ -0,0 +1,158 @@
+package main
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/base64"
+	"encoding/binary"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// decodeHDR is a straightforward implementation of HdrHistogram's
+// decoder of compressed V2 encoding.
+func decodeHDR(t *testing.T, encoded string) (hdrEncodingHeader, []uint64) {
+	t.Helper()
+	raw, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var prefix [2]int32
+	r := bytes.NewReader(raw)
+	if err := binary.Read(r, binary.BigEndian, &prefix); err != nil {
+		t.Fatal(err)
+	}
+	if prefix[0] != hdrCompressedEncodingCookie || int(prefix[1]) != r.Len() {
+		t.Fatalf("unexpected compressed encoding prefix: %x", prefix)
+	}
+	zr, err := zlib.NewReader(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var header hdrEncodingHeader
+	if err := binary.Read(zr, binary.BigEndian, &header); err != nil {
+		t.Fatal(err)
+	}
+	payload, err := io.ReadAll(zr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if int(header.PayloadLength) != len(payload) {
+		t.Fatalf("expected payload of %v bytes, but got %v",
+			header.PayloadLength, len(payload))
+	}
+	var counts []uint64
+	for len(payload) > 0 {
+		var u uint64
+		for i := uint(0); ; i++ {
+			b := payload[0]
+			payload = payload[1:]
+			if i == 8 {
+				u |= uint64(b) << 56
+				break
+			}
+			u |= uint64(b&0x7f) << (7 * i)
+			if b < 0x80 {
+				break
+			}
+		}
+		v := int64(u>>1) ^ -int64(u&1)
+		if v < 0 {
+			counts = append(counts, make([]uint64, -v)...)
+		} else {
+			counts = append(counts, uint64(v))
+		}
+	}
+	return header, counts
+}
+
+func TestHDREncodingRoundTrip(t *testing.T) {
+	h := newHDRHistogram(2)
+	values := []uint64{1, 1, 7, 199, 200, 12345, 1 << 20, 1<<40 + 1}
+	for _, v := range values {
+		h.Increment(v)
+	}
+	s := h.snapshot()
+	encoded, err := s.encode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	header, counts := decodeHDR(t, encoded)
+	exp := hdrEncodingHeader{
+		Cookie:                 hdrEncodingCookie,
+		PayloadLength:          header.PayloadLength,
+		Digits:                 2,
+		LowestDiscernibleValue: 1,
+		HighestTrackableValue:  int64(hdrHighestTrackable),
+		ConversionRatio:        1,
+	}
+	if header != exp {
+		t.Errorf("expected header %+v, but got %+v", exp, header)
+	}
+	// trailing zeros aren't encoded
+	full := make([]uint64, len(s.counts))
+	copy(full, counts)
+	if !reflect.DeepEqual(full, s.counts) {
+		t.Error("decoded counts differ from the recorded ones")
+	}
+	if !strings.HasPrefix(encoded, "HISTF") {
+		t.Errorf("expected encoding to start with HISTF, but got %q",
+			encoded[:5])
+	}
+}
+
+func TestPutZigZag(t *testing.T) {
+	expectations := []struct {
+		in  int64
+		out []byte
+	}{
+		{0, []byte{0}},
+		{-1, []byte{1}},
+		{1, []byte{2}},
+		{64, []byte{0x80, 1}},
+		{-3, []byte{5}},
+		{
+			-1 << 63,
+			[]byte{0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff},
+		},
+	}
+	for _, e := range expectations {
+		var buf bytes.Buffer
+		putZigZag(&buf, e.in)
+		if !bytes.Equal(buf.Bytes(), e.out) {
+			t.Errorf("%v: expected %x, but got %x", e.in, e.out, buf.Bytes())
+		}
+	}
+}
+
+func TestWriteHgrm(t *testing.T) {
+	h := newHDRHistogram(3)
+	for v := uint64(1); v <= 1000; v++ {
+		h.Increment(v)
+	}
+	var out bytes.Buffer
+	if err := writeHgrm(&out, h); err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSuffix(out.String(), "\n"), "\n")
+	expected := map[int]string{
+		0:  "       Value     Percentile TotalCount 1/(1-Percentile)",
+		1:  "",
+		2:  "       0.001 0.000000000000          1           1.00",
+		3:  "       0.100 0.100000000000        100           1.11",
+		-4: "       1.000 1.000000000000       1000",
+		-3: "#[Mean    =        0.500, StdDeviation   =        0.289]",
+		-2: "#[Max     =        1.000, Total count    =         1000]",
+		-1: "#[Buckets =           31, SubBuckets     =         2048]",
+	}
+	for i, e := range expected {
+		if i < 0 {
+			i += len(lines)
+		}
+		if lines[i] != e {
+			t.Errorf("line %v: expected %q, but got %q", i, e, lines[i])
+		}
+	}
+}
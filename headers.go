@@ -29,3 +29,15 @@ func (h *headersList) Set(value string) error {
 	})
 	return nil
 }
+
+// withHeader returns a copy of h, in which key is set to value.
+func withHeader(h *headersList, key, value string) *headersList {
+	res := make(headersList, 0, len(*h)+1)
+	for _, hdr := range *h {
+		if !strings.EqualFold(hdr.key, key) {
+			res = append(res, hdr)
+		}
+	}
+	res = append(res, header{key, value})
+	return &res
+}
@@ -1,9 +1,11 @@
 package internal
 
 import (
+	"fmt"
 	"math"
 	"net/url"
 	"sort"
+	"strings"
 	"time"
 )
 
@@ -42,7 +44,29 @@ type Spec struct {
 	Timeout    time.Duration
 	ClientType ClientType
 
-	Rate *uint64
+	Rate      *uint64
+	ThinkTime string
+
+	TLSHandshake         bool
+	TLSSessionResumption bool
+
+	ScenarioPath string
+	MixPath      string
+
+	// ReplaySpeed is zero, unless the original timing of the
+	// replayed requests was kept.
+	ReplayPath  string
+	ReplaySpeed float64
+
+	// MaxRedirects is zero, unless redirects were followed.
+	MaxRedirects uint64
+
+	CompressBody   string
+	AcceptEncoding string
+
+	// SampleResponses is the number of responses saved for each
+	// status code.
+	SampleResponses uint64
 }
 
 // RequestURL returns URL as string.
@@ -91,6 +115,316 @@ type Results struct {
 
 	Latencies ReadonlyUint64Histogram
 	Requests  ReadonlyFloat64Histogram
+
+	// ResponseSizes is map[size of response body in bytes]responses
+	ResponseSizes ReadonlyUint64Histogram
+
+	// TLSHandshakes is nil, unless the test was run in TLS handshake
+	// benchmarking mode.
+	TLSHandshakes *TLSHandshakes
+
+	// Connections is nil, unless connections were tracked.
+	Connections *Connections
+
+	// Steps are the results of individual scenario steps, if the
+	// test was run with a scenario.
+	Steps []StepStats
+
+	// Mix holds the results of each request of the mix, if the test
+	// was run with one.
+	Mix []MixEntryStats
+
+	// Redirects is nil, unless redirects were followed.
+	Redirects *Redirects
+
+	// ThinkTime is nil, unless connections paused between requests.
+	ThinkTime *ThinkTime
+
+	// TokenFailures is the number of failed attempts to refresh
+	// bearer token.
+	TokenFailures uint64
+
+	// ResponseBodies is nil, unless Accept-Encoding was sent.
+	ResponseBodies *ResponseBodies
+
+	// ResponseSamples is nil, unless responses were sampled.
+	ResponseSamples *ResponseSamples
+
+	// RequestLog is nil, unless requests were logged.
+	RequestLog *RequestLog
+
+	// Timeline is the number of requests completed over time.
+	Timeline *Timeline
+
+	// Tracing is nil, unless trace context was propagated.
+	Tracing *Tracing
+}
+
+// Tracing holds the slowest of the traced requests and the number of
+// spans exported.
+type Tracing struct {
+	// Slowest are sorted from the slowest one.
+	Slowest []TracedRequest
+
+	// Dropped is the number of spans, which weren't exported, because
+	// exporting couldn't keep up or failed.
+	Exported, Dropped uint64
+}
+
+// TracedRequest is a request sent with trace context.
+type TracedRequest struct {
+	TraceID     string
+	Method, URL string
+
+	// Code is zero and Error is set, if the request failed.
+	Code  int
+	Error string
+
+	// Latency is in microseconds.
+	Latency uint64
+}
+
+// Timeline holds the number of requests completed during each interval
+// of the test. Requests are counted in batches spread between the
+// intervals, so the numbers aren't whole.
+type Timeline struct {
+	Interval time.Duration
+	Requests []float64
+
+	// End is the time from the start of the test until the last
+	// request accounted, the last interval ends there.
+	End time.Duration
+}
+
+// RequestsPerSecond returns the rate of requests during each interval.
+func (t *Timeline) RequestsPerSecond() []float64 {
+	rps := make([]float64, 0, len(t.Requests))
+	for i, n := range t.Requests {
+		length := t.Interval
+		if end := time.Duration(i+1) * t.Interval; end > t.End {
+			length -= end - t.End
+		}
+		if length <= 0 {
+			break
+		}
+		rps = append(rps, n/length.Seconds())
+	}
+	return rps
+}
+
+// HistogramBucket is a value recorded in the histogram and the number
+// of times it was recorded.
+type HistogramBucket struct {
+	Value uint64 `json:"value"`
+	Count uint64 `json:"count"`
+}
+
+// LatenciesBuckets returns recorded latencies (in microseconds) in
+// ascending order.
+func (r Results) LatenciesBuckets() []HistogramBucket {
+	buckets := make([]HistogramBucket, 0, r.Latencies.Count())
+	r.Latencies.VisitAll(func(v, c uint64) bool {
+		buckets = append(buckets, HistogramBucket{v, c})
+		return true
+	})
+	sort.Slice(buckets, func(i, j int) bool {
+		return buckets[i].Value < buckets[j].Value
+	})
+	return buckets
+}
+
+// RequestLog tells how many records were written into the request
+// log and how many were dropped, because writing couldn't keep up.
+type RequestLog struct {
+	Path             string
+	Written, Dropped uint64
+}
+
+// ThinkTime holds the time connections spent pausing between
+// requests and waiting for responses.
+type ThinkTime struct {
+	Pauses         uint64
+	Total, Waiting time.Duration
+}
+
+// Mean returns average length of a single pause.
+func (t *ThinkTime) Mean() time.Duration {
+	if t.Pauses == 0 {
+		return 0
+	}
+	return t.Total / time.Duration(t.Pauses)
+}
+
+// EffectiveConcurrency returns average number of requests in flight,
+// which is lower than the number of connections, since connections
+// don't send requests while thinking.
+func (r Results) EffectiveConcurrency() float64 {
+	if r.ThinkTime == nil || r.TimeTaken <= 0 {
+		return 0
+	}
+	return r.ThinkTime.Waiting.Seconds() / r.TimeTaken.Seconds()
+}
+
+// ResponseSamples tells where sampled responses were saved.
+type ResponseSamples struct {
+	Dir           string
+	Saved, Failed uint64
+}
+
+// ResponseBodies holds total sizes of response bodies as they were
+// received and after decompression, as well as the number of bodies,
+// which couldn't be decompressed.
+type ResponseBodies struct {
+	Compressed, Decompressed uint64
+	DecodeFailures           uint64
+}
+
+// CompressionRatio returns how many times bodies were smaller because
+// of compression.
+func (r *ResponseBodies) CompressionRatio() float64 {
+	if r.Compressed == 0 {
+		return 1
+	}
+	return float64(r.Decompressed) / float64(r.Compressed)
+}
+
+// Redirects holds information about redirects followed during the
+// test.
+type Redirects struct {
+	// Hops is map[number of redirects followed]requests
+	Hops ReadonlyUint64Histogram
+}
+
+// HopsWithCount is the number of requests that took that many hops.
+type HopsWithCount struct {
+	Hops, Count uint64
+}
+
+// Total returns total number of redirects followed.
+func (r *Redirects) Total() uint64 {
+	total := uint64(0)
+	r.Hops.VisitAll(func(hops, count uint64) bool {
+		total += hops * count
+		return true
+	})
+	return total
+}
+
+// Redirected returns number of requests that were redirected at least
+// once.
+func (r *Redirects) Redirected() uint64 {
+	redirected := uint64(0)
+	r.Hops.VisitAll(func(hops, count uint64) bool {
+		if hops > 0 {
+			redirected += count
+		}
+		return true
+	})
+	return redirected
+}
+
+// Distribution returns number of requests for each number of hops in
+// ascending order of hops.
+func (r *Redirects) Distribution() []HopsWithCount {
+	var res []HopsWithCount
+	r.Hops.VisitAll(func(hops, count uint64) bool {
+		if count > 0 {
+			res = append(res, HopsWithCount{hops, count})
+		}
+		return true
+	})
+	sort.Slice(res, func(i, j int) bool {
+		return res[i].Hops < res[j].Hops
+	})
+	return res
+}
+
+// StepStats holds results of a single step of the scenario.
+type StepStats struct {
+	Name   string
+	Method string
+
+	Requests, Errors uint64
+
+	Latencies ReadonlyUint64Histogram
+}
+
+// LatenciesStats performs various statistical calculations on
+// latencies of the step.
+func (s StepStats) LatenciesStats(percentiles []float64) *LatenciesStats {
+	return uint64HistogramStats(s.Latencies, percentiles)
+}
+
+// MixEntryStats holds results of a single request of the mix.
+type MixEntryStats struct {
+	Name   string
+	Method string
+
+	// Expected and Actual are the shares of this request among all
+	// requests sent, as set by weights and as achieved.
+	Expected, Actual float64
+
+	Requests, Errors uint64
+
+	Latencies ReadonlyUint64Histogram
+}
+
+// LatenciesStats performs various statistical calculations on
+// latencies of the request.
+func (m MixEntryStats) LatenciesStats(percentiles []float64) *LatenciesStats {
+	return uint64HistogramStats(m.Latencies, percentiles)
+}
+
+// TLSHandshakes holds information about TLS handshakes performed
+// during the test.
+type TLSHandshakes struct {
+	Full, Resumed uint64
+
+	Latencies ReadonlyUint64Histogram
+}
+
+// Connections holds information about connections opened during
+// the test.
+type Connections struct {
+	Opened, Reused uint64
+
+	// Open is the number of connections left open at the end of
+	// the test.
+	ClosedByServer, ClosedByClient, Open uint64
+
+	DialFailures uint64
+
+	// Peak is the largest number of connections open at once.
+	Peak uint64
+
+	// Requests is the distribution of the number of requests sent over
+	// a connection, Lifetimes is in microseconds. Both include the
+	// connections left open, as if they were closed at the end of the
+	// test.
+	Requests, Lifetimes ReadonlyUint64Histogram
+}
+
+// RequestsStats performs the same calculations as LatenciesStats on
+// the numbers of requests sent over connections.
+func (c *Connections) RequestsStats(percentiles []float64) *LatenciesStats {
+	return uint64HistogramStats(c.Requests, percentiles)
+}
+
+// LifetimesStats performs various statistical calculations on
+// lifetimes of connections.
+func (c *Connections) LifetimesStats(percentiles []float64) *LatenciesStats {
+	return uint64HistogramStats(c.Lifetimes, percentiles)
+}
+
+// Total returns total number of successful TLS handshakes.
+func (t *TLSHandshakes) Total() uint64 {
+	return t.Full + t.Resumed
+}
+
+// LatenciesStats performs various statistical calculations on
+// handshake latencies.
+func (t *TLSHandshakes) LatenciesStats(percentiles []float64) *LatenciesStats {
+	return uint64HistogramStats(t.Latencies, percentiles)
 }
 
 // ReadonlyUint64Histogram is a readonly histogram with uint64 keys
@@ -107,12 +441,98 @@ type ReadonlyFloat64Histogram interface {
 	Count() uint64
 }
 
+// TotalRequests returns the number of requests, which got responses
+// with any code or failed.
+func (r Results) TotalRequests() uint64 {
+	return r.Req1XX + r.Req2XX + r.Req3XX + r.Req4XX + r.Req5XX + r.Others
+}
+
+// Check is the outcome of a check of the results, such as absence of
+// errors.
+type Check struct {
+	// Group is the part of the test checked, e.g. "steps".
+	Group, Name string
+
+	// Failure is empty, unless the check failed, Details describe
+	// the failure in full.
+	Failure, Details string
+}
+
+// Passed tells whether the check passed.
+func (c Check) Passed() bool {
+	return c.Failure == ""
+}
+
+// Checks is a list of checks.
+type Checks []Check
+
+// Failures returns the number of failed checks.
+func (cs Checks) Failures() int {
+	failures := 0
+	for _, c := range cs {
+		if !c.Passed() {
+			failures++
+		}
+	}
+	return failures
+}
+
+// Checks returns the outcomes of checks bombardier performs on the
+// results: that requests didn't fail and got no 4xx or 5xx codes and
+// that no requests of scenario steps and of the mix failed. Latencies
+// and throughput aren't checked.
+func (r Results) Checks() Checks {
+	errs := Check{Group: "requests", Name: "no errors"}
+	if r.Others > 0 {
+		errs.Failure = fmt.Sprintf("%v of %v requests failed",
+			r.Others, r.TotalRequests())
+		details := make([]string, 0, len(r.Errors))
+		for _, e := range r.Errors {
+			details = append(details, fmt.Sprintf("%v - %v", e.Error, e.Count))
+		}
+		errs.Details = strings.Join(details, "\n")
+	}
+	codes := Check{Group: "requests", Name: "no 4xx/5xx codes"}
+	if r.Req4XX+r.Req5XX > 0 {
+		codes.Failure = fmt.Sprintf("%v of %v responses had 4xx/5xx codes",
+			r.Req4XX+r.Req5XX, r.TotalRequests()-r.Others)
+		codes.Details = fmt.Sprintf("4xx - %v, 5xx - %v", r.Req4XX, r.Req5XX)
+	}
+	checks := Checks{errs, codes}
+	for _, s := range r.Steps {
+		checks = append(checks,
+			requestsCheck("steps", s.Method, s.Name, s.Requests, s.Errors))
+	}
+	for _, m := range r.Mix {
+		checks = append(checks,
+			requestsCheck("mix", m.Method, m.Name, m.Requests, m.Errors))
+	}
+	return checks
+}
+
+func requestsCheck(group, method, name string, requests, errors uint64) Check {
+	c := Check{Group: group, Name: method + " " + name}
+	if errors > 0 {
+		c.Failure = fmt.Sprintf("%v of %v requests failed", errors, requests)
+	}
+	return c
+}
+
 // Throughput returns total throughput (read + write) in bytes per
 // second
 func (r Results) Throughput() float64 {
 	return float64(r.BytesRead+r.BytesWritten) / r.TimeTaken.Seconds()
 }
 
+// HandshakesPerSecond returns the average number of TLS handshakes
+// performed per second.
+func (r Results) HandshakesPerSecond() float64 {
+	if r.TLSHandshakes == nil {
+		return 0
+	}
+	return float64(r.TLSHandshakes.Total()) / r.TimeTaken.Seconds()
+}
+
 // LatenciesStats contains statistical information about latencies.
 type LatenciesStats struct {
 	// These are in microseconds
@@ -127,7 +547,21 @@ type LatenciesStats struct {
 // LatenciesStats performs various statistical calculations on
 // latencies.
 func (r Results) LatenciesStats(percentiles []float64) *LatenciesStats {
-	h := r.Latencies
+	return uint64HistogramStats(r.Latencies, percentiles)
+}
+
+// ResponseSizesStats performs the same calculations as LatenciesStats
+// on sizes of response bodies, so the values are in bytes.
+func (r Results) ResponseSizesStats(percentiles []float64) *LatenciesStats {
+	if r.ResponseSizes == nil {
+		return nil
+	}
+	return uint64HistogramStats(r.ResponseSizes, percentiles)
+}
+
+func uint64HistogramStats(
+	h ReadonlyUint64Histogram, percentiles []float64,
+) *LatenciesStats {
 	sum := uint64(0)
 	count := uint64(0)
 	max := uint64(0)
@@ -285,8 +719,60 @@ func (r Results) RequestsStats(percentiles []float64) *RequestsStats {
 // ErrorWithCount contains error description alongside with number of
 // times this error occurred.
 type ErrorWithCount struct {
+	// Error is the message with addresses, ports and URLs replaced,
+	// so that errors differing only in them are counted together.
 	Error string
 	Count uint64
+
+	// Category is the kind of error, e.g. "connection refused" or
+	// "read timeout", Example is one of the original messages.
+	Category string
+	Example  string
+}
+
+// ErrorCategory is the number of errors of the same kind.
+type ErrorCategory struct {
+	Name  string
+	Count uint64
+
+	// Examples are the original messages of the most frequent errors
+	// of the category.
+	Examples []string
+}
+
+// maxErrorExamples is the maximum number of examples of a category.
+const maxErrorExamples = 3
+
+// ErrorCategories groups Errors by their categories, the most
+// frequent category goes first.
+func (r Results) ErrorCategories() []ErrorCategory {
+	var categories []ErrorCategory
+	index := make(map[string]int)
+	for _, e := range r.Errors {
+		name := e.Category
+		if name == "" {
+			name = "other"
+		}
+		i, ok := index[name]
+		if !ok {
+			i = len(categories)
+			index[name] = i
+			categories = append(categories, ErrorCategory{Name: name})
+		}
+		c := &categories[i]
+		c.Count += e.Count
+		if len(c.Examples) < maxErrorExamples {
+			example := e.Example
+			if example == "" {
+				example = e.Error
+			}
+			c.Examples = append(c.Examples, example)
+		}
+	}
+	sort.SliceStable(categories, func(i, j int) bool {
+		return categories[i].Count > categories[j].Count
+	})
+	return categories
 }
 
 // TestType represents the type of test that were performed.
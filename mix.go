@@ -0,0 +1,122 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"math/rand"
+	"net/url"
+	"sort"
+	"strconv"
+	"sync/atomic"
+)
+
+// mixSpec is the format of the request mix file.
+type mixSpec struct {
+	Requests []mixEntrySpec `json:"requests"`
+}
+
+// mixEntrySpec is a request described the same way as scenario step,
+// but with a weight.
+type mixEntrySpec struct {
+	stepSpec
+	Weight float64 `json:"weight"`
+}
+
+// mix picks one of the requests at random for each request sent, so
+// that each request is sent with probability proportional to its
+// weight.
+type mix struct {
+	entries []*scenarioStep
+	weights []float64
+
+	// cumulative[i] is the sum of weights of entries up to i
+	cumulative []float64
+}
+
+func loadMix(
+	path string, base *url.URL, feeder *dataFeeder, digits uint64,
+) (*mix, error) {
+	bytes, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var spec mixSpec
+	if err := json.Unmarshal(bytes, &spec); err != nil {
+		return nil, fmt.Errorf("invalid request mix %q: %v", path, err)
+	}
+	return newMix(spec, base, feeder, digits)
+}
+
+func newMix(
+	spec mixSpec, base *url.URL, feeder *dataFeeder, digits uint64,
+) (*mix, error) {
+	if len(spec.Requests) == 0 {
+		return nil, errEmptyMix
+	}
+	m := new(mix)
+	total := 0.0
+	for i, es := range spec.Requests {
+		if es.Name == "" {
+			es.Name = "request " + strconv.Itoa(i+1)
+		}
+		if es.Weight <= 0 {
+			return nil, fmt.Errorf("%v: %v", es.Name, errInvalidMixWeight)
+		}
+		entry, err := newScenarioStep(es.stepSpec, base, feeder, digits)
+		if err != nil {
+			return nil, fmt.Errorf("%v: %v", es.Name, err)
+		}
+		total += es.Weight
+		m.entries = append(m.entries, entry)
+		m.weights = append(m.weights, es.Weight)
+		m.cumulative = append(m.cumulative, total)
+	}
+	return m, nil
+}
+
+func (m *mix) pick() int {
+	r := rand.Float64() * m.totalWeight()
+	i := sort.SearchFloat64s(m.cumulative, r)
+	if m.cumulative[i] == r {
+		// r falls onto the boundary, which belongs to the next entry
+		i++
+	}
+	return i
+}
+
+func (m *mix) prepare(s *session) (*renderedRequest, error) {
+	s.step = m.pick()
+	entry := m.entries[s.step]
+	rr, err := entry.templates.prepare(s)
+	if err != nil {
+		return nil, err
+	}
+	rr.method = entry.method
+	rr.onResponse = entry.extractInto(s)
+	return rr, nil
+}
+
+func (m *mix) compressBodies(encoding string) error {
+	for _, entry := range m.entries {
+		if err := entry.templates.compressBodies(encoding); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// record accounts the result of the request last picked for s.
+func (m *mix) record(s *session, code int, usTaken uint64, err error) {
+	entry := m.entries[s.step]
+	atomic.AddUint64(&entry.requests, 1)
+	entry.latencies.Increment(usTaken)
+	if err != nil || code < 0 || code >= 400 {
+		atomic.AddUint64(&entry.errors, 1)
+	}
+}
+
+// totalWeight is the sum of weights of all the entries.
+func (m *mix) totalWeight() float64 {
+	return m.cumulative[len(m.cumulative)-1]
+}
@@ -0,0 +1,42 @@
+package main
+
+import (
+	"math"
+	"testing"
+)
+
+func TestMixPick(t *testing.T) {
+	m, err := newMix(mixSpec{Requests: []mixEntrySpec{
+		{stepSpec{Path: "/a"}, 70},
+		{stepSpec{Path: "/b"}, 25},
+		{stepSpec{Path: "/c"}, 5},
+	}}, ParseURLOrPanic("http://localhost"), nil, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	const picks = 100000
+	counts := make([]int, len(m.entries))
+	for i := 0; i < picks; i++ {
+		counts[m.pick()]++
+	}
+	for i, expected := range []float64{0.7, 0.25, 0.05} {
+		actual := float64(counts[i]) / picks
+		if math.Abs(actual-expected) > 0.01 {
+			t.Errorf("entry %v: expected share %v, but got %v",
+				i, expected, actual)
+		}
+	}
+}
+
+func TestMixValidation(t *testing.T) {
+	base := ParseURLOrPanic("http://localhost")
+	if _, err := newMix(mixSpec{}, base, nil, 0); err != errEmptyMix {
+		t.Errorf("expected %v, but got %v", errEmptyMix, err)
+	}
+	_, err := newMix(mixSpec{Requests: []mixEntrySpec{
+		{stepSpec{Name: "zero"}, 0},
+	}}, base, nil, 0)
+	if err == nil || err.Error() != "zero: "+errInvalidMixWeight.Error() {
+		t.Errorf("expected weight error, but got %v", err)
+	}
+}
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const (
+	// oauth2RetryInterval is how soon to retry failed token fetch.
+	oauth2RetryInterval = 1 * time.Second
+	// oauth2MinRefresh prevents hammering the token endpoint with
+	// tokens, which expire almost immediately.
+	oauth2MinRefresh = 1 * time.Second
+)
+
+// oauth2Credentials are used to obtain tokens via OAuth 2.0 client
+// credentials grant.
+type oauth2Credentials struct {
+	tokenURL     string
+	clientID     string
+	clientSecret string
+	scope        string
+}
+
+type oauth2TokenResponse struct {
+	AccessToken string `json:"access_token"`
+	TokenType   string `json:"token_type"`
+	ExpiresIn   int64  `json:"expires_in"`
+}
+
+// newOAuth2BearerToken fetches the token before returning and then
+// refreshes it in the background before it expires.
+func newOAuth2BearerToken(
+	creds oauth2Credentials, timeout time.Duration, tlsConfig *tls.Config,
+) (*bearerToken, error) {
+	client := &http.Client{
+		Transport: &http.Transport{
+			Proxy:           http.ProxyFromEnvironment,
+			TLSClientConfig: tlsConfig,
+		},
+		Timeout: timeout,
+	}
+	resp, err := fetchOAuth2Token(client, creds)
+	if err != nil {
+		return nil, err
+	}
+	t := newBearerToken(resp.AccessToken)
+	go t.keepRefreshed(oauth2RefreshIn(resp), func() (time.Duration, error) {
+		resp, err := fetchOAuth2Token(client, creds)
+		if err != nil {
+			return oauth2RetryInterval, err
+		}
+		t.set(resp.AccessToken)
+		return oauth2RefreshIn(resp), nil
+	})
+	return t, nil
+}
+
+// oauth2RefreshIn returns the delay after which the token has to be
+// refreshed, leaving a quarter of its lifetime as a safety margin.
+func oauth2RefreshIn(resp *oauth2TokenResponse) time.Duration {
+	if resp.ExpiresIn <= 0 {
+		return defaultBearerTokenRefresh
+	}
+	refreshIn := time.Duration(resp.ExpiresIn) * time.Second * 3 / 4
+	if refreshIn < oauth2MinRefresh {
+		return oauth2MinRefresh
+	}
+	return refreshIn
+}
+
+func fetchOAuth2Token(
+	client *http.Client, creds oauth2Credentials,
+) (*oauth2TokenResponse, error) {
+	form := url.Values{"grant_type": {"client_credentials"}}
+	if creds.scope != "" {
+		form.Set("scope", creds.scope)
+	}
+	req, err := http.NewRequest(
+		"POST", creds.tokenURL, strings.NewReader(form.Encode()),
+	)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	req.SetBasicAuth(
+		url.QueryEscape(creds.clientID), url.QueryEscape(creds.clientSecret),
+	)
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	body, err := ioutil.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf(
+			"token endpoint responded with %v: %s", resp.Status, body,
+		)
+	}
+	var tr oauth2TokenResponse
+	if err := json.Unmarshal(body, &tr); err != nil {
+		return nil, fmt.Errorf("invalid token response: %v", err)
+	}
+	if tr.AccessToken == "" {
+		return nil, errEmptyToken
+	}
+	return &tr, nil
+}
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// tokenServer is a stub of OAuth 2.0 token endpoint, which issues
+// tokens "token-1", "token-2" and so on.
+type tokenServer struct {
+	*httptest.Server
+
+	expiresIn int64
+	issued    uint64
+	failing   int32
+}
+
+func newTokenServer(t *testing.T, expiresIn int64) *tokenServer {
+	ts := &tokenServer{expiresIn: expiresIn}
+	ts.Server = httptest.NewServer(
+		http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+			// credentials are form-encoded as per RFC 6749
+			id, secret, ok := r.BasicAuth()
+			secret, err := url.QueryUnescape(secret)
+			if !ok || err != nil || id != "client" || secret != "s3cr:t" {
+				rw.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			if r.FormValue("grant_type") != "client_credentials" {
+				t.Errorf("unexpected grant type %q", r.FormValue("grant_type"))
+			}
+			if atomic.LoadInt32(&ts.failing) != 0 {
+				rw.WriteHeader(http.StatusServiceUnavailable)
+				return
+			}
+			n := atomic.AddUint64(&ts.issued, 1)
+			rw.Header().Set("Content-Type", "application/json")
+			_ = json.NewEncoder(rw).Encode(oauth2TokenResponse{
+				AccessToken: fmt.Sprintf("token-%v", n),
+				TokenType:   "Bearer",
+				ExpiresIn:   ts.expiresIn,
+			})
+		}),
+	)
+	t.Cleanup(ts.Close)
+	return ts
+}
+
+func (ts *tokenServer) credentials() oauth2Credentials {
+	return oauth2Credentials{
+		tokenURL:     ts.URL,
+		clientID:     "client",
+		clientSecret: "s3cr:t",
+	}
+}
+
+func (ts *tokenServer) setFailing(failing bool) {
+	v := int32(0)
+	if failing {
+		v = 1
+	}
+	atomic.StoreInt32(&ts.failing, v)
+}
+
+func TestOAuth2BearerTokenRefresh(t *testing.T) {
+	ts := newTokenServer(t, 1)
+	token, err := newOAuth2BearerToken(ts.credentials(), defaultTimeout, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer token.close()
+	if e, a := "token-1", token.get(); e != a {
+		t.Errorf("expected %q, but got %q", e, a)
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for token.get() == "token-1" && time.Now().Before(deadline) {
+		time.Sleep(50 * time.Millisecond)
+	}
+	if e, a := "token-2", token.get(); e != a {
+		t.Errorf("expected %q, but got %q", e, a)
+	}
+
+	ts.setFailing(true)
+	for token.refreshFailures() == 0 && time.Now().Before(deadline) {
+		time.Sleep(50 * time.Millisecond)
+	}
+	if token.refreshFailures() == 0 {
+		t.Error("expected failed refreshes to be counted")
+	}
+	if e, a := "token-2", token.get(); e != a {
+		t.Errorf("expected last known token %q, but got %q", e, a)
+	}
+}
+
+func TestOAuth2BearerTokenInvalidCredentials(t *testing.T) {
+	ts := newTokenServer(t, 1)
+	creds := ts.credentials()
+	creds.clientSecret = "wrong"
+	if _, err := newOAuth2BearerToken(creds, defaultTimeout, nil); err == nil {
+		t.Error("expected an error with invalid credentials")
+	}
+}
+
+func TestOAuth2RefreshIn(t *testing.T) {
+	expectations := []struct {
+		expiresIn int64
+		refreshIn time.Duration
+	}{
+		{0, defaultBearerTokenRefresh},
+		{1, oauth2MinRefresh},
+		{3600, 45 * time.Minute},
+	}
+	for _, e := range expectations {
+		resp := &oauth2TokenResponse{ExpiresIn: e.expiresIn}
+		if a := oauth2RefreshIn(resp); a != e.refreshIn {
+			t.Errorf("expected %v for %v, but got %v",
+				e.refreshIn, e.expiresIn, a)
+		}
+	}
+}
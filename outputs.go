@@ -0,0 +1,105 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"text/template"
+
+	"github.com/codesenberg/bombardier/internal"
+)
+
+// outputSpec is a file, besides the standard output, to write the
+// result to.
+type outputSpec struct {
+	format format
+	path   string
+
+	// append is true, if the result has to be appended to the file
+	// rather than replace its contents.
+	append   bool
+	noHeader bool
+}
+
+type outputList []outputSpec
+
+func (o *outputList) String() string {
+	return fmt.Sprint(*o)
+}
+
+func (o *outputList) IsCumulative() bool {
+	return true
+}
+
+// Set parses comma-separated key=value options, e.g.
+// format=json,file=result.json.
+func (o *outputList) Set(value string) error {
+	var spec outputSpec
+	for _, option := range strings.Split(value, ",") {
+		res := strings.SplitN(option, "=", 2)
+		if len(res) != 2 {
+			return errInvalidOutputFormat
+		}
+		var err error
+		switch key, val := strings.TrimSpace(res[0]), res[1]; key {
+		case "format":
+			spec.format = formatFromString(val)
+			if spec.format == nil {
+				return fmt.Errorf(
+					"unknown format or invalid format spec %q", val,
+				)
+			}
+		case "file":
+			spec.path = val
+		case "append":
+			spec.append, err = strconv.ParseBool(val)
+		case "header":
+			var header bool
+			header, err = strconv.ParseBool(val)
+			spec.noHeader = !header
+		default:
+			return fmt.Errorf("unknown output option %q", key)
+		}
+		if err != nil {
+			return fmt.Errorf("invalid value of %q: %v", option, err)
+		}
+	}
+	if spec.format == nil || spec.path == "" {
+		return errInvalidOutputFormat
+	}
+	*o = append(*o, spec)
+	return nil
+}
+
+// resultOutput is the file the result is written to in its own
+// format.
+type resultOutput struct {
+	file     *os.File
+	template *template.Template
+}
+
+func (b *bombardier) openOutput(spec outputSpec) (*resultOutput, error) {
+	tmpl, err := b.prepareTemplate(spec.format, spec.noHeader)
+	if err != nil {
+		return nil, err
+	}
+	flags := os.O_WRONLY | os.O_CREATE | os.O_TRUNC
+	if spec.append {
+		flags = os.O_WRONLY | os.O_CREATE | os.O_APPEND
+	}
+	// the file is opened before the test to fail early
+	file, err := os.OpenFile(spec.path, flags, 0644)
+	if err != nil {
+		return nil, err
+	}
+	return &resultOutput{file: file, template: tmpl}, nil
+}
+
+func (o *resultOutput) write(info internal.TestInfo) error {
+	err := o.template.Execute(o.file, info)
+	if cerr := o.file.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
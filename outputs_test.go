@@ -0,0 +1,52 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestOutputListParsing(t *testing.T) {
+	outputs := new(outputList)
+	for _, v := range []string{
+		"format=json,file=result.json",
+		"file=results.csv,format=c,append=true,header=false",
+		"format=path:/path/to/tmpl.txt,file=out.txt,append=false",
+	} {
+		if err := outputs.Set(v); err != nil {
+			t.Fatal(err)
+		}
+	}
+	expected := outputList{
+		{format: knownFormat("json"), path: "result.json"},
+		{
+			format:   knownFormat("csv"),
+			path:     "results.csv",
+			append:   true,
+			noHeader: true,
+		},
+		{
+			format: userDefinedTemplate("/path/to/tmpl.txt"),
+			path:   "out.txt",
+		},
+	}
+	if !reflect.DeepEqual(*outputs, expected) {
+		t.Errorf("expected %v, but got %v", expected, *outputs)
+	}
+	for _, v := range []string{
+		"format=json", "file=result.json", "json", "format=json,file",
+	} {
+		if err := outputs.Set(v); err != errInvalidOutputFormat {
+			t.Errorf("expected %v for %q, but got %v",
+				errInvalidOutputFormat, v, err)
+		}
+	}
+	for _, v := range []string{
+		"format=xml,file=result.xml",
+		"format=json,file=result.json,append=maybe",
+		"format=json,file=result.json,mode=0600",
+	} {
+		if err := outputs.Set(v); err == nil {
+			t.Errorf("expected an error for %q", v)
+		}
+	}
+}
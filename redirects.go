@@ -0,0 +1,100 @@
+package main
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/valyala/fasthttp"
+)
+
+// credentialHeaders are the headers net/http doesn't send, when
+// redirected to a different host.
+var credentialHeaders = []string{
+	"Authorization", "Www-Authenticate", "Cookie", "Cookie2",
+}
+
+// crossHostHeaders returns the names of headers, which mustn't be
+// sent to a host other than the one of the original request, i.e.
+// credentials, cookies and the headers set by authorizer, if any.
+func crossHostHeaders(authorizer requestAuthorizer) []string {
+	names := append([]string(nil), credentialHeaders...)
+	if authorizer != nil {
+		names = append(names, authorizer.headerNames()...)
+	}
+	return names
+}
+
+// delFastHTTPHeaders deletes headers with the given names from h,
+// ignoring case, since header names aren't normalized.
+func delFastHTTPHeaders(h *fasthttp.RequestHeader, names []string) {
+	var keys []string
+	h.VisitAll(func(key, _ []byte) {
+		for _, name := range names {
+			if strings.EqualFold(string(key), name) {
+				keys = append(keys, string(key))
+				return
+			}
+		}
+	})
+	for _, key := range keys {
+		h.Del(key)
+	}
+}
+
+// redirectKey identifies a request in a chain of redirects. Visiting
+// the same key twice means that the chain is a loop.
+func redirectKey(method, url string) string {
+	return method + " " + url
+}
+
+// redirectMethod returns the method to use for the request made in
+// response to a redirect with the given code and whether the body of
+// the original request must be sent again. It mirrors the behaviour of
+// net/http.
+func redirectMethod(code int, method string) (string, bool) {
+	switch code {
+	case http.StatusMovedPermanently, http.StatusFound, http.StatusSeeOther:
+		if method != http.MethodGet && method != http.MethodHead {
+			method = http.MethodGet
+		}
+		return method, false
+	}
+	return method, true
+}
+
+// checkRedirectFunc returns a net/http redirect policy, which follows
+// up to maxRedirects redirects and reports loops as errors. Responses
+// that still redirect after maxRedirects hops are returned as is.
+// Headers named by stripped aren't sent to other hosts.
+func checkRedirectFunc(
+	maxRedirects uint64, stripped []string,
+) func(req *http.Request, via []*http.Request) error {
+	return func(req *http.Request, via []*http.Request) error {
+		if uint64(len(via)) > maxRedirects {
+			return http.ErrUseLastResponse
+		}
+		if req.URL.Host != via[0].URL.Host {
+			for _, name := range stripped {
+				req.Header.Del(name)
+			}
+		}
+		key := redirectKey(req.Method, req.URL.String())
+		for _, prev := range via {
+			if redirectKey(prev.Method, prev.URL.String()) == key {
+				return errRedirectLoop
+			}
+		}
+		return nil
+	}
+}
+
+// redirectHops returns the number of redirects followed to get resp.
+func redirectHops(resp *http.Response) uint64 {
+	hops := uint64(0)
+	req := resp.Request
+	for req != nil && req.Response != nil {
+		hops++
+		req = req.Response.Request
+	}
+	return hops
+}
@@ -0,0 +1,258 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+const accessLogTimeFormat = "02/Jan/2006:15:04:05 -0700"
+
+// accessLogLine matches common and combined log formats used by nginx
+// and Apache, referer and user agent are optional.
+var accessLogLine = regexp.MustCompile(
+	`^\S+ \S+ \S+ \[([^\]]+)\] "(\S+) (\S+)[^"]*" \d{3} \S+` +
+		`(?: "((?:[^"\\]|\\.)*)" "((?:[^"\\]|\\.)*)")?`,
+)
+
+// replayEntry is a single request taken from the HAR file or the
+// access log.
+type replayEntry struct {
+	method      string
+	path, query string
+	headers     []header
+	body        []byte
+
+	// offset is the time since the first request.
+	offset time.Duration
+}
+
+// replay sends the recorded requests in order. With speed set, the
+// original intervals between the requests are kept, divided by speed,
+// and the replay stops after the last request, otherwise requests
+// are sent as fast as possible over and over again.
+type replay struct {
+	entries []replayEntry
+	speed   float64
+
+	next  uint64
+	start time.Time
+	done  <-chan struct{}
+}
+
+func loadReplay(
+	path string, base *url.URL, keepHeaders []string, speed float64,
+	done <-chan struct{},
+) (*replay, error) {
+	contents, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var entries []replayEntry
+	if strings.HasSuffix(path, ".har") ||
+		bytes.HasPrefix(bytes.TrimSpace(contents), []byte("{")) {
+		entries, err = parseHAR(contents, keepHeaders)
+	} else {
+		entries, err = parseAccessLog(contents, keepHeaders)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid replay file %q: %v", path, err)
+	}
+	if len(entries) == 0 {
+		return nil, errEmptyReplay
+	}
+	prefix, first := strings.TrimSuffix(base.Path, "/"), entries[0].offset
+	for i := range entries {
+		entries[i].path = prefix + entries[i].path
+		entries[i].offset -= first
+	}
+	return &replay{
+		entries: entries,
+		speed:   speed,
+		done:    done,
+	}, nil
+}
+
+type harFile struct {
+	Log struct {
+		Entries []struct {
+			StartedDateTime time.Time `json:"startedDateTime"`
+			Request         struct {
+				Method   string         `json:"method"`
+				URL      string         `json:"url"`
+				Headers  []harNameValue `json:"headers"`
+				PostData *struct {
+					MimeType string `json:"mimeType"`
+					Text     string `json:"text"`
+				} `json:"postData"`
+			} `json:"request"`
+		} `json:"entries"`
+	} `json:"log"`
+}
+
+type harNameValue struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+func parseHAR(contents []byte, keepHeaders []string) ([]replayEntry, error) {
+	var har harFile
+	if err := json.Unmarshal(contents, &har); err != nil {
+		return nil, err
+	}
+	var entries []replayEntry
+	for i, he := range har.Log.Entries {
+		r := he.Request
+		if !allowedHTTPMethod(r.Method) {
+			return nil, fmt.Errorf(
+				"entry %v: %v", i+1, &invalidHTTPMethodError{method: r.Method},
+			)
+		}
+		u, err := url.Parse(r.URL)
+		if err != nil {
+			return nil, fmt.Errorf("entry %v: %v", i+1, err)
+		}
+		e := replayEntry{
+			method: r.Method,
+			path:   u.Path,
+			query:  u.RawQuery,
+			offset: time.Duration(he.StartedDateTime.UnixNano()),
+		}
+		for _, h := range r.Headers {
+			// skip HTTP/2 pseudo-headers
+			if !strings.HasPrefix(h.Name, ":") && keepHeader(keepHeaders, h.Name) {
+				e.headers = append(e.headers, header{h.Name, h.Value})
+			}
+		}
+		if r.PostData != nil && r.PostData.Text != "" && canHaveBody(r.Method) {
+			e.body = []byte(r.PostData.Text)
+			if r.PostData.MimeType != "" {
+				e.headers = append(e.headers,
+					header{"Content-Type", r.PostData.MimeType})
+			}
+		}
+		entries = append(entries, e)
+	}
+	return entries, nil
+}
+
+// parseAccessLog skips the lines, which can't be parsed, as access
+// logs usually contain garbage sent by scanners and such.
+func parseAccessLog(
+	contents []byte, keepHeaders []string,
+) ([]replayEntry, error) {
+	var entries []replayEntry
+	scanner := bufio.NewScanner(bytes.NewReader(contents))
+	scanner.Buffer(nil, 1<<20)
+	for scanner.Scan() {
+		m := accessLogLine.FindStringSubmatch(scanner.Text())
+		if m == nil || !allowedHTTPMethod(m[2]) {
+			continue
+		}
+		t, err := time.Parse(accessLogTimeFormat, m[1])
+		if err != nil {
+			continue
+		}
+		u, err := url.ParseRequestURI(m[3])
+		if err != nil {
+			continue
+		}
+		e := replayEntry{
+			method: m[2],
+			path:   u.Path,
+			query:  u.RawQuery,
+			offset: time.Duration(t.UnixNano()),
+		}
+		for _, h := range []header{{"Referer", m[4]}, {"User-Agent", m[5]}} {
+			if h.value != "" && h.value != "-" && keepHeader(keepHeaders, h.key) {
+				h.value = strings.ReplaceAll(h.value, `\"`, `"`)
+				e.headers = append(e.headers, h)
+			}
+		}
+		entries = append(entries, e)
+	}
+	return entries, scanner.Err()
+}
+
+func keepHeader(keepHeaders []string, name string) bool {
+	for _, k := range keepHeaders {
+		if http.CanonicalHeaderKey(k) == http.CanonicalHeaderKey(name) {
+			return true
+		}
+	}
+	return false
+}
+
+// begin marks the start of the replay, offsets of the requests are
+// counted from it.
+func (r *replay) begin() {
+	r.start = time.Now()
+}
+
+func (r *replay) prepare(*session) (*renderedRequest, error) {
+	n := atomic.AddUint64(&r.next, 1) - 1
+	if r.speed > 0 && n >= uint64(len(r.entries)) {
+		return nil, errDataExhausted
+	}
+	e := &r.entries[n%uint64(len(r.entries))]
+	if r.speed > 0 {
+		wait := time.Until(
+			r.start.Add(time.Duration(float64(e.offset) / r.speed)),
+		)
+		if wait > 0 {
+			timer := time.NewTimer(wait)
+			select {
+			case <-timer.C:
+			case <-r.done:
+				// the test is over anyway
+				timer.Stop()
+				return nil, errDataExhausted
+			}
+		}
+	}
+	return &renderedRequest{
+		method: e.method,
+		path:   e.path,
+		query:  e.query,
+		// entries are shared, so appending must not reuse their arrays
+		headers:  e.headers[:len(e.headers):len(e.headers)],
+		body:     e.body,
+		hasPath:  true,
+		hasQuery: true,
+		hasBody:  true,
+	}, nil
+}
+
+func (r *replay) compressBodies(encoding string) error {
+	for i := range r.entries {
+		e := &r.entries[i]
+		if len(e.body) == 0 {
+			continue
+		}
+		var err error
+		if e.body, err = compressBytes(encoding, e.body); err != nil {
+			return err
+		}
+		e.headers = append(e.headers, header{"Content-Encoding", encoding})
+	}
+	return nil
+}
+
+// splitList splits comma-separated list, dropping empty elements.
+func splitList(list string) []string {
+	var res []string
+	for _, s := range strings.Split(list, ",") {
+		if s = strings.TrimSpace(s); s != "" {
+			res = append(res, s)
+		}
+	}
+	return res
+}
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+const testHAR = `{"log":{"entries":[
+{"startedDateTime":"2024-01-01T10:00:00.000Z","request":{
+	"method":"GET","url":"https://example.com/items?page=2",
+	"headers":[{"name":":authority","value":"example.com"},
+		{"name":"accept","value":"application/json"},
+		{"name":"Cookie","value":"secret"}]}},
+{"startedDateTime":"2024-01-01T10:00:01.500Z","request":{
+	"method":"POST","url":"https://example.com/cart",
+	"headers":[],
+	"postData":{"mimeType":"application/json","text":"{\"id\":1}"}}}
+]}}`
+
+const testAccessLog = `127.0.0.1 - - [01/Jan/2024:10:00:00 +0000] "GET /items?page=2 HTTP/1.1" 200 512 "-" "curl/8.0"
+garbage
+127.0.0.1 - frank [01/Jan/2024:10:00:02 +0000] "DELETE /cart/1 HTTP/1.1" 204 0
+10.0.0.1 - - [01/Jan/2024:10:00:03 +0000] "-" 400 0 "-" "-"
+10.0.0.1 - - [01/Jan/2024:10:00:04 +0000] "POST /cart HTTP/1.1" 201 17 "https://example.com/items" "Mozilla/5.0 \"quoted\""
+`
+
+func TestParseHAR(t *testing.T) {
+	entries, err := parseHAR([]byte(testHAR), []string{"Accept"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 2 {
+		t.Fatalf("expected 2 entries, but got %v", len(entries))
+	}
+	get, post := entries[0], entries[1]
+	if get.method != "GET" || get.path != "/items" || get.query != "page=2" ||
+		!reflect.DeepEqual(get.headers, []header{{"accept", "application/json"}}) {
+		t.Errorf("unexpected entry: %+v", get)
+	}
+	if post.method != "POST" || post.path != "/cart" ||
+		string(post.body) != `{"id":1}` ||
+		!reflect.DeepEqual(post.headers, []header{{"Content-Type", "application/json"}}) {
+		t.Errorf("unexpected entry: %+v", post)
+	}
+	if post.offset-get.offset != 1500*time.Millisecond {
+		t.Errorf("expected 1.5s between entries, but got %v",
+			post.offset-get.offset)
+	}
+}
+
+func TestParseAccessLog(t *testing.T) {
+	entries, err := parseAccessLog(
+		[]byte(testAccessLog), []string{"user-agent", "referer"},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 3 {
+		t.Fatalf("expected 3 entries, but got %+v", entries)
+	}
+	expectations := []struct {
+		method, path, query string
+		headers             []header
+	}{
+		{"GET", "/items", "page=2", []header{{"User-Agent", "curl/8.0"}}},
+		{"DELETE", "/cart/1", "", nil},
+		{"POST", "/cart", "", []header{
+			{"Referer", "https://example.com/items"},
+			{"User-Agent", `Mozilla/5.0 "quoted"`},
+		}},
+	}
+	for i, e := range expectations {
+		got := entries[i]
+		if got.method != e.method || got.path != e.path ||
+			got.query != e.query || !reflect.DeepEqual(got.headers, e.headers) {
+			t.Errorf("expected %+v, but got %+v", e, got)
+		}
+	}
+	if entries[2].offset-entries[0].offset != 4*time.Second {
+		t.Errorf("unexpected offset: %v", entries[2].offset)
+	}
+}
+
+func TestReplayTiming(t *testing.T) {
+	path := writeDataFile(t, "access.log", testAccessLog)
+	done := make(chan struct{})
+	r, err := loadReplay(
+		path, ParseURLOrPanic("http://localhost/api/"), nil, 8, done,
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.begin()
+	s := newSession(0)
+	for _, expected := range []time.Duration{0, 250, 500} {
+		rr, err := r.prepare(s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		elapsed := time.Since(r.start)
+		if elapsed < expected*time.Millisecond ||
+			elapsed > expected*time.Millisecond+200*time.Millisecond {
+			t.Errorf("%v sent after %v, expected %vms", rr.path, elapsed, expected)
+		}
+		if rr.path[:5] != "/api/" {
+			t.Errorf("expected path prefix to be kept, got %v", rr.path)
+		}
+	}
+	if _, err := r.prepare(s); err != errDataExhausted {
+		t.Errorf("expected %v, but got %v", errDataExhausted, err)
+	}
+}
@@ -0,0 +1,468 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/codesenberg/bombardier/internal"
+)
+
+const (
+	defaultReportInterval = time.Second
+
+	metricsPrefix = "bombardier"
+
+	// statsdMaxPacket keeps datagrams within the usual MTU.
+	statsdMaxPacket = 1432
+
+	// reportsBacklog is the number of reports waiting to be sent,
+	// after which new ones are dropped rather than slow the test.
+	reportsBacklog = 16
+)
+
+// reportSpec is where to send metrics during the test: kind is one of
+// statsd, graphite and influxdb, addr is host:port or, for influxdb,
+// URL of the write endpoint.
+type reportSpec struct {
+	kind, addr string
+}
+
+type reportList []reportSpec
+
+func (r *reportList) String() string {
+	return fmt.Sprint(*r)
+}
+
+func (r *reportList) IsCumulative() bool {
+	return true
+}
+
+func (r *reportList) Set(value string) error {
+	res := strings.SplitN(value, ":", 2)
+	if len(res) != 2 {
+		return errInvalidReportSpec
+	}
+	spec := reportSpec{kind: res[0], addr: res[1]}
+	switch spec.kind {
+	case "statsd", "graphite":
+		if _, _, err := net.SplitHostPort(spec.addr); err != nil {
+			return errInvalidReportSpec
+		}
+	case "influxdb":
+		u, err := url.Parse(spec.addr)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+			return errInvalidReportSpec
+		}
+	default:
+		return errInvalidReportSpec
+	}
+	*r = append(*r, spec)
+	return nil
+}
+
+type tag struct {
+	key, value string
+}
+
+type tagList []tag
+
+func (t *tagList) String() string {
+	return fmt.Sprint(*t)
+}
+
+func (t *tagList) IsCumulative() bool {
+	return true
+}
+
+func (t *tagList) Set(value string) error {
+	res := strings.SplitN(value, "=", 2)
+	if len(res) != 2 || res[0] == "" || res[1] == "" {
+		return errInvalidReportTag
+	}
+	*t = append(*t, tag{res[0], res[1]})
+	return nil
+}
+
+// metric is a single value reported, name is dot-separated, e.g.
+// latency.p99. Counters hold the number of events during the interval,
+// other metrics are gauges.
+type metric struct {
+	name    string
+	value   float64
+	counter bool
+}
+
+func (m metric) formatValue() string {
+	return strconv.FormatFloat(m.value, 'f', -1, 64)
+}
+
+type metricsReporter interface {
+	report(ms []metric, at time.Time) error
+	close() error
+}
+
+var statsdTagReplacer = strings.NewReplacer(
+	",", "_", "|", "_", "#", "_", ":", "_", "\n", "_",
+)
+
+// statsdReporter sends metrics over UDP, tags are sent as DogStatsD
+// does.
+type statsdReporter struct {
+	conn net.Conn
+	tags string
+}
+
+func newStatsdReporter(addr string, tags tagList) (*statsdReporter, error) {
+	conn, err := net.Dial("udp", addr)
+	if err != nil {
+		return nil, err
+	}
+	r := &statsdReporter{conn: conn}
+	if len(tags) > 0 {
+		strs := make([]string, len(tags))
+		for i, t := range tags {
+			strs[i] = statsdTagReplacer.Replace(t.key) + ":" +
+				statsdTagReplacer.Replace(t.value)
+		}
+		r.tags = "|#" + strings.Join(strs, ",")
+	}
+	return r, nil
+}
+
+func (r *statsdReporter) report(ms []metric, _ time.Time) error {
+	var packet bytes.Buffer
+	for _, m := range ms {
+		typ := "g"
+		if m.counter {
+			typ = "c"
+		}
+		line := metricsPrefix + "." + m.name + ":" + m.formatValue() +
+			"|" + typ + r.tags
+		if packet.Len() > 0 && packet.Len()+1+len(line) > statsdMaxPacket {
+			if _, err := r.conn.Write(packet.Bytes()); err != nil {
+				return err
+			}
+			packet.Reset()
+		}
+		if packet.Len() > 0 {
+			packet.WriteByte('\n')
+		}
+		packet.WriteString(line)
+	}
+	_, err := r.conn.Write(packet.Bytes())
+	return err
+}
+
+func (r *statsdReporter) close() error {
+	return r.conn.Close()
+}
+
+var graphiteTagReplacer = strings.NewReplacer(
+	" ", "_", ";", "_", "~", "_", "\n", "_",
+)
+
+// graphiteReporter sends metrics over TCP using plaintext protocol,
+// tags are sent as Graphite 1.1 tagged series. It reconnects, if
+// sending fails.
+type graphiteReporter struct {
+	addr    string
+	timeout time.Duration
+	conn    net.Conn
+	tags    string
+}
+
+func newGraphiteReporter(
+	addr string, tags tagList, timeout time.Duration,
+) *graphiteReporter {
+	r := &graphiteReporter{addr: addr, timeout: timeout}
+	for _, t := range tags {
+		r.tags += ";" + graphiteTagReplacer.Replace(t.key) + "=" +
+			graphiteTagReplacer.Replace(t.value)
+	}
+	return r
+}
+
+func (r *graphiteReporter) report(ms []metric, at time.Time) error {
+	if r.conn == nil {
+		conn, err := net.DialTimeout("tcp", r.addr, r.timeout)
+		if err != nil {
+			return err
+		}
+		r.conn = conn
+	}
+	var buf bytes.Buffer
+	ts := strconv.FormatInt(at.Unix(), decBase)
+	for _, m := range ms {
+		buf.WriteString(metricsPrefix + "." + m.name + r.tags + " " +
+			m.formatValue() + " " + ts + "\n")
+	}
+	if r.timeout > 0 {
+		if err := r.conn.SetWriteDeadline(time.Now().Add(r.timeout)); err != nil {
+			return err
+		}
+	}
+	if _, err := r.conn.Write(buf.Bytes()); err != nil {
+		r.conn.Close()
+		r.conn = nil
+		return err
+	}
+	return nil
+}
+
+func (r *graphiteReporter) close() error {
+	if r.conn == nil {
+		return nil
+	}
+	return r.conn.Close()
+}
+
+var influxKeyReplacer = strings.NewReplacer(
+	",", `\,`, "=", `\=`, " ", `\ `, "\n", `\n`,
+)
+
+// influxReporter posts metrics in InfluxDB line protocol as a single
+// point with a field for each metric. Credentials from the URL are
+// sent using basic authentication.
+type influxReporter struct {
+	url    string
+	user   *url.Userinfo
+	client *http.Client
+	tags   string
+}
+
+func newInfluxReporter(
+	addr string, tags tagList, timeout time.Duration,
+) (*influxReporter, error) {
+	u, err := url.Parse(addr)
+	if err != nil {
+		return nil, err
+	}
+	r := &influxReporter{
+		user:   u.User,
+		client: &http.Client{Timeout: timeout},
+	}
+	u.User = nil
+	r.url = u.String()
+	// line protocol wants tags sorted by key
+	sorted := append(tagList(nil), tags...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].key < sorted[j].key
+	})
+	for _, t := range sorted {
+		r.tags += "," + influxKeyReplacer.Replace(t.key) + "=" +
+			influxKeyReplacer.Replace(t.value)
+	}
+	return r, nil
+}
+
+func (r *influxReporter) report(ms []metric, at time.Time) error {
+	fields := make([]string, len(ms))
+	for i, m := range ms {
+		value := m.formatValue()
+		if m.counter {
+			value += "i"
+		}
+		name := strings.Replace(m.name, ".", "_", -1)
+		fields[i] = influxKeyReplacer.Replace(name) + "=" + value
+	}
+	line := metricsPrefix + r.tags + " " + strings.Join(fields, ",") +
+		" " + strconv.FormatInt(at.UnixNano(), decBase) + "\n"
+	req, err := http.NewRequest("POST", r.url, strings.NewReader(line))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "text/plain; charset=utf-8")
+	if r.user != nil {
+		password, _ := r.user.Password()
+		req.SetBasicAuth(r.user.Username(), password)
+	}
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("influxdb responded with %v", resp.Status)
+	}
+	return nil
+}
+
+func (r *influxReporter) close() error {
+	return nil
+}
+
+type timedMetrics struct {
+	at      time.Time
+	metrics []metric
+}
+
+// metricsReports periodically computes metrics of the last interval
+// and sends them to the reporters. tick and close must be called from
+// a single goroutine, sending happens in the background.
+type metricsReports struct {
+	reporters   []metricsReporter
+	interval    time.Duration
+	percentiles []float64
+
+	latencies *hdrHistogram
+	codes     [6]*uint64
+
+	lastTime   time.Time
+	lastCounts [6]uint64
+	last       *hdrHistogram
+
+	queue   chan timedMetrics
+	done    chan struct{}
+	dropped int
+	failed  int
+	err     error
+}
+
+func newMetricsReports(c config, latencies *hdrHistogram, codes [6]*uint64) (
+	*metricsReports, error,
+) {
+	r := &metricsReports{
+		interval:    c.reportInterval,
+		percentiles: defaultPercentiles,
+		latencies:   latencies,
+		codes:       codes,
+	}
+	if r.interval == 0 {
+		r.interval = defaultReportInterval
+	}
+	if c.percentiles != nil {
+		r.percentiles = *c.percentiles
+	}
+	var tags tagList
+	if c.reportTags != nil {
+		tags = *c.reportTags
+	}
+	for _, spec := range *c.reports {
+		var (
+			rep metricsReporter
+			err error
+		)
+		switch spec.kind {
+		case "statsd":
+			rep, err = newStatsdReporter(spec.addr, tags)
+		case "graphite":
+			rep = newGraphiteReporter(spec.addr, tags, c.timeout)
+		case "influxdb":
+			rep, err = newInfluxReporter(spec.addr, tags, c.timeout)
+		}
+		if err != nil {
+			return nil, err
+		}
+		r.reporters = append(r.reporters, rep)
+	}
+	return r, nil
+}
+
+// begin starts sending metrics of intervals counted from start.
+func (r *metricsReports) begin(start time.Time) {
+	r.lastTime = start
+	r.last = r.latencies.snapshot()
+	r.queue = make(chan timedMetrics, reportsBacklog)
+	r.done = make(chan struct{})
+	go r.sender()
+}
+
+// tick reports metrics, if the interval has passed since the last
+// report.
+func (r *metricsReports) tick(now time.Time) {
+	if now.Sub(r.lastTime) < r.interval {
+		return
+	}
+	select {
+	case r.queue <- r.collect(now):
+	default:
+		r.dropped++
+	}
+}
+
+func (r *metricsReports) collect(now time.Time) timedMetrics {
+	var counts [6]uint64
+	total := uint64(0)
+	for i, c := range r.codes {
+		counts[i] = atomic.LoadUint64(c)
+		total += counts[i] - r.lastCounts[i]
+	}
+	current := r.latencies.snapshot()
+	interval := current.snapshot()
+	interval.subtract(r.last)
+	rps := 0.0
+	if elapsed := now.Sub(r.lastTime); elapsed > 0 {
+		rps = float64(total) / elapsed.Seconds()
+	}
+
+	ms := []metric{{name: "rps", value: rps}}
+	stats := internal.Results{Latencies: interval}.
+		LatenciesStats(r.percentiles)
+	if stats != nil {
+		ms = append(ms,
+			metric{name: "latency.mean", value: stats.Mean},
+			metric{name: "latency.max", value: stats.Max},
+		)
+		for _, pc := range r.percentiles {
+			ms = append(ms, metric{
+				name: "latency.p" +
+					strings.Replace(formatPercentile(pc), ".", "_", -1),
+				value: float64(stats.Percentiles[pc]),
+			})
+		}
+	}
+	for i, name := range []string{
+		"requests.1xx", "requests.2xx", "requests.3xx",
+		"requests.4xx", "requests.5xx", "errors",
+	} {
+		ms = append(ms, metric{
+			name:    name,
+			value:   float64(counts[i] - r.lastCounts[i]),
+			counter: true,
+		})
+	}
+	r.lastTime, r.lastCounts, r.last = now, counts, current
+	return timedMetrics{at: now, metrics: ms}
+}
+
+func (r *metricsReports) sender() {
+	defer close(r.done)
+	for tm := range r.queue {
+		for _, rep := range r.reporters {
+			if err := rep.report(tm.metrics, tm.at); err != nil {
+				r.failed++
+				r.err = err
+			}
+		}
+	}
+}
+
+// close reports metrics of the last interval and waits for all the
+// reports to be sent.
+func (r *metricsReports) close(now time.Time) error {
+	r.queue <- r.collect(now)
+	close(r.queue)
+	<-r.done
+	for _, rep := range r.reporters {
+		if err := rep.close(); err != nil && r.err == nil {
+			r.err = err
+		}
+	}
+	if r.failed > 0 {
+		return fmt.Errorf("failed to send %v report(s): %v", r.failed, r.err)
+	}
+	if r.dropped > 0 {
+		return fmt.Errorf(
+			"%v report(s) dropped, because sending was too slow", r.dropped,
+		)
+	}
+	return r.err
+}
@@ -0,0 +1,220 @@
+package main
+
+import (
+	"bufio"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+var testMetrics = []metric{
+	{name: "rps", value: 1000.5},
+	{name: "latency.p99_9", value: 1500},
+	{name: "requests.2xx", value: 42, counter: true},
+}
+
+func TestReportListParsing(t *testing.T) {
+	reports := new(reportList)
+	for _, v := range []string{
+		"statsd:localhost:8125",
+		"graphite:127.0.0.1:2003",
+		"influxdb:http://localhost:8086/write?db=bench",
+	} {
+		if err := reports.Set(v); err != nil {
+			t.Fatal(err)
+		}
+	}
+	expected := reportList{
+		{"statsd", "localhost:8125"},
+		{"graphite", "127.0.0.1:2003"},
+		{"influxdb", "http://localhost:8086/write?db=bench"},
+	}
+	if !reflect.DeepEqual(*reports, expected) {
+		t.Errorf("expected %v, but got %v", expected, *reports)
+	}
+	for _, v := range []string{
+		"statsd", "statsd:localhost", "influxdb:localhost:8086",
+		"prometheus:localhost:9090",
+	} {
+		if err := reports.Set(v); err != errInvalidReportSpec {
+			t.Errorf("expected %v for %q, but got %v",
+				errInvalidReportSpec, v, err)
+		}
+	}
+}
+
+func TestTagListParsing(t *testing.T) {
+	tags := new(tagList)
+	for _, v := range []string{"test=checkout", "sha=abc=def"} {
+		if err := tags.Set(v); err != nil {
+			t.Fatal(err)
+		}
+	}
+	expected := tagList{{"test", "checkout"}, {"sha", "abc=def"}}
+	if !reflect.DeepEqual(*tags, expected) {
+		t.Errorf("expected %v, but got %v", expected, *tags)
+	}
+	for _, v := range []string{"test", "=checkout", "test="} {
+		if err := tags.Set(v); err != errInvalidReportTag {
+			t.Errorf("expected %v for %q, but got %v",
+				errInvalidReportTag, v, err)
+		}
+	}
+}
+
+func TestStatsdReporter(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	r, err := newStatsdReporter(conn.LocalAddr().String(),
+		tagList{{"test", "a,b"}, {"sha", "abc"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.close()
+	if err := r.report(testMetrics, time.Now()); err != nil {
+		t.Fatal(err)
+	}
+	buf := make([]byte, statsdMaxPacket)
+	conn.SetReadDeadline(time.Now().Add(time.Second))
+	n, _, err := conn.ReadFrom(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := "bombardier.rps:1000.5|g|#test:a_b,sha:abc\n" +
+		"bombardier.latency.p99_9:1500|g|#test:a_b,sha:abc\n" +
+		"bombardier.requests.2xx:42|c|#test:a_b,sha:abc"
+	if string(buf[:n]) != expected {
+		t.Errorf("expected %q, but got %q", expected, buf[:n])
+	}
+}
+
+func TestStatsdReporterSplitsPackets(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	r, err := newStatsdReporter(conn.LocalAddr().String(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.close()
+	var ms []metric
+	for i := 0; i < 200; i++ {
+		ms = append(ms, metric{name: "requests.2xx", value: 1, counter: true})
+	}
+	if err := r.report(ms, time.Now()); err != nil {
+		t.Fatal(err)
+	}
+	buf := make([]byte, 2*statsdMaxPacket)
+	lines := 0
+	conn.SetReadDeadline(time.Now().Add(time.Second))
+	for lines < len(ms) {
+		n, _, err := conn.ReadFrom(buf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if n > statsdMaxPacket {
+			t.Errorf("packet of %v bytes exceeds the limit", n)
+		}
+		lines += strings.Count(string(buf[:n]), "\n") + 1
+	}
+	if lines != len(ms) {
+		t.Errorf("expected %v lines, but got %v", len(ms), lines)
+	}
+}
+
+func TestGraphiteReporter(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	received := make(chan []string, 1)
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		var lines []string
+		s := bufio.NewScanner(conn)
+		for len(lines) < len(testMetrics) && s.Scan() {
+			lines = append(lines, s.Text())
+		}
+		received <- lines
+	}()
+	r := newGraphiteReporter(ln.Addr().String(),
+		tagList{{"test", "check out"}}, defaultTimeout)
+	defer r.close()
+	at := time.Unix(1700000000, 0)
+	if err := r.report(testMetrics, at); err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{
+		"bombardier.rps;test=check_out 1000.5 1700000000",
+		"bombardier.latency.p99_9;test=check_out 1500 1700000000",
+		"bombardier.requests.2xx;test=check_out 42 1700000000",
+	}
+	select {
+	case lines := <-received:
+		if !reflect.DeepEqual(lines, expected) {
+			t.Errorf("expected %q, but got %q", expected, lines)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("no metrics received")
+	}
+}
+
+func TestInfluxReporter(t *testing.T) {
+	var body, user, password string
+	s := httptest.NewServer(
+		http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+			bytes, _ := io.ReadAll(r.Body)
+			body = string(bytes)
+			user, password, _ = r.BasicAuth()
+			if r.URL.Query().Get("db") != "bench" {
+				rw.WriteHeader(http.StatusNotFound)
+				return
+			}
+			rw.WriteHeader(http.StatusNoContent)
+		}),
+	)
+	defer s.Close()
+	u := strings.Replace(s.URL, "://", "://bob:secret@", 1)
+	r, err := newInfluxReporter(u+"/write?db=bench",
+		tagList{{"test", "check out"}, {"sha", "abc"}}, defaultTimeout)
+	if err != nil {
+		t.Fatal(err)
+	}
+	at := time.Unix(1700000000, 0)
+	if err := r.report(testMetrics, at); err != nil {
+		t.Fatal(err)
+	}
+	expected := `bombardier,sha=abc,test=check\ out ` +
+		"rps=1000.5,latency_p99_9=1500,requests_2xx=42i " +
+		"1700000000000000000\n"
+	if body != expected {
+		t.Errorf("expected %q, but got %q", expected, body)
+	}
+	if user != "bob" || password != "secret" {
+		t.Errorf("expected bob:secret credentials, but got %v:%v",
+			user, password)
+	}
+
+	r, err = newInfluxReporter(s.URL+"/write?db=other", nil, defaultTimeout)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := r.report(testMetrics, at); err == nil {
+		t.Error("expected an error for unsuccessful response")
+	}
+}
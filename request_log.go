@@ -0,0 +1,104 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"math/rand"
+	"os"
+	"sync/atomic"
+	"time"
+)
+
+// requestLogBuffer is the number of records, which may wait to be
+// written, before new ones are dropped.
+const requestLogBuffer = 64 * 1024
+
+// requestRecord is a single line of the request log.
+type requestRecord struct {
+	Time      time.Time `json:"time"`
+	Worker    uint64    `json:"worker"`
+	Method    string    `json:"method"`
+	URL       string    `json:"url"`
+	Status    int       `json:"status,omitempty"`
+	LatencyUs uint64    `json:"latencyUs"`
+	Bytes     int64     `json:"bytes"`
+	Error     string    `json:"error,omitempty"`
+}
+
+// fill is called by clients to record what was actually sent and
+// received.
+func (r *requestRecord) fill(method, url string, bytes int64) {
+	r.Method, r.URL, r.Bytes = method, url, bytes
+}
+
+// requestLogger writes records of (possibly, sampled) requests as
+// JSON Lines. Records are written by a separate goroutine, so that
+// logging doesn't slow down the workers, and dropped, if the writer
+// can't keep up.
+type requestLogger struct {
+	path   string
+	sample float64
+
+	file    *os.File
+	records chan *requestRecord
+	done    chan struct{}
+	err     error
+
+	written, dropped uint64
+}
+
+func newRequestLogger(path string, sample float64) (*requestLogger, error) {
+	file, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+	l := &requestLogger{
+		path:    path,
+		sample:  sample,
+		file:    file,
+		records: make(chan *requestRecord, requestLogBuffer),
+		done:    make(chan struct{}),
+	}
+	go l.writer()
+	return l, nil
+}
+
+// sampled tells whether the next request should be logged.
+func (l *requestLogger) sampled() bool {
+	return l.sample >= 1 || rand.Float64() < l.sample
+}
+
+func (l *requestLogger) log(r *requestRecord) {
+	select {
+	case l.records <- r:
+	default:
+		atomic.AddUint64(&l.dropped, 1)
+	}
+}
+
+func (l *requestLogger) writer() {
+	defer close(l.done)
+	w := bufio.NewWriter(l.file)
+	enc := json.NewEncoder(w)
+	for r := range l.records {
+		if l.err != nil {
+			continue
+		}
+		if l.err = enc.Encode(r); l.err == nil {
+			atomic.AddUint64(&l.written, 1)
+		}
+	}
+	if err := w.Flush(); l.err == nil {
+		l.err = err
+	}
+}
+
+// close waits for all the records to be written and closes the file.
+func (l *requestLogger) close() error {
+	close(l.records)
+	<-l.done
+	if err := l.file.Close(); l.err == nil {
+		l.err = err
+	}
+	return l.err
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func readRequestLog(t *testing.T, path string) []requestRecord {
+	file, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	var records []requestRecord
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		var r requestRecord
+		if err := json.Unmarshal(scanner.Bytes(), &r); err != nil {
+			t.Fatalf("invalid record %q: %v", scanner.Text(), err)
+		}
+		records = append(records, r)
+	}
+	if err := scanner.Err(); err != nil {
+		t.Fatal(err)
+	}
+	return records
+}
+
+func TestRequestLogger(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "requests.jsonl")
+	l, err := newRequestLogger(path, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !l.sampled() {
+		t.Error("expected all requests to be sampled")
+	}
+	now := time.Now().Round(0)
+	l.log(&requestRecord{
+		Time: now, Worker: 3, Method: "GET", URL: "http://localhost/",
+		Status: 200, LatencyUs: 1500, Bytes: 42,
+	})
+	l.log(&requestRecord{
+		Time: now, Worker: 4, Method: "POST", URL: "http://localhost/",
+		LatencyUs: 2000, Error: "timeout",
+	})
+	if err := l.close(); err != nil {
+		t.Fatal(err)
+	}
+	if l.written != 2 || l.dropped != 0 {
+		t.Errorf("expected 2 written and 0 dropped, but got %v and %v",
+			l.written, l.dropped)
+	}
+	records := readRequestLog(t, path)
+	if len(records) != 2 {
+		t.Fatalf("expected 2 records, but got %v", len(records))
+	}
+	if r := records[0]; !r.Time.Equal(now) || r.Worker != 3 ||
+		r.Status != 200 || r.Bytes != 42 || r.Error != "" {
+		t.Errorf("unexpected record: %+v", r)
+	}
+	if r := records[1]; r.Method != "POST" || r.Status != 0 ||
+		r.Error != "timeout" {
+		t.Errorf("unexpected record: %+v", r)
+	}
+}
+
+func TestRequestLoggerSampling(t *testing.T) {
+	l, err := newRequestLogger(
+		filepath.Join(t.TempDir(), "requests.jsonl"), 0.1,
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.close()
+	sampled := 0
+	for i := 0; i < 10000; i++ {
+		if l.sampled() {
+			sampled++
+		}
+	}
+	if sampled < 500 || sampled > 1500 {
+		t.Errorf("expected about 1000 sampled requests, but got %v", sampled)
+	}
+}
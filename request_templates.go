@@ -0,0 +1,308 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"math/rand"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"text/template"
+	"time"
+
+	uuid "github.com/satori/go.uuid"
+)
+
+const (
+	templateActionStart = "{{"
+
+	randomStringAlphabet = "abcdefghijklmnopqrstuvwxyz" +
+		"ABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789"
+)
+
+// requestContext is what gets passed to the request templates.
+type requestContext struct {
+	// Counter is the sequence number of the request, starting from 1.
+	Counter uint64
+	// Time is the moment at which the request was prepared.
+	Time time.Time
+	// Data is the row of the data file, if any, keyed by column name.
+	Data map[string]string
+	// Vars are the variables extracted from the previous responses
+	// received by the same virtual user.
+	Vars map[string]string
+}
+
+// requestTemplate is a single part of the request (path, query,
+// header value or body) that has to be evaluated for each request.
+// Parts without any template actions are kept as static strings.
+type requestTemplate struct {
+	static string
+	tmpl   *template.Template
+}
+
+func (t *requestTemplate) execute(buf *bytes.Buffer, ctx *requestContext) error {
+	buf.Reset()
+	if t.tmpl == nil {
+		_, err := buf.WriteString(t.static)
+		return err
+	}
+	return t.tmpl.Execute(buf, ctx)
+}
+
+type headerTemplate struct {
+	key   string
+	value *requestTemplate
+}
+
+// requestTemplates holds all the templated parts of the request.
+// Unless compiled with all parts included, parts of the request that
+// contain no template actions are not stored here at all, so clients
+// can use them as is.
+type requestTemplates struct {
+	path, query, body *requestTemplate
+	headers           []headerTemplate
+
+	counter uint64
+	bufPool sync.Pool
+	// feeder, if set, provides a new data row for each request.
+	// Otherwise templates use the row the session already has.
+	feeder *dataFeeder
+	// encoding, if set, is used to compress non-empty bodies.
+	encoding string
+
+	linesMu sync.Mutex
+	lines   map[string][]string
+}
+
+// responseHandler inspects the response to the request. header
+// returns the value of the response header with the given name.
+type responseHandler func(
+	code int, header func(string) string, body []byte,
+) error
+
+// renderedRequest contains the parts of the request that differ
+// from the ones clients were configured with.
+type renderedRequest struct {
+	method      string
+	path, query string
+	headers     []header
+	body        []byte
+
+	hasPath, hasQuery, hasBody bool
+
+	// onResponse is called after the response is received, if set.
+	onResponse responseHandler
+}
+
+func isTemplated(s string) bool {
+	return strings.Contains(s, templateActionStart)
+}
+
+// newRequestTemplates compiles templated parts of the request. It
+// returns nil if nothing in the request is templated.
+func newRequestTemplates(
+	u *url.URL, headers *headersList, body *string, feeder *dataFeeder,
+) (*requestTemplates, error) {
+	rt, err := compileRequestTemplates(u, headers, body, feeder, false)
+	if err != nil {
+		return nil, err
+	}
+	if rt.path == nil && rt.query == nil &&
+		rt.body == nil && len(rt.headers) == 0 {
+		return nil, nil
+	}
+	return rt, nil
+}
+
+// compileRequestTemplates compiles parts of the request. Static parts
+// are only included if all is true.
+func compileRequestTemplates(
+	u *url.URL, headers *headersList, body *string, feeder *dataFeeder,
+	all bool,
+) (*requestTemplates, error) {
+	rt := &requestTemplates{
+		feeder: feeder,
+		bufPool: sync.Pool{
+			New: func() interface{} {
+				return new(bytes.Buffer)
+			},
+		},
+		lines: make(map[string][]string),
+	}
+	funcs := rt.funcs()
+	parse := func(name, text string) (*requestTemplate, error) {
+		if !isTemplated(text) {
+			if all {
+				return &requestTemplate{static: text}, nil
+			}
+			return nil, nil
+		}
+		tmpl, err := template.New(name).
+			Option("missingkey=error").
+			Funcs(funcs).
+			Parse(text)
+		if err != nil {
+			return nil, err
+		}
+		return &requestTemplate{tmpl: tmpl}, nil
+	}
+
+	var err error
+	if rt.path, err = parse("path", u.Path); err != nil {
+		return nil, err
+	}
+	if rt.query, err = parse("query", u.RawQuery); err != nil {
+		return nil, err
+	}
+	if body != nil {
+		if rt.body, err = parse("body", *body); err != nil {
+			return nil, err
+		}
+	}
+	if headers != nil {
+		for _, h := range *headers {
+			value, err := parse("header "+h.key, h.value)
+			if err != nil {
+				return nil, err
+			}
+			if value != nil {
+				rt.headers = append(rt.headers, headerTemplate{h.key, value})
+			}
+		}
+	}
+	return rt, nil
+}
+
+func (rt *requestTemplates) funcs() template.FuncMap {
+	return template.FuncMap{
+		"RandomInt": func(min, max int) int {
+			if max <= min {
+				return min
+			}
+			return min + rand.Intn(max-min)
+		},
+		"RandomString": func(n int) string {
+			b := make([]byte, n)
+			for i := range b {
+				b[i] = randomStringAlphabet[rand.Intn(len(randomStringAlphabet))]
+			}
+			return string(b)
+		},
+		"RandomLine": rt.randomLine,
+		"UUIDV4": func() string {
+			return uuid.NewV4().String()
+		},
+	}
+}
+
+// randomLine returns a random line from the file at path. Files are
+// read once, on the first use.
+func (rt *requestTemplates) randomLine(path string) (string, error) {
+	rt.linesMu.Lock()
+	lines, ok := rt.lines[path]
+	if !ok {
+		var err error
+		lines, err = readLines(path)
+		if err != nil {
+			rt.linesMu.Unlock()
+			return "", err
+		}
+		rt.lines[path] = lines
+	}
+	rt.linesMu.Unlock()
+	if len(lines) == 0 {
+		return "", nil
+	}
+	return lines[rand.Intn(len(lines))], nil
+}
+
+func readLines(path string) ([]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	var lines []string
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		if line := sc.Text(); line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return lines, sc.Err()
+}
+
+func (rt *requestTemplates) prepare(s *session) (*renderedRequest, error) {
+	if rt.feeder != nil {
+		var err error
+		if s.data, err = rt.feeder.next(s); err != nil {
+			return nil, err
+		}
+	}
+	ctx := &requestContext{
+		Counter: atomic.AddUint64(&rt.counter, 1),
+		Time:    time.Now(),
+		Data:    s.data,
+		Vars:    s.vars,
+	}
+	buf := rt.bufPool.Get().(*bytes.Buffer)
+	defer rt.bufPool.Put(buf)
+
+	r := new(renderedRequest)
+	if rt.path != nil {
+		if err := rt.path.execute(buf, ctx); err != nil {
+			return nil, err
+		}
+		r.path, r.hasPath = buf.String(), true
+	}
+	if rt.query != nil {
+		if err := rt.query.execute(buf, ctx); err != nil {
+			return nil, err
+		}
+		r.query, r.hasQuery = buf.String(), true
+	}
+	if len(rt.headers) > 0 {
+		r.headers = make([]header, 0, len(rt.headers))
+		for _, h := range rt.headers {
+			if err := h.value.execute(buf, ctx); err != nil {
+				return nil, err
+			}
+			r.headers = append(r.headers, header{h.key, buf.String()})
+		}
+	}
+	if rt.body != nil {
+		if err := rt.body.execute(buf, ctx); err != nil {
+			return nil, err
+		}
+		r.body, r.hasBody = append([]byte(nil), buf.Bytes()...), true
+		if rt.encoding != "" && len(r.body) > 0 {
+			// static body is already compressed
+			if rt.body.tmpl != nil {
+				var err error
+				if r.body, err = compressBytes(rt.encoding, r.body); err != nil {
+					return nil, err
+				}
+			}
+			r.headers = append(r.headers, header{"Content-Encoding", rt.encoding})
+		}
+	}
+	return r, nil
+}
+
+// compressBodies makes rt compress non-empty bodies with encoding.
+// Static body is compressed right away.
+func (rt *requestTemplates) compressBodies(encoding string) error {
+	rt.encoding = encoding
+	if rt.body == nil || rt.body.tmpl != nil || rt.body.static == "" {
+		return nil
+	}
+	compressed, err := compressBytes(encoding, []byte(rt.body.static))
+	if err != nil {
+		return err
+	}
+	rt.body.static = string(compressed)
+	return nil
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"strconv"
+	"testing"
+)
+
+func TestRequestTemplatesShouldBeNilIfNothingIsTemplated(t *testing.T) {
+	headers := headersList([]header{{"Header1", "Value1"}})
+	body := "abracadabra"
+	rt, err := newRequestTemplates(
+		ParseURLOrPanic("http://localhost:8080/items?id=1"), &headers, &body, nil,
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rt != nil {
+		t.Errorf("expected no templates, but got %+v", rt)
+	}
+}
+
+func TestRequestTemplatesRendering(t *testing.T) {
+	headers := headersList([]header{
+		{"Static", "static"},
+		{"X-Request-Number", "{{ .Counter }}"},
+	})
+	body := `{"id":{{ RandomInt 5 6 }},"name":"{{ RandomString 8 }}"}`
+	rt, err := newRequestTemplates(
+		ParseURLOrPanic(
+			"http://localhost:8080/items/{{ .Counter }}?line={{ RandomLine \"testbody.txt\" }}",
+		),
+		&headers, &body, nil,
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rt.headers) != 1 {
+		t.Errorf("only one header should be templated, got %+v", rt.headers)
+	}
+	for i := 1; i <= 3; i++ {
+		rr, err := rt.prepare(newSession(0))
+		if err != nil {
+			t.Fatal(err)
+		}
+		counter := strconv.Itoa(i)
+		if e, a := "/items/"+counter, rr.path; e != a {
+			t.Errorf("expected path %q, but got %q", e, a)
+		}
+		if e, a := "line=abracadabra", rr.query; e != a {
+			t.Errorf("expected query %q, but got %q", e, a)
+		}
+		if e, a := []header{{"X-Request-Number", counter}}, rr.headers; len(a) != 1 || a[0] != e[0] {
+			t.Errorf("expected headers %v, but got %v", e, a)
+		}
+		if l := len(rr.body); l != len(`{"id":5,"name":"12345678"}`) {
+			t.Errorf("unexpected body %q", rr.body)
+		}
+	}
+}
+
+func TestRequestTemplatesInvalidTemplate(t *testing.T) {
+	body := "{{ NoSuchFunction }}"
+	_, err := newRequestTemplates(
+		ParseURLOrPanic("http://localhost:8080"), new(headersList), &body, nil,
+	)
+	if err == nil {
+		t.Error("invalid template compiled successfully")
+	}
+}
+
+func TestRequestTemplatesMissingFile(t *testing.T) {
+	body := `{{ RandomLine "/does/not/exist.forreal" }}`
+	rt, err := newRequestTemplates(
+		ParseURLOrPanic("http://localhost:8080"), new(headersList), &body, nil,
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := rt.prepare(newSession(0)); err == nil {
+		t.Error("expected an error when reading lines from missing file")
+	}
+}
+
+func BenchmarkRequestTemplatesRender(b *testing.B) {
+	body := `{"id":{{ RandomInt 1 1000 }},"uuid":"{{ UUIDV4 }}"}`
+	rt, err := newRequestTemplates(
+		ParseURLOrPanic("http://localhost:8080/items/{{ .Counter }}"),
+		new(headersList), &body, nil,
+	)
+	if err != nil {
+		b.Fatal(err)
+	}
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			if _, err := rt.prepare(newSession(0)); err != nil {
+				b.Error(err)
+			}
+		}
+	})
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+)
+
+const defaultSamplesDir = "samples"
+
+// responseSampler saves the first few responses with each status code
+// into the directory, one file per response.
+type responseSampler struct {
+	dir   string
+	limit uint64
+
+	mu    sync.Mutex
+	taken map[int]uint64
+
+	// saved and failed count the samples written and the ones that
+	// couldn't be written, failures aren't counted as request errors.
+	saved, failed uint64
+}
+
+func newResponseSampler(dir string, limit uint64) (*responseSampler, error) {
+	if dir == "" {
+		dir = defaultSamplesDir
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	return &responseSampler{
+		dir:   dir,
+		limit: limit,
+		taken: make(map[int]uint64),
+	}, nil
+}
+
+// take reserves a sample slot for the response with code, ok is false
+// if enough responses with that code were sampled already.
+func (r *responseSampler) take(code int) (n uint64, ok bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.taken[code] >= r.limit {
+		return 0, false
+	}
+	r.taken[code]++
+	return r.taken[code], true
+}
+
+// save writes the response head (status line and headers) followed by
+// its body into the file named after code and n, e.g. 500-1.http.
+func (r *responseSampler) save(code int, n uint64, head, body []byte) {
+	if err := r.write(code, n, head, body); err != nil {
+		atomic.AddUint64(&r.failed, 1)
+		return
+	}
+	atomic.AddUint64(&r.saved, 1)
+}
+
+func (r *responseSampler) write(code int, n uint64, head, body []byte) error {
+	name := filepath.Join(r.dir, fmt.Sprintf("%d-%d.http", code, n))
+	file, err := os.Create(name)
+	if err != nil {
+		return err
+	}
+	_, err = file.Write(head)
+	if err == nil {
+		_, err = file.Write(body)
+	}
+	if cerr := file.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+func (r *responseSampler) savedCount() uint64 {
+	return atomic.LoadUint64(&r.saved)
+}
+
+func (r *responseSampler) failedCount() uint64 {
+	return atomic.LoadUint64(&r.failed)
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestResponseSamplerLimit(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "samples")
+	s, err := newResponseSampler(dir, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := uint64(1); i <= 2; i++ {
+		if n, ok := s.take(500); !ok || n != i {
+			t.Errorf("expected sample #%v, but got %v, %v", i, n, ok)
+		}
+	}
+	if _, ok := s.take(500); ok {
+		t.Error("expected no more samples of 500s")
+	}
+	if n, ok := s.take(200); !ok || n != 1 {
+		t.Errorf("expected first sample of 200s, but got %v, %v", n, ok)
+	}
+
+	s.save(500, 2, []byte("HTTP/1.1 500\r\n\r\n"), []byte("boom"))
+	contents, err := ioutil.ReadFile(filepath.Join(dir, "500-2.http"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(contents) != "HTTP/1.1 500\r\n\r\nboom" {
+		t.Errorf("unexpected sample contents: %q", contents)
+	}
+	if s.savedCount() != 1 || s.failedCount() != 0 {
+		t.Errorf("expected 1 saved sample, but got %v (failed: %v)",
+			s.savedCount(), s.failedCount())
+	}
+
+	if err := os.RemoveAll(dir); err != nil {
+		t.Fatal(err)
+	}
+	s.save(200, 1, []byte("HTTP/1.1 200\r\n\r\n"), nil)
+	if s.savedCount() != 1 || s.failedCount() != 1 {
+		t.Errorf("expected 1 failed sample, but got %v (saved: %v)",
+			s.failedCount(), s.savedCount())
+	}
+}
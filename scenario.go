@@ -0,0 +1,306 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync/atomic"
+)
+
+// scenarioSpec is the format of the scenario file.
+type scenarioSpec struct {
+	Steps []stepSpec `json:"steps"`
+}
+
+type stepSpec struct {
+	Name    string            `json:"name"`
+	Method  string            `json:"method"`
+	Path    string            `json:"path"`
+	Headers map[string]string `json:"headers"`
+	Body    string            `json:"body"`
+	Extract []extractSpec     `json:"extract"`
+}
+
+// extractSpec describes where to take the value of the variable from.
+// Exactly one of Header, JSON and Regex must be set.
+type extractSpec struct {
+	Var    string `json:"var"`
+	Header string `json:"header"`
+	JSON   string `json:"json"`
+	Regex  string `json:"regex"`
+}
+
+type extractor struct {
+	name    string
+	extract func(header func(string) string, body []byte) (string, bool)
+}
+
+type scenarioStep struct {
+	name       string
+	method     string
+	templates  *requestTemplates
+	extractors []extractor
+
+	requests, errors uint64
+	latencies        *hdrHistogram
+}
+
+// scenario is a list of steps each virtual user performs in order.
+// Values extracted from responses are available to the later steps
+// as .Vars in request templates, while all steps of one iteration
+// share the same row of the data file. Failed step starts the
+// scenario over.
+type scenario struct {
+	steps  []*scenarioStep
+	feeder *dataFeeder
+}
+
+func loadScenario(
+	path string, base *url.URL, feeder *dataFeeder, digits uint64,
+) (*scenario, error) {
+	bytes, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var spec scenarioSpec
+	if err := json.Unmarshal(bytes, &spec); err != nil {
+		return nil, fmt.Errorf("invalid scenario %q: %v", path, err)
+	}
+	return newScenario(spec, base, feeder, digits)
+}
+
+// newScenario compiles the steps, latencies of which are recorded
+// with the given number of significant digits.
+func newScenario(
+	spec scenarioSpec, base *url.URL, feeder *dataFeeder, digits uint64,
+) (*scenario, error) {
+	if len(spec.Steps) == 0 {
+		return nil, errEmptyScenario
+	}
+	sc := &scenario{feeder: feeder}
+	for i, ss := range spec.Steps {
+		if ss.Name == "" {
+			ss.Name = "step " + strconv.Itoa(i+1)
+		}
+		step, err := newScenarioStep(ss, base, nil, digits)
+		if err != nil {
+			return nil, fmt.Errorf("%v: %v", ss.Name, err)
+		}
+		sc.steps = append(sc.steps, step)
+	}
+	return sc, nil
+}
+
+func newScenarioStep(
+	ss stepSpec, base *url.URL, feeder *dataFeeder, digits uint64,
+) (*scenarioStep, error) {
+	step := &scenarioStep{
+		name:      ss.Name,
+		method:    ss.Method,
+		latencies: newHDRHistogram(digits),
+	}
+	if step.method == "" {
+		step.method = "GET"
+	}
+	if !allowedHTTPMethod(step.method) {
+		return nil, &invalidHTTPMethodError{method: step.method}
+	}
+	if !canHaveBody(step.method) && ss.Body != "" {
+		return nil, errBodyNotAllowed
+	}
+
+	u := *base
+	if ss.Path != "" {
+		pu, err := url.Parse(ss.Path)
+		if err != nil {
+			return nil, err
+		}
+		if pu.Scheme != "" || pu.Host != "" {
+			return nil, errAbsoluteStepURL
+		}
+		u.Path, u.RawPath, u.RawQuery = pu.Path, "", pu.RawQuery
+	}
+
+	keys := make([]string, 0, len(ss.Headers))
+	for k := range ss.Headers {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	headers := make(headersList, 0, len(keys))
+	for _, k := range keys {
+		headers = append(headers, header{k, ss.Headers[k]})
+	}
+
+	var err error
+	step.templates, err = compileRequestTemplates(
+		&u, &headers, &ss.Body, feeder, true,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, es := range ss.Extract {
+		e, err := newExtractor(es)
+		if err != nil {
+			return nil, err
+		}
+		step.extractors = append(step.extractors, e)
+	}
+	return step, nil
+}
+
+func newExtractor(es extractSpec) (extractor, error) {
+	e := extractor{name: es.Var}
+	if es.Var == "" {
+		return e, errNoExtractVar
+	}
+	sources := 0
+	if es.Header != "" {
+		sources++
+		name := es.Header
+		e.extract = func(header func(string) string, _ []byte) (string, bool) {
+			v := header(name)
+			return v, v != ""
+		}
+	}
+	if es.JSON != "" {
+		sources++
+		path := strings.Split(es.JSON, ".")
+		e.extract = func(_ func(string) string, body []byte) (string, bool) {
+			return extractJSON(body, path)
+		}
+	}
+	if es.Regex != "" {
+		sources++
+		re, err := regexp.Compile(es.Regex)
+		if err != nil {
+			return e, err
+		}
+		e.extract = func(_ func(string) string, body []byte) (string, bool) {
+			m := re.FindSubmatch(body)
+			if m == nil {
+				return "", false
+			}
+			if len(m) > 1 {
+				return string(m[1]), true
+			}
+			return string(m[0]), true
+		}
+	}
+	if sources != 1 {
+		return e, fmt.Errorf(
+			"%q must be extracted from exactly one of header, json or regex",
+			es.Var,
+		)
+	}
+	return e, nil
+}
+
+// extractJSON walks the dot-separated path, where numbers are
+// treated as array indices. Non-string values are returned as JSON.
+func extractJSON(body []byte, path []string) (string, bool) {
+	var v interface{}
+	if err := json.Unmarshal(body, &v); err != nil {
+		return "", false
+	}
+	for _, p := range path {
+		switch tv := v.(type) {
+		case map[string]interface{}:
+			var ok bool
+			if v, ok = tv[p]; !ok {
+				return "", false
+			}
+		case []interface{}:
+			i, err := strconv.Atoi(p)
+			if err != nil || i < 0 || i >= len(tv) {
+				return "", false
+			}
+			v = tv[i]
+		default:
+			return "", false
+		}
+	}
+	if s, ok := v.(string); ok {
+		return s, true
+	}
+	bytes, err := json.Marshal(v)
+	if err != nil {
+		return "", false
+	}
+	return string(bytes), true
+}
+
+func (sc *scenario) prepare(s *session) (*renderedRequest, error) {
+	if s.step == 0 {
+		clear(s.vars)
+		if sc.feeder != nil {
+			var err error
+			if s.data, err = sc.feeder.next(s); err != nil {
+				return nil, err
+			}
+		}
+	}
+	step := sc.steps[s.step]
+	rr, err := step.templates.prepare(s)
+	if err != nil {
+		return nil, err
+	}
+	rr.method = step.method
+	rr.onResponse = step.extractInto(s)
+	return rr, nil
+}
+
+func (sc *scenario) compressBodies(encoding string) error {
+	for _, step := range sc.steps {
+		if err := step.templates.compressBodies(encoding); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// extractInto returns the handler, which extracts variables of the
+// step from the response into s, or nil, if there is nothing to
+// extract.
+func (step *scenarioStep) extractInto(s *session) responseHandler {
+	if len(step.extractors) == 0 {
+		return nil
+	}
+	return func(_ int, header func(string) string, body []byte) error {
+		for _, e := range step.extractors {
+			v, ok := e.extract(header, body)
+			if !ok {
+				return &extractionError{step.name, e.name}
+			}
+			s.vars[e.name] = v
+		}
+		return nil
+	}
+}
+
+// record accounts the result of the current step of s and moves s
+// to the next step.
+func (sc *scenario) record(s *session, code int, usTaken uint64, err error) {
+	step := sc.steps[s.step]
+	atomic.AddUint64(&step.requests, 1)
+	step.latencies.Increment(usTaken)
+	if err != nil || code < 0 || code >= 400 {
+		atomic.AddUint64(&step.errors, 1)
+		s.step = 0
+		return
+	}
+	s.step = (s.step + 1) % len(sc.steps)
+}
+
+type extractionError struct {
+	step, variable string
+}
+
+func (e *extractionError) Error() string {
+	return fmt.Sprintf("step %q: failed to extract %q", e.step, e.variable)
+}
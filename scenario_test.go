@@ -0,0 +1,124 @@
+package main
+
+import (
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestExtractJSON(t *testing.T) {
+	body := []byte(`{"auth":{"token":"abc","ttl":60},"items":[{"id":1},{"id":"two"}]}`)
+	expectations := []struct {
+		path  string
+		value string
+		ok    bool
+	}{
+		{"auth.token", "abc", true},
+		{"auth.ttl", "60", true},
+		{"auth", `{"token":"abc","ttl":60}`, true},
+		{"items.0.id", "1", true},
+		{"items.1.id", "two", true},
+		{"items.2.id", "", false},
+		{"items.x", "", false},
+		{"auth.token.value", "", false},
+		{"missing", "", false},
+	}
+	for _, e := range expectations {
+		v, ok := extractJSON(body, strings.Split(e.path, "."))
+		if v != e.value || ok != e.ok {
+			t.Errorf("for %q expected %q, %v, but got %q, %v",
+				e.path, e.value, e.ok, v, ok)
+		}
+	}
+	if _, ok := extractJSON([]byte("not a json"), []string{"a"}); ok {
+		t.Error("extracted value from invalid JSON")
+	}
+}
+
+func TestExtractors(t *testing.T) {
+	header := http.Header{"X-Session": []string{"s1"}}
+	body := []byte(`<a href="/items?id=42">`)
+	expectations := []struct {
+		spec  extractSpec
+		value string
+		ok    bool
+	}{
+		{extractSpec{Var: "v", Header: "X-Session"}, "s1", true},
+		{extractSpec{Var: "v", Header: "X-Missing"}, "", false},
+		{extractSpec{Var: "v", Regex: `id=(\d+)`}, "42", true},
+		{extractSpec{Var: "v", Regex: `id=\d+`}, "id=42", true},
+		{extractSpec{Var: "v", Regex: `name=(\w+)`}, "", false},
+	}
+	for _, e := range expectations {
+		ex, err := newExtractor(e.spec)
+		if err != nil {
+			t.Error(err)
+			continue
+		}
+		v, ok := ex.extract(header.Get, body)
+		if v != e.value || ok != e.ok {
+			t.Errorf("for %+v expected %q, %v, but got %q, %v",
+				e.spec, e.value, e.ok, v, ok)
+		}
+	}
+}
+
+func TestInvalidScenarios(t *testing.T) {
+	base := ParseURLOrPanic("http://localhost:8080")
+	invalid := []scenarioSpec{
+		{},
+		{Steps: []stepSpec{{Method: "NOSUCHMETHOD"}}},
+		{Steps: []stepSpec{{Method: "HEAD", Body: "body"}}},
+		{Steps: []stepSpec{{Path: "http://example.com/"}}},
+		{Steps: []stepSpec{{Body: "{{ NoSuchFunction }}"}}},
+		{Steps: []stepSpec{{Extract: []extractSpec{{Header: "X"}}}}},
+		{Steps: []stepSpec{{Extract: []extractSpec{{Var: "v"}}}}},
+		{Steps: []stepSpec{{Extract: []extractSpec{
+			{Var: "v", Header: "X", JSON: "x"},
+		}}}},
+		{Steps: []stepSpec{{Extract: []extractSpec{{Var: "v", Regex: "("}}}}},
+	}
+	for _, spec := range invalid {
+		if _, err := newScenario(spec, base, nil, 0); err == nil {
+			t.Errorf("invalid scenario %+v accepted", spec)
+		}
+	}
+	if _, err := loadScenario("/does/not/exist.json", base, nil, 0); err == nil {
+		t.Error("expected an error for a missing scenario file")
+	}
+}
+
+func TestScenarioStepsShareDataRow(t *testing.T) {
+	path := writeDataFile(t, "users.csv", "user\nalice\nbob\n")
+	feeder, err := newDataFeeder(path, feedSequential, true, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer feeder.close()
+	spec := scenarioSpec{Steps: []stepSpec{
+		{Name: "login", Path: "/login/{{ .Data.user }}"},
+		{Name: "items", Path: "/items/{{ .Data.user }}"},
+	}}
+	sc, err := newScenario(spec, ParseURLOrPanic("http://localhost:8080"), feeder, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := newSession(0)
+	var paths []string
+	for i := 0; i < 2*len(sc.steps); i++ {
+		rr, err := sc.prepare(s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		paths = append(paths, rr.path)
+		sc.record(s, http.StatusOK, 1, nil)
+	}
+	exp := []string{
+		"/login/alice", "/items/alice",
+		"/login/bob", "/items/bob",
+	}
+	if !reflect.DeepEqual(paths, exp) {
+		t.Errorf("expected %v, but got %v", exp, paths)
+	}
+}
@@ -0,0 +1,38 @@
+package main
+
+import "net/http"
+
+// session holds the state of a single virtual user (i.e. a worker),
+// which persists between the requests made by it.
+type session struct {
+	id uint64
+
+	// dataRow is the number of data rows already consumed by this
+	// session, used by the unique data feeding mode.
+	dataRow uint64
+
+	// step is the index of the next scenario step (or of the last
+	// picked request of the mix), while vars are the values extracted
+	// from responses and data is the row of the data file used
+	// during the current iteration of the scenario.
+	step int
+	vars map[string]string
+	data map[string]string
+
+	// jar is nil, unless cookies are enabled.
+	jar http.CookieJar
+
+	// record is non-nil, if the current request has to be logged.
+	record *requestRecord
+
+	// trace is non-nil, if trace context of the current request has
+	// to be propagated.
+	trace *requestTrace
+}
+
+func newSession(id uint64) *session {
+	return &session{
+		id:   id,
+		vars: make(map[string]string),
+	}
+}
@@ -10,6 +10,9 @@ besides those described in aforementioned documentation, namely:
     Returns the URL string used for the load test.
   - WithLatencies()
     Tells whether --latencies flag were activated.
+  - WithHeader()
+    Tells whether header should be printed, i.e. --no-header
+    flag weren't activated.
   - FormatBinary(numberOfBytes float64) string
     Converts bytes to kilo-, mega-, giga-, etc.- bytes, and
     appends appropriate suffix "KB", "MB", "GB", etc.
@@ -22,8 +25,22 @@ besides those described in aforementioned documentation, namely:
   - FloatsToArray(ps ...float64) []float64
     Converts a bunch of floats into array, since, again,
     type conversions are not available in templates.
+  - Percentiles() []float64
+    Returns percentiles set with --percentiles (or the default
+    ones) as fractions, e.g. 0.999 for 99.9th percentile.
+  - FormatPercentile(pc float64) string
+    Converts percentile fraction into percents without trailing
+    zeros, e.g. "99.9" for 0.999.
+  - CSVField(s string) string
+    Quotes s, if it contains commas, quotes or line breaks, so
+    it can be used as a field of CSV record.
+  - MarkdownCell(s string) string
+    Escapes s, so it's shown as is in a cell of Markdown table.
   - Multiply(num, coeff float64) float64
     Arithmetics are not available inside of templates either.
+  - JSON(v interface{}) (string, error)
+    Encodes v as JSON, "<", ">" and "&" are escaped, so the
+    result can be safely embedded into HTML.
   - StringToBytes(s string) []byte
     Convenience function to convert string to []byte.
   - UUIDV1() (UUID, error)
@@ -6,6 +6,10 @@ var (
 	templates = map[string][]byte{
 		"plain-text": []byte(plainTextTemplate),
 		"json":       []byte(jsonTemplate),
+		"html":       []byte(htmlTemplate),
+		"csv":        []byte(csvTemplate),
+		"markdown":   []byte(markdownTemplate),
+		"junit":      []byte(junitTemplate),
 	}
 )
 
@@ -29,6 +33,14 @@ func formatFromString(formatSpec string) format {
 		return knownFormat("plain-text")
 	case "j", "json":
 		return knownFormat("json")
+	case "h", "html":
+		return knownFormat("html")
+	case "c", "csv":
+		return knownFormat("csv")
+	case "md", "markdown":
+		return knownFormat("markdown")
+	case "ju", "junit":
+		return knownFormat("junit")
 	}
 	// nil represents unknown format
 	return nil
@@ -37,22 +49,103 @@ func formatFromString(formatSpec string) format {
 const (
 	plainTextTemplate = `
 {{- printf "%10v %10v %10v %10v" "Statistics" "Avg" "Stdev" "Max" }}
-{{ with .Result.RequestsStats (FloatsToArray 0.5 0.75 0.9 0.95 0.99) }}
+{{ with .Result.RequestsStats Percentiles }}
 	{{- printf "  %-10v %10.2f %10.2f %10.2f" "Reqs/sec" .Mean .Stddev .Max -}}
 {{ else }}
 	{{- print "  There wasn't enough data to compute statistics for requests." }}
 {{ end }}
-{{ with .Result.LatenciesStats (FloatsToArray 0.5 0.75 0.9 0.95 0.99) }}
+{{ with .Result.LatenciesStats Percentiles }}
 	{{- printf "  %-10v %10v %10v %10v" "Latency" (FormatTimeUs .Mean) (FormatTimeUs .Stddev) (FormatTimeUs .Max) }}
 	{{- if WithLatencies }}
   		{{- "\n  Latency Distribution" }}
 		{{- range $pc, $lat := .Percentiles }}
-			{{- printf "\n     %2.0f%% %10s" (Multiply $pc 100) (FormatTimeUsUint64 $lat) -}}
+			{{- printf "\n  %5v%% %10s" (FormatPercentile $pc) (FormatTimeUsUint64 $lat) -}}
 		{{ end -}}
 	{{ end }}
 {{ else }}
 	{{- print "  There wasn't enough data to compute statistics for latencies." }}
 {{ end -}}
+{{ with .Result.ResponseSizesStats Percentiles -}}
+	{{- printf "  %-10v %10v %10v %10v" "Resp. size" (FormatBinary .Mean) (FormatBinary .Stddev) (FormatBinary .Max) }}
+	{{- if WithLatencies }}
+		{{- "\n  Response Size Distribution" }}
+		{{- range $pc, $size := .Percentiles }}
+			{{- printf "\n  %5v%% %10s" (FormatPercentile $pc) (FormatBinaryUint64 $size) -}}
+		{{ end -}}
+	{{ end }}
+{{ end -}}
+{{ with .Result.TLSHandshakes -}}
+{{ with .LatenciesStats Percentiles -}}
+	{{- printf "  %-10v %10v %10v %10v" "Handshake" (FormatTimeUs .Mean) (FormatTimeUs .Stddev) (FormatTimeUs .Max) }}
+{{ end -}}
+{{ "  TLS handshakes:" }}
+{{ printf "    full - %v, resumed - %v, %.2f/s" .Full .Resumed $.Result.HandshakesPerSecond }}
+{{ end -}}
+{{ with .Result.Connections -}}
+{{ "  Connections:" }}
+{{ printf "    opened - %v, reused - %v, peak - %v, dial failures - %v" .Opened .Reused .Peak .DialFailures }}
+{{ printf "    closed by server - %v, by client - %v, open - %v" .ClosedByServer .ClosedByClient .Open }}
+{{ with .RequestsStats Percentiles -}}
+{{ printf "    requests per connection - %.2f (avg), %.0f (max)" .Mean .Max }}
+{{ end -}}
+{{ with .LifetimesStats Percentiles -}}
+{{ printf "    lifetime - %v (avg), %v (max)" (FormatTimeUs .Mean) (FormatTimeUs .Max) }}
+{{ end -}}
+{{ end -}}
+{{ with .Result.Steps -}}
+{{ "  Steps:" }}
+{{ range . -}}
+{{ printf "    %v %v - %v, errors - %v" .Method .Name .Requests .Errors }}
+	{{- with .LatenciesStats Percentiles -}}
+		{{ printf ", latency - %v (avg), %v (max)" (FormatTimeUs .Mean) (FormatTimeUs .Max) }}
+	{{- end }}
+{{ end -}}
+{{ end -}}
+{{ with .Result.Mix -}}
+{{ "  Mix:" }}
+{{ range . -}}
+{{ printf "    %v %v - %v (%.2f%%, expected %.2f%%), errors - %v" .Method .Name .Requests (Multiply .Actual 100) (Multiply .Expected 100) .Errors }}
+	{{- with .LatenciesStats Percentiles -}}
+		{{ printf ", latency - %v (avg), %v (max)" (FormatTimeUs .Mean) (FormatTimeUs .Max) }}
+	{{- end }}
+{{ end -}}
+{{ end -}}
+{{ with .Result.Redirects -}}
+{{ "  Redirects:" }}
+{{ printf "    followed - %v, redirected requests - %v" .Total .Redirected }}
+{{ range .Distribution -}}
+{{ printf "    %v hop(s) - %v" .Hops .Count }}
+{{ end -}}
+{{ end -}}
+{{ with .Result.ResponseBodies -}}
+{{ "  Response bodies:" }}
+{{ printf "    compressed - %v, decompressed - %v, ratio - %.2f" (FormatBinaryUint64 .Compressed) (FormatBinaryUint64 .Decompressed) .CompressionRatio }}
+{{ if .DecodeFailures -}}
+{{ printf "    failed to decompress - %v" .DecodeFailures }}
+{{ end -}}
+{{ end -}}
+{{ with .Result.ResponseSamples -}}
+{{ printf "  Response samples: %v saved to %v, failed - %v" .Saved .Dir .Failed }}
+{{ end -}}
+{{ with .Result.RequestLog -}}
+{{ printf "  Request log: %v written to %v, dropped - %v" .Written .Path .Dropped }}
+{{ end -}}
+{{ with .Result.Tracing -}}
+{{ "  Slowest requests:" }}
+{{ range .Slowest -}}
+{{ printf "    %10v %v %v %v - " (FormatTimeUsUint64 .Latency) .TraceID .Method .URL }}
+	{{- if .Error }}{{ .Error }}{{ else }}{{ .Code }}{{ end }}
+{{ end -}}
+{{ if or .Exported .Dropped -}}
+{{ printf "  Spans: %v exported, dropped - %v" .Exported .Dropped }}
+{{ end -}}
+{{ end -}}
+{{ with .Result.ThinkTime -}}
+{{ printf "  Think time: %v (avg), effective concurrency - %.2f of %v" (FormatTimeUs (Multiply .Mean.Seconds 1e6)) $.Result.EffectiveConcurrency $.Spec.NumberOfConnections }}
+{{ end -}}
+{{ with .Result.TokenFailures -}}
+{{ printf "  Token refresh failures: %v" . }}
+{{ end -}}
 {{ with .Result -}}
 {{ "  HTTP codes:" }}
 {{ printf "    1xx - %v, 2xx - %v, 3xx - %v, 4xx - %v, 5xx - %v" .Req1XX .Req2XX .Req3XX .Req4XX .Req5XX }}
@@ -63,6 +156,15 @@ const (
 			{{- printf "\n    %10v - %v" .Error .Count }}
 		{{- end -}}
 	{{ end -}}
+	{{- with .ErrorCategories }}
+		{{- "\n  Error categories:"}}
+		{{- range . }}
+			{{- printf "\n    %v - %v" .Name .Count }}
+			{{- range .Examples }}
+				{{- printf "\n      %v" . }}
+			{{- end }}
+		{{- end -}}
+	{{ end -}}
 {{ end }}
 {{ printf "  %-10v %10v/s\n" "Throughput:" (FormatBinary .Result.Throughput)}}`
 	jsonTemplate = `{"spec":{
@@ -114,6 +216,40 @@ const (
 {{- with .Rate -}}
 ,"rate":{{ . }}
 {{- end -}}
+{{- with .ThinkTime -}}
+,"thinkTime":{{ . | printf "%q" }}
+{{- end -}}
+
+{{- if .TLSHandshake -}}
+,"tlsHandshake":true,"tlsResumption":{{ .TLSSessionResumption }}
+{{- end -}}
+
+{{- with .ScenarioPath -}}
+,"scenario":{{ . | printf "%q" }}
+{{- end -}}
+{{- with .MixPath -}}
+,"mix":{{ . | printf "%q" }}
+{{- end -}}
+{{- with .ReplayPath -}}
+,"replay":{{ . | printf "%q" }}
+{{- end -}}
+{{- with .ReplaySpeed -}}
+,"replaySpeed":{{ . }}
+{{- end -}}
+
+{{- with .MaxRedirects -}}
+,"maxRedirects":{{ . }}
+{{- end -}}
+
+{{- with .CompressBody -}}
+,"compressBody":{{ . | printf "%q" }}
+{{- end -}}
+{{- with .AcceptEncoding -}}
+,"acceptEncoding":{{ . | printf "%q" }}
+{{- end -}}
+{{- with .SampleResponses -}}
+,"sampleResponses":{{ . }}
+{{- end -}}
 {{- end -}}
 },
 
@@ -133,39 +269,591 @@ const (
 ,"errors":[
 {{- range $index, $error :=  . -}}
 {{- if ne $index 0 -}},{{- end -}}
-{"description":{{ .Error | printf "%q" }},"count":{{ .Count }}}
+{"description":{{ .Error | printf "%q" }},"count":{{ .Count -}}
+,"category":{{ .Category | printf "%q" -}}
+,"example":{{ .Example | printf "%q" }}}
+{{- end -}}
+]
+{{- end -}}
+
+{{- with .ErrorCategories -}}
+,"errorCategories":[
+{{- range $index, $category := . -}}
+{{- if ne $index 0 -}},{{- end -}}
+{"name":{{ .Name | printf "%q" }},"count":{{ .Count }},"examples":[
+{{- range $i, $example := .Examples -}}
+{{- if ne $i 0 -}},{{- end -}}
+{{ . | printf "%q" }}
+{{- end -}}
+]}
+{{- end -}}
+]
+{{- end -}}
+
+{{- with .TLSHandshakes -}}
+,"tlsHandshakes":{"full":{{ .Full -}}
+,"resumed":{{ .Resumed -}}
+,"perSecond":{{ $.Result.HandshakesPerSecond -}}
+{{- with .LatenciesStats Percentiles -}}
+,"latency":{"mean":{{ .Mean -}}
+,"stddev":{{ .Stddev -}}
+,"max":{{ .Max -}}
+}
+{{- end -}}
+}
+{{- end -}}
+
+{{- with .Connections -}}
+,"connections":{"opened":{{ .Opened }},"reused":{{ .Reused -}}
+,"closedByServer":{{ .ClosedByServer }},"closedByClient":{{ .ClosedByClient -}}
+,"open":{{ .Open }},"dialFailures":{{ .DialFailures }},"peak":{{ .Peak -}}
+{{- with .RequestsStats Percentiles -}}
+,"requestsPerConnection":{"mean":{{ .Mean }},"stddev":{{ .Stddev }},"max":{{ .Max }}}
+{{- end -}}
+{{- with .LifetimesStats Percentiles -}}
+,"lifetime":{"mean":{{ .Mean }},"stddev":{{ .Stddev }},"max":{{ .Max }}}
+{{- end -}}
+}
+{{- end -}}
+
+{{- with .Steps -}}
+,"steps":[
+{{- range $index, $step := . -}}
+{{- if ne $index 0 -}},{{- end -}}
+{"name":{{ .Name | printf "%q" }},"method":{{ .Method | printf "%q" -}}
+,"requests":{{ .Requests }},"errors":{{ .Errors -}}
+{{- with .LatenciesStats Percentiles -}}
+,"latency":{"mean":{{ .Mean }},"stddev":{{ .Stddev }},"max":{{ .Max }}}
+{{- end -}}
+}
+{{- end -}}
+]
+{{- end -}}
+
+{{- with .Mix -}}
+,"mix":[
+{{- range $index, $entry := . -}}
+{{- if ne $index 0 -}},{{- end -}}
+{"name":{{ .Name | printf "%q" }},"method":{{ .Method | printf "%q" -}}
+,"expected":{{ .Expected }},"actual":{{ .Actual -}}
+,"requests":{{ .Requests }},"errors":{{ .Errors -}}
+{{- with .LatenciesStats Percentiles -}}
+,"latency":{"mean":{{ .Mean }},"stddev":{{ .Stddev }},"max":{{ .Max }}}
+{{- end -}}
+}
 {{- end -}}
 ]
 {{- end -}}
 
-{{- with .LatenciesStats (FloatsToArray 0.5 0.75 0.9 0.95 0.99) -}}
+{{- with .ResponseBodies -}}
+,"responseBodies":{"compressed":{{ .Compressed -}}
+,"decompressed":{{ .Decompressed -}}
+,"compressionRatio":{{ .CompressionRatio -}}
+,"decodeFailures":{{ .DecodeFailures -}}
+}
+{{- end -}}
+
+{{- with .ResponseSamples -}}
+,"responseSamples":{"dir":{{ .Dir | printf "%q" }},"saved":{{ .Saved -}}
+,"failed":{{ .Failed }}}
+{{- end -}}
+
+{{- with .RequestLog -}}
+,"requestLog":{"path":{{ .Path | printf "%q" }},"written":{{ .Written -}}
+,"dropped":{{ .Dropped }}}
+{{- end -}}
+
+{{- with .Tracing -}}
+,"tracing":{"slowest":[
+{{- range $index, $request := .Slowest -}}
+{{- if ne $index 0 -}},{{- end -}}
+{"traceId":"{{ .TraceID }}","method":{{ .Method | printf "%q" -}}
+,"url":{{ .URL | printf "%q" }},"latency":{{ .Latency -}}
+{{- if .Error -}}
+,"error":{{ .Error | printf "%q" }}
+{{- else -}}
+,"code":{{ .Code }}
+{{- end -}}
+}
+{{- end -}}
+],"exported":{{ .Exported }},"dropped":{{ .Dropped }}}
+{{- end -}}
+
+{{- with .ThinkTime -}}
+,"thinkTime":{"pauses":{{ .Pauses -}}
+,"meanSeconds":{{ .Mean.Seconds -}}
+,"totalSeconds":{{ .Total.Seconds -}}
+,"effectiveConcurrency":{{ $.Result.EffectiveConcurrency -}}
+}
+{{- end -}}
+
+{{- with .TokenFailures -}}
+,"tokenFailures":{{ . }}
+{{- end -}}
+
+{{- with .Redirects -}}
+,"redirects":{"total":{{ .Total }},"redirected":{{ .Redirected -}}
+,"hops":{
+{{- range $index, $hops := .Distribution -}}
+{{- if ne $index 0 -}},{{- end -}}
+"{{ .Hops }}":{{ .Count }}
+{{- end -}}
+}}
+{{- end -}}
+
+{{- with $stats := .LatenciesStats Percentiles -}}
 ,"latency":{"mean":{{ .Mean -}}
 ,"stddev":{{ .Stddev -}}
 ,"max":{{ .Max -}}
 
 {{- if WithLatencies -}}
 ,"percentiles":{
-{{- range $pc, $lat := .Percentiles }}
-{{- if ne $pc 0.5 -}},{{- end -}}
-{{- printf "\"%2.0f\":%d" (Multiply $pc 100) $lat -}}
+{{- range $index, $pc := Percentiles }}
+{{- if ne $index 0 -}},{{- end -}}
+"{{ FormatPercentile $pc }}":{{ index $stats.Percentiles $pc }}
+{{- end -}}
+}
 {{- end -}}
+
 }
 {{- end -}}
 
+{{- with $stats := .ResponseSizesStats Percentiles -}}
+,"responseSize":{"mean":{{ .Mean -}}
+,"stddev":{{ .Stddev -}}
+,"max":{{ .Max -}}
+
+{{- if WithLatencies -}}
+,"percentiles":{
+{{- range $index, $pc := Percentiles }}
+{{- if ne $index 0 -}},{{- end -}}
+"{{ FormatPercentile $pc }}":{{ index $stats.Percentiles $pc }}
+{{- end -}}
 }
 {{- end -}}
 
-{{- with .RequestsStats (FloatsToArray 0.5 0.75 0.9 0.95 0.99) -}}
+}
+{{- end -}}
+
+{{- with $stats := .RequestsStats Percentiles -}}
 ,"rps":{"mean":{{ .Mean -}}
 ,"stddev":{{ .Stddev -}}
 ,"max":{{ .Max -}}
 ,"percentiles":{
-{{- range $pc, $rps := .Percentiles }}
-{{- if ne $pc 0.5 -}},{{- end -}}
-{{- printf "\"%2.0f\":%f" (Multiply $pc 100) $rps -}}
+{{- range $index, $pc := Percentiles }}
+{{- if ne $index 0 -}},{{- end -}}
+"{{ FormatPercentile $pc }}":{{ index $stats.Percentiles $pc | printf "%f" }}
 {{- end -}}
 }}
 {{- end -}}
 }}
 {{- end -}}`
+
+	htmlTemplate = `<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>bombardier report: {{ .Spec.Method | html }} {{ .Spec.RequestURL | html }}</title>
+<style>
+body { font: 14px/1.4 -apple-system, "Segoe UI", Helvetica, Arial, sans-serif; color: #222; margin: 0 auto; max-width: 1100px; padding: 16px 24px; }
+h1 { font-size: 22px; margin: 8px 0 2px; }
+h2 { font-size: 17px; margin: 28px 0 8px; border-bottom: 1px solid #ddd; padding-bottom: 4px; }
+.subtitle { color: #666; margin: 0 0 16px; word-break: break-all; }
+.cards { display: flex; flex-wrap: wrap; gap: 12px; }
+.card { border: 1px solid #ddd; border-radius: 6px; padding: 8px 14px; min-width: 130px; }
+.card .value { font-size: 20px; font-weight: 600; }
+.card .label { color: #666; font-size: 12px; }
+table { border-collapse: collapse; }
+th, td { text-align: left; padding: 3px 12px 3px 0; vertical-align: top; }
+td.num, th.num { text-align: right; }
+.charts { display: flex; flex-wrap: wrap; gap: 16px; }
+.chart { flex: 1 1 500px; }
+.chart h3 { font-size: 14px; margin: 4px 0; }
+svg { width: 100%; height: auto; overflow: visible; }
+svg text { font-size: 11px; fill: #555; }
+svg .axis { stroke: #999; }
+svg .grid { stroke: #eee; }
+svg .line { fill: none; stroke: #1f77b4; stroke-width: 1.5; }
+svg .bar { fill: #1f77b4; }
+.swatch { display: inline-block; width: 10px; height: 10px; margin-right: 6px; }
+.error { word-break: break-all; }
+</style>
+</head>
+<body>
+<h1>bombardier report</h1>
+<p class="subtitle">{{ .Spec.Method | html }} {{ .Spec.RequestURL | html }}</p>
+
+{{- with .Result }}
+<div class="cards">
+<div class="card"><div class="value">{{ .TotalRequests }}</div><div class="label">Requests</div></div>
+{{- with .RequestsStats Percentiles }}
+<div class="card"><div class="value">{{ printf "%.2f" .Mean }}</div><div class="label">Reqs/sec (avg)</div></div>
+{{- end }}
+{{- with .LatenciesStats Percentiles }}
+<div class="card"><div class="value">{{ FormatTimeUs .Mean }}</div><div class="label">Latency (avg)</div></div>
+<div class="card"><div class="value">{{ FormatTimeUs .Max }}</div><div class="label">Latency (max)</div></div>
+{{- end }}
+<div class="card"><div class="value">{{ FormatBinary .Throughput }}/s</div><div class="label">Throughput</div></div>
+<div class="card"><div class="value">{{ FormatTimeUs (Multiply .TimeTaken.Seconds 1e6) }}</div><div class="label">Time taken</div></div>
+</div>
+{{- end }}
+
+{{- with .Spec }}
+<h2>Test</h2>
+<table>
+<tr><th>Connections</th><td>{{ .NumberOfConnections }}</td></tr>
+{{- if .IsTimedTest }}
+<tr><th>Duration</th><td>{{ .TestDuration }}</td></tr>
+{{- else }}
+<tr><th>Requests</th><td>{{ .NumberOfRequests }}</td></tr>
+{{- end }}
+<tr><th>Method</th><td>{{ .Method | html }}</td></tr>
+<tr><th>URL</th><td>{{ .RequestURL | html }}</td></tr>
+{{- range .Headers }}
+<tr><th>Header</th><td>{{ .Key | html }}: {{ .Value | html }}</td></tr>
+{{- end }}
+{{- if .BodyFilePath }}
+<tr><th>Body file</th><td>{{ .BodyFilePath | html }}</td></tr>
+{{- else if .Body }}
+<tr><th>Body</th><td>{{ .Body | html }}</td></tr>
+{{- end }}
+<tr><th>Client</th><td>
+{{- if .IsFastHTTP }}fasthttp{{ end }}
+{{- if .IsNetHTTPV1 }}net/http.v1{{ end }}
+{{- if .IsNetHTTPV2 }}net/http.v2{{ end -}}
+</td></tr>
+<tr><th>Timeout</th><td>{{ .Timeout }}</td></tr>
+{{- with .Rate }}
+<tr><th>Rate</th><td>{{ . }} requests/sec</td></tr>
+{{- end }}
+{{- with .ThinkTime }}
+<tr><th>Think time</th><td>{{ . | html }}</td></tr>
+{{- end }}
+{{- with .ScenarioPath }}
+<tr><th>Scenario</th><td>{{ . | html }}</td></tr>
+{{- end }}
+{{- with .MixPath }}
+<tr><th>Mix</th><td>{{ . | html }}</td></tr>
+{{- end }}
+{{- with .ReplayPath }}
+<tr><th>Replay</th><td>{{ . | html }}</td></tr>
+{{- end }}
+</table>
+{{- end }}
+
+{{- with .Result }}
+<h2>Latency</h2>
+{{- with $stats := .LatenciesStats Percentiles }}
+<table>
+<tr><th>Average</th><td class="num">{{ FormatTimeUs .Mean }}</td></tr>
+<tr><th>Stdev</th><td class="num">{{ FormatTimeUs .Stddev }}</td></tr>
+{{- range $pc := Percentiles }}
+<tr><th>{{ FormatPercentile $pc }}%</th><td class="num">{{ FormatTimeUsUint64 (index $stats.Percentiles $pc) }}</td></tr>
+{{- end }}
+<tr><th>Max</th><td class="num">{{ FormatTimeUs .Max }}</td></tr>
+</table>
+{{- else }}
+<p>There wasn't enough data to compute statistics for latencies.</p>
+{{- end }}
+<div class="charts">
+<div class="chart"><h3>Latency by percentile</h3><div id="percentiles"></div></div>
+<div class="chart"><h3>Latency histogram</h3><div id="histogram"></div></div>
+</div>
+
+<h2>Requests per second</h2>
+{{- with .RequestsStats Percentiles }}
+<p>Average {{ printf "%.2f" .Mean }}, stdev {{ printf "%.2f" .Stddev }}, max {{ printf "%.2f" .Max }}.</p>
+{{- end }}
+<div class="chart"><div id="timeline"></div></div>
+
+<h2>HTTP codes</h2>
+<div class="charts">
+<div class="chart">
+<table id="codes">
+<tr><th>1xx</th><td class="num">{{ .Req1XX }}</td></tr>
+<tr><th>2xx</th><td class="num">{{ .Req2XX }}</td></tr>
+<tr><th>3xx</th><td class="num">{{ .Req3XX }}</td></tr>
+<tr><th>4xx</th><td class="num">{{ .Req4XX }}</td></tr>
+<tr><th>5xx</th><td class="num">{{ .Req5XX }}</td></tr>
+<tr><th>others</th><td class="num">{{ .Others }}</td></tr>
+</table>
+</div>
+<div class="chart"><div id="pie"></div></div>
+</div>
+
+{{- with .Errors }}
+<h2>Errors</h2>
+<table>
+<tr><th class="num">Count</th><th>Category</th><th>Error</th></tr>
+{{- range . }}
+<tr><td class="num">{{ .Count }}</td><td>{{ .Category | html }}</td><td class="error">{{ .Error | html }}</td></tr>
+{{- end }}
+</table>
+{{- end }}
+
+<script id="data" type="application/json">
+{"latencies":{{ JSON .LatenciesBuckets }}
+{{- with .Timeline -}}
+,"timeline":{"interval":{{ .Interval.Seconds }},"rps":{{ JSON .RequestsPerSecond }}}
+{{- end -}}
+,"codes":[["1xx",{{ .Req1XX }}],["2xx",{{ .Req2XX }}],["3xx",{{ .Req3XX }}],["4xx",{{ .Req4XX }}],["5xx",{{ .Req5XX }}],["others",{{ .Others }}]]}
+</script>
+{{- end }}
+<script>
+(function () {
+  var data = JSON.parse(document.getElementById("data").textContent);
+  var ns = "http://www.w3.org/2000/svg";
+  var W = 520, H = 260, M = { left: 64, right: 12, top: 10, bottom: 36 };
+
+  function node(tag, attrs, parent, text) {
+    var n = document.createElementNS(ns, tag);
+    for (var k in attrs) n.setAttribute(k, attrs[k]);
+    if (text !== undefined) n.textContent = text;
+    if (parent) parent.appendChild(n);
+    return n;
+  }
+
+  function fmtUs(us) {
+    if (us === 0) return "0";
+    var units = [[3600e6, "h"], [60e6, "m"], [1e6, "s"], [1e3, "ms"]];
+    for (var i = 0; i < units.length; i++) {
+      if (us >= units[i][0]) return (us / units[i][0]).toFixed(2) + units[i][1];
+    }
+    return us.toFixed(2) + "us";
+  }
+
+  // niceTicks returns about count round ticks from 0 to at least max.
+  function niceTicks(max, count) {
+    if (max <= 0) return [0, 1];
+    var raw = max / count, magnitude = Math.pow(10, Math.floor(Math.log10(raw)));
+    var step = [1, 2, 5, 10].map(function (m) { return m * magnitude; })
+      .filter(function (s) { return s >= raw; })[0];
+    var ticks = [];
+    for (var i = 0; i <= Math.ceil(max / step - 1e-9); i++) ticks.push(i * step);
+    return ticks;
+  }
+
+  // chart draws axes with the given ticks and returns functions
+  // mapping values into coordinates.
+  function chart(id, xMax, xTicks, yMax, yTicks, yFormat, xLabel) {
+    var el = document.getElementById(id);
+    if (!el) return null;
+    var svg = node("svg", { viewBox: "0 0 " + W + " " + H }, el);
+    var pw = W - M.left - M.right, ph = H - M.top - M.bottom;
+    var x = function (v) { return M.left + (xMax > 0 ? v / xMax * pw : 0); };
+    var y = function (v) { return M.top + ph - (yMax > 0 ? v / yMax * ph : 0); };
+    yTicks.forEach(function (t) {
+      node("line", { x1: M.left, x2: M.left + pw, y1: y(t), y2: y(t), "class": "grid" }, svg);
+      node("text", { x: M.left - 6, y: y(t) + 4, "text-anchor": "end" }, svg, yFormat(t));
+    });
+    xTicks.forEach(function (t) {
+      node("text", { x: x(t.value), y: M.top + ph + 16, "text-anchor": "middle" }, svg, t.label);
+    });
+    node("line", { x1: M.left, x2: M.left + pw, y1: M.top + ph, y2: M.top + ph, "class": "axis" }, svg);
+    node("line", { x1: M.left, x2: M.left, y1: M.top, y2: M.top + ph, "class": "axis" }, svg);
+    node("text", { x: M.left + pw / 2, y: H - 4, "text-anchor": "middle" }, svg, xLabel);
+    return { svg: svg, x: x, y: y };
+  }
+
+  function empty(id, message) {
+    var el = document.getElementById(id);
+    if (el) el.textContent = message;
+  }
+
+  function drawPercentiles(buckets) {
+    var total = 0;
+    buckets.forEach(function (b) { total += b.count; });
+    if (total === 0) return empty("percentiles", "No latencies recorded.");
+    var xMax = Math.max(1, Math.ceil(Math.log10(total)));
+    var px = function (p) { return p >= 1 ? xMax : Math.min(xMax, -Math.log10(1 - p)); };
+    var points = [], seen = 0, yMax = buckets[buckets.length - 1].value;
+    buckets.forEach(function (b) {
+      points.push([px(seen / total), b.value]);
+      seen += b.count;
+      points.push([px(seen / total), b.value]);
+    });
+    var xTicks = [];
+    for (var i = 0; i <= xMax; i++) {
+      xTicks.push({ value: i, label: i === 0 ? "0%" : (100 - Math.pow(10, 2 - i)).toFixed(Math.max(0, i - 2)) + "%" });
+    }
+    var yTicks = niceTicks(yMax, 5);
+    var c = chart("percentiles", xMax, xTicks, yTicks[yTicks.length - 1], yTicks, fmtUs, "Percentile");
+    node("path", {
+      d: points.map(function (p, i) { return (i ? "L" : "M") + c.x(p[0]).toFixed(1) + "," + c.y(p[1]).toFixed(1); }).join(""),
+      "class": "line"
+    }, c.svg);
+  }
+
+  function drawHistogram(buckets) {
+    if (buckets.length === 0) return empty("histogram", "No latencies recorded.");
+    var bins = 40, lo = Math.log(Math.max(1, buckets[0].value));
+    var hi = Math.log(buckets[buckets.length - 1].value + 1);
+    var counts = [];
+    for (var i = 0; i < bins; i++) counts.push(0);
+    buckets.forEach(function (b) {
+      var i = Math.floor((Math.log(Math.max(1, b.value)) - lo) / (hi - lo || 1) * bins);
+      counts[Math.max(0, Math.min(bins - 1, i))] += b.count;
+    });
+    var edge = function (i) { return Math.exp(lo + (hi - lo) * i / bins); };
+    var xTicks = [];
+    for (var j = 0; j <= bins; j += 10) xTicks.push({ value: j, label: fmtUs(edge(j)) });
+    var yTicks = niceTicks(Math.max.apply(null, counts), 5);
+    var c = chart("histogram", bins, xTicks, yTicks[yTicks.length - 1], yTicks, String, "Latency (log scale)");
+    counts.forEach(function (n, i) {
+      if (n === 0) return;
+      node("rect", {
+        x: c.x(i) + 0.5, y: c.y(n), width: Math.max(1, c.x(1) - c.x(0) - 1),
+        height: c.y(0) - c.y(n), "class": "bar"
+      }, c.svg).appendChild(node("title", {}, null, fmtUs(edge(i)) + " - " + fmtUs(edge(i + 1)) + ": " + n));
+    });
+  }
+
+  function drawTimeline(timeline) {
+    if (!timeline || timeline.rps.length === 0) return empty("timeline", "No requests recorded.");
+    var ticks = niceTicks(timeline.rps.length * timeline.interval, 8);
+    var xTicks = ticks.map(function (t) { return { value: t, label: +t.toFixed(2) + "s" }; });
+    var yTicks = niceTicks(Math.max.apply(null, timeline.rps), 5);
+    var c = chart("timeline", ticks[ticks.length - 1], xTicks, yTicks[yTicks.length - 1], yTicks, String, "Time");
+    var d = "";
+    timeline.rps.forEach(function (r, i) {
+      var x = (i + 0.5) * timeline.interval;
+      d += (i ? "L" : "M") + c.x(x).toFixed(1) + "," + c.y(r).toFixed(1);
+    });
+    node("path", { d: d, "class": "line" }, c.svg);
+  }
+
+  function drawPie(codes) {
+    var colors = { "1xx": "#9e9e9e", "2xx": "#2ca02c", "3xx": "#1f77b4", "4xx": "#ff7f0e", "5xx": "#d62728", "others": "#9467bd" };
+    var total = 0;
+    codes.forEach(function (c) { total += c[1]; });
+    document.querySelectorAll("#codes th").forEach(function (th) {
+      var swatch = document.createElement("span");
+      swatch.className = "swatch";
+      swatch.style.background = colors[th.textContent];
+      th.insertBefore(swatch, th.firstChild);
+    });
+    if (total === 0) return empty("pie", "No requests recorded.");
+    var svg = node("svg", { viewBox: "-110 -110 220 220", style: "max-width: 260px" }, document.getElementById("pie"));
+    var angle = 0;
+    codes.forEach(function (c) {
+      if (c[1] === 0) return;
+      var share = c[1] / total, title = c[0] + ": " + c[1] + " (" + (share * 100).toFixed(2) + "%)";
+      var shape;
+      if (share === 1) {
+        shape = node("circle", { r: 100, fill: colors[c[0]] }, svg);
+      } else {
+        var a2 = angle + share * 2 * Math.PI;
+        shape = node("path", {
+          d: "M0,0L" + 100 * Math.sin(angle) + "," + -100 * Math.cos(angle) +
+            "A100,100 0 " + (share > 0.5 ? 1 : 0) + " 1 " + 100 * Math.sin(a2) + "," + -100 * Math.cos(a2) + "Z",
+          fill: colors[c[0]]
+        }, svg);
+        angle = a2;
+      }
+      node("title", {}, shape, title);
+    });
+  }
+
+  drawPercentiles(data.latencies);
+  drawHistogram(data.latencies);
+  drawTimeline(data.timeline);
+  drawPie(data.codes);
+})();
+</script>
+</body>
+</html>
+`
+	csvTemplate = `
+{{- if WithHeader -}}
+url,method,numberOfConnections,timeTakenSeconds,
+{{- "" }}req1xx,req2xx,req3xx,req4xx,req5xx,others,
+{{- "" }}rpsMean,rpsStddev,rpsMax,latencyMean,latencyStddev,latencyMax
+{{- range Percentiles }},latency{{ FormatPercentile . }}{{ end -}}
+,bytesRead,bytesWritten,throughput
+{{ end -}}
+{{ CSVField .Spec.RequestURL }},{{ CSVField .Spec.Method }}
+{{- "" }},{{ .Spec.NumberOfConnections }},{{ .Result.TimeTaken.Seconds }}
+{{- with .Result -}}
+,{{ .Req1XX }},{{ .Req2XX }},{{ .Req3XX }},{{ .Req4XX }},{{ .Req5XX }},{{ .Others }}
+{{- end -}}
+{{- with .Result.RequestsStats Percentiles -}}
+{{ printf ",%.2f,%.2f,%.2f" .Mean .Stddev .Max }}
+{{- else -}}
+,,,
+{{- end -}}
+{{- with $stats := .Result.LatenciesStats Percentiles -}}
+{{ printf ",%.2f,%.2f,%.0f" .Mean .Stddev .Max }}
+	{{- range Percentiles }},{{ index $stats.Percentiles . }}{{ end -}}
+{{- else -}}
+,,,{{ range Percentiles }},{{ end -}}
+{{- end -}}
+,{{ .Result.BytesRead }},{{ .Result.BytesWritten }},{{ printf "%.2f" .Result.Throughput }}
+`
+	markdownTemplate = `
+{{- with .Spec -}}
+| Target | Connections | {{ if .IsTimedTest }}Duration{{ else }}Requests{{ end }} | Time taken |
+| --- | --: | --: | --: |
+| {{ MarkdownCell .Method }} {{ MarkdownCell .RequestURL }} | {{ .NumberOfConnections }} | {{ if .IsTimedTest }}{{ .TestDuration }}{{ else }}{{ .NumberOfRequests }}{{ end }} | {{ FormatTimeUs (Multiply $.Result.TimeTaken.Seconds 1e6) }} |
+{{- end }}
+
+| Statistics | Avg | Stdev | Max |
+| --- | --: | --: | --: |
+{{- with .Result.RequestsStats Percentiles }}
+| Reqs/sec | {{ printf "%.2f" .Mean }} | {{ printf "%.2f" .Stddev }} | {{ printf "%.2f" .Max }} |
+{{- end }}
+{{- with .Result.LatenciesStats Percentiles }}
+| Latency | {{ FormatTimeUs .Mean }} | {{ FormatTimeUs .Stddev }} | {{ FormatTimeUs .Max }} |
+
+| Percentile | Latency |
+| --: | --: |
+{{- range $pc, $lat := .Percentiles }}
+| {{ FormatPercentile $pc }}% | {{ FormatTimeUsUint64 $lat }} |
+{{- end }}
+{{- end }}
+
+| 1xx | 2xx | 3xx | 4xx | 5xx | Others | Throughput |
+| --: | --: | --: | --: | --: | --: | --: |
+{{- with .Result }}
+| {{ .Req1XX }} | {{ .Req2XX }} | {{ .Req3XX }} | {{ .Req4XX }} | {{ .Req5XX }} | {{ .Others }} | {{ FormatBinary .Throughput }}/s |
+{{- end }}
+{{- with .Result.Errors }}
+
+| Error | Category | Count |
+| --- | --- | --: |
+{{- range . }}
+| {{ MarkdownCell .Error }} | {{ MarkdownCell .Category }} | {{ .Count }} |
+{{- end }}
+{{- end }}
+`
+	junitTemplate = `<?xml version="1.0" encoding="UTF-8"?>
+{{ $checks := .Result.Checks -}}
+<testsuites name="bombardier" tests="{{ len $checks }}" failures="{{ $checks.Failures }}" time="{{ .Result.TimeTaken.Seconds }}">
+  <testsuite name="{{ html .Spec.Method }} {{ html .Spec.RequestURL }}" tests="{{ len $checks }}" failures="{{ $checks.Failures }}" errors="0" time="{{ .Result.TimeTaken.Seconds }}">
+    <properties>
+      <property name="numberOfConnections" value="{{ .Spec.NumberOfConnections }}"/>
+{{- if .Spec.IsTimedTest }}
+      <property name="testDurationSeconds" value="{{ .Spec.TestDuration.Seconds }}"/>
+{{- else }}
+      <property name="numberOfRequests" value="{{ .Spec.NumberOfRequests }}"/>
+{{- end }}
+      <property name="requests" value="{{ .Result.TotalRequests }}"/>
+{{- with .Result.RequestsStats Percentiles }}
+      <property name="rpsMean" value="{{ printf "%.2f" .Mean }}"/>
+{{- end }}
+{{- with .Result.LatenciesStats Percentiles }}
+      <property name="latencyMean" value="{{ printf "%.2f" .Mean }}"/>
+{{- end }}
+      <property name="throughput" value="{{ printf "%.2f" .Result.Throughput }}"/>
+    </properties>
+{{- range $checks }}
+    <testcase classname="bombardier.{{ html .Group }}" name="{{ html .Name }}"
+	{{- if .Passed }}/>
+	{{- else }}>
+      <failure message="{{ html .Failure }}" type="{{ html .Group }}">{{ html .Details }}</failure>
+    </testcase>
+	{{- end }}
+{{- end }}
+  </testsuite>
+</testsuites>
+`
 )
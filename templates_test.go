@@ -0,0 +1,98 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/codesenberg/bombardier/internal"
+	fhist "github.com/codesenberg/concurrent/float64/histogram"
+)
+
+var updateGolden = flag.Bool("update", false, "update golden files")
+
+// testInfo returns results of an imaginary test with a few failures,
+// whose error messages and URL need escaping in most formats.
+func testInfo() internal.TestInfo {
+	latencies := newHDRHistogram(defaultHistogramDigits)
+	for us := uint64(100); us <= 10000; us += 100 {
+		latencies.Increment(us)
+	}
+	requests := fhist.Default()
+	for _, rps := range []float64{950, 1000, 1000, 1050} {
+		requests.Increment(rps)
+	}
+	return internal.TestInfo{
+		Spec: internal.Spec{
+			NumberOfConnections: 125,
+			TestType:            internal.ByTime,
+			TestDuration:        10 * time.Second,
+			Method:              "GET",
+			URL:                 ParseURLOrPanic(`http://localhost:8080/?q="a,b"&c=<d|e>`),
+		},
+		Result: internal.Results{
+			BytesRead:    2 * MB,
+			BytesWritten: 512 * KB,
+			TimeTaken:    10 * time.Second,
+			Req2XX:       9900,
+			Req5XX:       90,
+			Others:       10,
+			Errors: []internal.ErrorWithCount{
+				{
+					Error:    `dial tcp: lookup "a|b" & <c>`,
+					Count:    10,
+					Category: "dns",
+					Example:  `dial tcp: lookup "a|b" & <c>`,
+				},
+			},
+			Latencies: latencies,
+			Requests:  requests,
+			Steps: []internal.StepStats{
+				{Name: "login", Method: "POST", Requests: 5000, Errors: 5},
+				{Name: "profile", Method: "GET", Requests: 5000},
+			},
+		},
+	}
+}
+
+func TestFormatsGolden(t *testing.T) {
+	expectations := []struct {
+		golden string
+		conf   config
+	}{
+		{"csv.golden", config{format: knownFormat("csv")}},
+		{"csv-no-header.golden", config{
+			format:   knownFormat("csv"),
+			noHeader: true,
+		}},
+		{"markdown.golden", config{format: knownFormat("markdown")}},
+		{"junit.golden", config{format: knownFormat("junit")}},
+	}
+	for _, e := range expectations {
+		b := &bombardier{conf: e.conf}
+		tmpl, err := b.prepareTemplate(e.conf.format, e.conf.noHeader)
+		if err != nil {
+			t.Fatal(err)
+		}
+		out := new(bytes.Buffer)
+		if err := tmpl.Execute(out, testInfo()); err != nil {
+			t.Fatal(err)
+		}
+		path := filepath.Join("testdata", e.golden)
+		if *updateGolden {
+			if err := ioutil.WriteFile(path, out.Bytes(), 0644); err != nil {
+				t.Fatal(err)
+			}
+		}
+		expected, err := ioutil.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(out.Bytes(), expected) {
+			t.Errorf("%v: expected\n%s\nbut got\n%s", e.golden, expected, out)
+		}
+	}
+}
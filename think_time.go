@@ -0,0 +1,123 @@
+package main
+
+import (
+	"bufio"
+	"math/rand"
+	"os"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// thinkTime pauses connections between the requests to simulate users,
+// who don't click as fast as they can.
+type thinkTime struct {
+	sample func() time.Duration
+
+	// pauses and thought (in nanoseconds) are the number of pauses
+	// and the total time spent in them by all the connections.
+	pauses, thought uint64
+}
+
+// parseThinkTime understands constant (500ms), uniformly distributed
+// (100ms-2s), exponentially distributed with the given mean
+// (exp:500ms) think times and think times picked at random from the
+// file with one duration per line (file:path).
+func parseThinkTime(spec string) (*thinkTime, error) {
+	switch {
+	case strings.HasPrefix(spec, "exp:"):
+		mean, err := parseThinkDuration(spec[len("exp:"):])
+		if err != nil {
+			return nil, err
+		}
+		return &thinkTime{sample: func() time.Duration {
+			return time.Duration(rand.ExpFloat64() * float64(mean))
+		}}, nil
+	case strings.HasPrefix(spec, "file:"):
+		durations, err := readThinkTimes(spec[len("file:"):])
+		if err != nil {
+			return nil, err
+		}
+		return &thinkTime{sample: func() time.Duration {
+			return durations[rand.Intn(len(durations))]
+		}}, nil
+	}
+	if min, max, ok := strings.Cut(spec, "-"); ok {
+		from, err := parseThinkDuration(min)
+		if err != nil {
+			return nil, err
+		}
+		to, err := parseThinkDuration(max)
+		if err != nil {
+			return nil, err
+		}
+		if to < from {
+			return nil, errInvalidThinkTime
+		}
+		return &thinkTime{sample: func() time.Duration {
+			return from + time.Duration(rand.Int63n(int64(to-from)+1))
+		}}, nil
+	}
+	d, err := parseThinkDuration(spec)
+	if err != nil {
+		return nil, err
+	}
+	return &thinkTime{sample: func() time.Duration {
+		return d
+	}}, nil
+}
+
+func parseThinkDuration(s string) (time.Duration, error) {
+	d, err := time.ParseDuration(strings.TrimSpace(s))
+	if err != nil || d < 0 {
+		return 0, errInvalidThinkTime
+	}
+	return d, nil
+}
+
+// readThinkTimes skips empty lines and lines starting with #.
+func readThinkTimes(path string) ([]time.Duration, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	var durations []time.Duration
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		d, err := parseThinkDuration(line)
+		if err != nil {
+			return nil, err
+		}
+		durations = append(durations, d)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if len(durations) == 0 {
+		return nil, errInvalidThinkTime
+	}
+	return durations, nil
+}
+
+// pause waits for the sampled think time, unless the test is done
+// earlier. Only completed pauses are accounted.
+func (t *thinkTime) pause(done <-chan struct{}) token {
+	d := t.sample()
+	if d > 0 {
+		timer := time.NewTimer(d)
+		defer timer.Stop()
+		select {
+		case <-timer.C:
+		case <-done:
+			return brk
+		}
+	}
+	atomic.AddUint64(&t.pauses, 1)
+	atomic.AddUint64(&t.thought, uint64(d))
+	return cont
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseThinkTime(t *testing.T) {
+	constant, err := parseThinkTime("250ms")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d := constant.sample(); d != 250*time.Millisecond {
+		t.Errorf("expected 250ms, but got %v", d)
+	}
+
+	uniform, err := parseThinkTime("100ms-200ms")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 1000; i++ {
+		if d := uniform.sample(); d < 100*time.Millisecond ||
+			d > 200*time.Millisecond {
+			t.Fatalf("%v is out of range", d)
+		}
+	}
+
+	exp, err := parseThinkTime("exp:100ms")
+	if err != nil {
+		t.Fatal(err)
+	}
+	total := time.Duration(0)
+	for i := 0; i < 10000; i++ {
+		total += exp.sample()
+	}
+	if mean := total / 10000; mean < 90*time.Millisecond ||
+		mean > 110*time.Millisecond {
+		t.Errorf("expected mean of about 100ms, but got %v", mean)
+	}
+
+	path := writeDataFile(t, "think.txt", "# pauses\n1s\n\n2s\n")
+	fromFile, err := parseThinkTime("file:" + path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 100; i++ {
+		if d := fromFile.sample(); d != time.Second && d != 2*time.Second {
+			t.Fatalf("unexpected think time %v", d)
+		}
+	}
+}
+
+func TestParseInvalidThinkTime(t *testing.T) {
+	emptyFile := writeDataFile(t, "empty.txt", "# nothing\n")
+	for _, spec := range []string{
+		"", "soon", "-1s", "2s-1s", "1s-", "exp:", "exp:-1s",
+		"file:" + emptyFile,
+	} {
+		if _, err := parseThinkTime(spec); err != errInvalidThinkTime {
+			t.Errorf("%q: expected %v, but got %v",
+				spec, errInvalidThinkTime, err)
+		}
+	}
+}
+
+func TestThinkTimePause(t *testing.T) {
+	tt, err := parseThinkTime("1h")
+	if err != nil {
+		t.Fatal(err)
+	}
+	done := make(chan struct{})
+	close(done)
+	if tt.pause(done) != brk {
+		t.Error("expected pause to be interrupted")
+	}
+
+	tt, err = parseThinkTime("20ms")
+	if err != nil {
+		t.Fatal(err)
+	}
+	start := time.Now()
+	if tt.pause(make(chan struct{})) != cont {
+		t.Error("expected pause to complete")
+	}
+	if elapsed := time.Since(start); elapsed < 20*time.Millisecond {
+		t.Errorf("paused only for %v", elapsed)
+	}
+	if tt.pauses != 1 || time.Duration(tt.thought) != 20*time.Millisecond {
+		t.Errorf("unexpected stats: %v pauses, %v", tt.pauses, tt.thought)
+	}
+}
@@ -0,0 +1,78 @@
+package main
+
+import "time"
+
+const (
+	defaultTimelineInterval = 100 * time.Millisecond
+
+	// maxTimelinePoints bounds the memory taken by the timeline, its
+	// interval doubles each time the test gets longer.
+	maxTimelinePoints = 1024
+)
+
+// timeline counts requests completed during each interval of the
+// test. Requests are reported in batches, so each batch is spread
+// between intervals it spans proportionally to the time spent in
+// each of them. It's not safe for concurrent use.
+type timeline struct {
+	start    time.Time
+	interval time.Duration
+	counts   []float64
+	end      time.Duration
+}
+
+func newTimeline() *timeline {
+	return &timeline{interval: defaultTimelineInterval}
+}
+
+// begin marks the start of the test.
+func (t *timeline) begin(start time.Time) {
+	t.start = start
+}
+
+// add accounts n requests completed from from to to.
+func (t *timeline) add(from, to time.Time, n float64) {
+	f, e := from.Sub(t.start), to.Sub(t.start)
+	if f < 0 {
+		f = 0
+	}
+	if e < f {
+		e = f
+	}
+	for e/t.interval >= maxTimelinePoints {
+		t.compact()
+	}
+	last := int(e / t.interval)
+	for len(t.counts) <= last {
+		t.counts = append(t.counts, 0)
+	}
+	if e > t.end {
+		t.end = e
+	}
+	if e == f {
+		t.counts[last] += n
+		return
+	}
+	span := float64(e - f)
+	for f < e {
+		i := f / t.interval
+		next := (i + 1) * t.interval
+		if next > e {
+			next = e
+		}
+		t.counts[i] += n * float64(next-f) / span
+		f = next
+	}
+}
+
+func (t *timeline) compact() {
+	for i := 0; i < len(t.counts); i += 2 {
+		c := t.counts[i]
+		if i+1 < len(t.counts) {
+			c += t.counts[i+1]
+		}
+		t.counts[i/2] = c
+	}
+	t.counts = t.counts[:(len(t.counts)+1)/2]
+	t.interval *= 2
+}
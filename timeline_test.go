@@ -0,0 +1,75 @@
+package main
+
+import (
+	"math"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/codesenberg/bombardier/internal"
+)
+
+func TestTimelineSpreadsBatches(t *testing.T) {
+	tl := newTimeline()
+	start := time.Now()
+	tl.begin(start)
+	at := func(ms int) time.Time {
+		return start.Add(time.Duration(ms) * time.Millisecond)
+	}
+	tl.add(at(50), at(250), 200)
+	tl.add(at(250), at(250), 10)
+	exp := []float64{50, 100, 60}
+	if !reflect.DeepEqual(tl.counts, exp) {
+		t.Errorf("expected %v, but got %v", exp, tl.counts)
+	}
+	if tl.end != 250*time.Millisecond {
+		t.Errorf("expected end at 250ms, but got %v", tl.end)
+	}
+}
+
+func TestTimelineCompaction(t *testing.T) {
+	tl := newTimeline()
+	start := time.Now()
+	tl.begin(start)
+	step := defaultTimelineInterval
+	for i := 0; i < 3*maxTimelinePoints; i++ {
+		from := start.Add(time.Duration(i) * step)
+		tl.add(from, from.Add(step), 1)
+	}
+	if len(tl.counts) > maxTimelinePoints {
+		t.Errorf("expected at most %v points, but got %v",
+			maxTimelinePoints, len(tl.counts))
+	}
+	if tl.interval != 4*defaultTimelineInterval {
+		t.Errorf("expected interval of %v, but got %v",
+			4*defaultTimelineInterval, tl.interval)
+	}
+	total := 0.0
+	for _, c := range tl.counts {
+		total += c
+	}
+	if math.Abs(total-3*maxTimelinePoints) > 1e-6 {
+		t.Errorf("expected %v requests in total, but got %v",
+			3*maxTimelinePoints, total)
+	}
+}
+
+func TestTimelineRequestsPerSecond(t *testing.T) {
+	tl := internal.Timeline{
+		Interval: 100 * time.Millisecond,
+		Requests: []float64{10, 20, 5, 0},
+		End:      250 * time.Millisecond,
+	}
+	// the last interval is only 50ms long and the one after it is
+	// empty
+	exp := []float64{100, 200, 100}
+	act := tl.RequestsPerSecond()
+	if len(act) != len(exp) {
+		t.Fatalf("expected %v, but got %v", exp, act)
+	}
+	for i := range exp {
+		if math.Abs(act[i]-exp[i]) > 1e-9 {
+			t.Errorf("expected %v, but got %v", exp, act)
+		}
+	}
+}
@@ -0,0 +1,404 @@
+package main
+
+import (
+	"bytes"
+	"container/heap"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/codesenberg/bombardier/internal"
+)
+
+const (
+	traceparentHeader = "traceparent"
+
+	defaultTraceSlowest = uint64(10)
+
+	otlpTracesPath = "/v1/traces"
+	// otlpBatchSize and otlpFlushInterval limit the number of spans
+	// in a single export request and how long spans wait for it.
+	otlpBatchSize     = 512
+	otlpFlushInterval = time.Second
+	// otlpBacklog is the number of spans waiting to be exported,
+	// after which new ones are dropped rather than slow the test.
+	otlpBacklog = 8 * otlpBatchSize
+
+	spanKindClient  = 3
+	spanStatusError = 2
+)
+
+// requestTrace is the trace context of the current request of the
+// session, which is propagated to the server.
+type requestTrace struct {
+	traceID [16]byte
+	spanID  [8]byte
+
+	method, url string
+	// sent is false, unless the client got to send the request.
+	sent bool
+}
+
+func newRequestTrace() *requestTrace {
+	t := new(requestTrace)
+	// all-zero ids are invalid
+	for t.traceID == [16]byte{} {
+		putUint64(t.traceID[:8], rand.Uint64())
+		putUint64(t.traceID[8:], rand.Uint64())
+	}
+	for t.spanID == [8]byte{} {
+		putUint64(t.spanID[:], rand.Uint64())
+	}
+	return t
+}
+
+func putUint64(b []byte, v uint64) {
+	for i := range b {
+		b[i] = byte(v >> (8 * uint(i)))
+	}
+}
+
+// traceparent returns the value of W3C traceparent header, requests
+// are always marked as sampled.
+func (t *requestTrace) traceparent() string {
+	return "00-" + hex.EncodeToString(t.traceID[:]) + "-" +
+		hex.EncodeToString(t.spanID[:]) + "-01"
+}
+
+// fill is called by clients to record what was actually sent.
+func (t *requestTrace) fill(method, url string) {
+	t.method, t.url, t.sent = method, url, true
+}
+
+// slowRequests is a min-heap of the slowest requests seen so far.
+type slowRequests []internal.TracedRequest
+
+func (s slowRequests) Len() int           { return len(s) }
+func (s slowRequests) Less(i, j int) bool { return s[i].Latency < s[j].Latency }
+func (s slowRequests) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
+
+func (s *slowRequests) Push(x interface{}) {
+	*s = append(*s, x.(internal.TracedRequest))
+}
+
+func (s *slowRequests) Pop() interface{} {
+	old := *s
+	x := old[len(old)-1]
+	*s = old[:len(old)-1]
+	return x
+}
+
+// tracer keeps the slowest traced requests and exports spans of all
+// of them, if an OTLP endpoint is set.
+type tracer struct {
+	n int
+
+	mu      sync.Mutex
+	slowest slowRequests
+	// threshold is the latency of the fastest of the slowest requests,
+	// once there are n of them, faster requests are skipped without
+	// taking the lock.
+	threshold uint64
+
+	exporter *otlpExporter
+}
+
+func newTracer(slowest uint64, otlpEndpoint string, timeout time.Duration) *tracer {
+	if slowest == 0 {
+		slowest = defaultTraceSlowest
+	}
+	t := &tracer{n: int(slowest)}
+	if otlpEndpoint != "" {
+		t.exporter = newOTLPExporter(otlpEndpoint, timeout)
+	}
+	return t
+}
+
+func (t *tracer) finish(
+	rt *requestTrace, code int, usTaken uint64, err error,
+) {
+	if t.exporter != nil {
+		t.exporter.add(rt, code, usTaken, err)
+	}
+	if usTaken <= atomic.LoadUint64(&t.threshold) {
+		return
+	}
+	r := internal.TracedRequest{
+		TraceID: hex.EncodeToString(rt.traceID[:]),
+		Method:  rt.method,
+		URL:     rt.url,
+		Latency: usTaken,
+	}
+	if code > 0 {
+		r.Code = code
+	}
+	if err != nil {
+		r.Error = err.Error()
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if len(t.slowest) < t.n {
+		heap.Push(&t.slowest, r)
+	} else if r.Latency > t.slowest[0].Latency {
+		t.slowest[0] = r
+		heap.Fix(&t.slowest, 0)
+	}
+	if len(t.slowest) == t.n {
+		atomic.StoreUint64(&t.threshold, t.slowest[0].Latency)
+	}
+}
+
+func (t *tracer) close() error {
+	if t.exporter == nil {
+		return nil
+	}
+	return t.exporter.close()
+}
+
+func (t *tracer) info() *internal.Tracing {
+	t.mu.Lock()
+	slowest := append([]internal.TracedRequest(nil), t.slowest...)
+	t.mu.Unlock()
+	sort.SliceStable(slowest, func(i, j int) bool {
+		return slowest[i].Latency > slowest[j].Latency
+	})
+	res := &internal.Tracing{Slowest: slowest}
+	if t.exporter != nil {
+		res.Exported = atomic.LoadUint64(&t.exporter.exported)
+		res.Dropped = atomic.LoadUint64(&t.exporter.dropped)
+	}
+	return res
+}
+
+// otlpURL returns the URL to post spans to, endpoint without a path
+// is treated as the base URL of the collector.
+func otlpURL(endpoint string) (string, error) {
+	u, err := url.Parse(endpoint)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") ||
+		u.Host == "" {
+		return "", errInvalidOTLPEndpoint
+	}
+	if u.Path == "" || u.Path == "/" {
+		u.Path = otlpTracesPath
+	}
+	return u.String(), nil
+}
+
+type span struct {
+	traceID    [16]byte
+	spanID     [8]byte
+	method     string
+	url        string
+	code       int
+	err        string
+	start, end time.Time
+}
+
+// otlpExporter sends spans in batches to OTLP/HTTP endpoint using
+// JSON encoding. Spans are sent by a separate goroutine and dropped,
+// if it can't keep up.
+type otlpExporter struct {
+	url    string
+	client *http.Client
+
+	spans chan span
+	done  chan struct{}
+
+	exported, dropped uint64
+	err               error
+}
+
+func newOTLPExporter(endpoint string, timeout time.Duration) *otlpExporter {
+	// endpoint is validated by checkArgs
+	u, _ := otlpURL(endpoint)
+	e := &otlpExporter{
+		url:    u,
+		client: &http.Client{Timeout: timeout},
+		spans:  make(chan span, otlpBacklog),
+		done:   make(chan struct{}),
+	}
+	go e.sender()
+	return e
+}
+
+func (e *otlpExporter) add(
+	rt *requestTrace, code int, usTaken uint64, err error,
+) {
+	end := time.Now()
+	s := span{
+		traceID: rt.traceID,
+		spanID:  rt.spanID,
+		method:  rt.method,
+		url:     rt.url,
+		code:    code,
+		start:   end.Add(-time.Duration(usTaken) * time.Microsecond),
+		end:     end,
+	}
+	if err != nil {
+		s.err = err.Error()
+	}
+	select {
+	case e.spans <- s:
+	default:
+		atomic.AddUint64(&e.dropped, 1)
+	}
+}
+
+func (e *otlpExporter) sender() {
+	defer close(e.done)
+	ticker := time.NewTicker(otlpFlushInterval)
+	defer ticker.Stop()
+	batch := make([]span, 0, otlpBatchSize)
+	flush := func() {
+		if len(batch) == 0 {
+			return
+		}
+		if err := e.export(batch); err != nil {
+			e.err = err
+			atomic.AddUint64(&e.dropped, uint64(len(batch)))
+		} else {
+			atomic.AddUint64(&e.exported, uint64(len(batch)))
+		}
+		batch = batch[:0]
+	}
+	for {
+		select {
+		case s, ok := <-e.spans:
+			if !ok {
+				flush()
+				return
+			}
+			batch = append(batch, s)
+			if len(batch) == otlpBatchSize {
+				flush()
+			}
+		case <-ticker.C:
+			flush()
+		}
+	}
+}
+
+// close exports the remaining spans, it must be called after all
+// requests are finished.
+func (e *otlpExporter) close() error {
+	close(e.spans)
+	<-e.done
+	if e.err != nil {
+		return fmt.Errorf("failed to export spans: %v", e.err)
+	}
+	return nil
+}
+
+type otlpValue struct {
+	StringValue *string `json:"stringValue,omitempty"`
+	IntValue    *string `json:"intValue,omitempty"`
+}
+
+type otlpAttribute struct {
+	Key   string    `json:"key"`
+	Value otlpValue `json:"value"`
+}
+
+func stringAttribute(key, value string) otlpAttribute {
+	return otlpAttribute{Key: key, Value: otlpValue{StringValue: &value}}
+}
+
+func intAttribute(key string, value int) otlpAttribute {
+	v := strconv.Itoa(value)
+	return otlpAttribute{Key: key, Value: otlpValue{IntValue: &v}}
+}
+
+type otlpStatus struct {
+	Code    int    `json:"code,omitempty"`
+	Message string `json:"message,omitempty"`
+}
+
+type otlpSpan struct {
+	TraceID           string          `json:"traceId"`
+	SpanID            string          `json:"spanId"`
+	Name              string          `json:"name"`
+	Kind              int             `json:"kind"`
+	StartTimeUnixNano string          `json:"startTimeUnixNano"`
+	EndTimeUnixNano   string          `json:"endTimeUnixNano"`
+	Attributes        []otlpAttribute `json:"attributes"`
+	Status            otlpStatus      `json:"status"`
+}
+
+type otlpScopeSpans struct {
+	Scope struct {
+		Name    string `json:"name"`
+		Version string `json:"version"`
+	} `json:"scope"`
+	Spans []otlpSpan `json:"spans"`
+}
+
+type otlpResourceSpans struct {
+	Resource struct {
+		Attributes []otlpAttribute `json:"attributes"`
+	} `json:"resource"`
+	ScopeSpans []otlpScopeSpans `json:"scopeSpans"`
+}
+
+type otlpTracesRequest struct {
+	ResourceSpans []otlpResourceSpans `json:"resourceSpans"`
+}
+
+func encodeSpans(spans []span) ([]byte, error) {
+	scope := otlpScopeSpans{Spans: make([]otlpSpan, len(spans))}
+	scope.Scope.Name, scope.Scope.Version = "bombardier", version
+	for i, s := range spans {
+		o := otlpSpan{
+			TraceID:           hex.EncodeToString(s.traceID[:]),
+			SpanID:            hex.EncodeToString(s.spanID[:]),
+			Name:              s.method,
+			Kind:              spanKindClient,
+			StartTimeUnixNano: strconv.FormatInt(s.start.UnixNano(), decBase),
+			EndTimeUnixNano:   strconv.FormatInt(s.end.UnixNano(), decBase),
+			Attributes: []otlpAttribute{
+				stringAttribute("http.request.method", s.method),
+				stringAttribute("url.full", s.url),
+			},
+		}
+		if s.code > 0 {
+			o.Attributes = append(o.Attributes,
+				intAttribute("http.response.status_code", s.code))
+		}
+		if s.err != "" {
+			o.Status = otlpStatus{Code: spanStatusError, Message: s.err}
+		} else if s.code >= 400 {
+			o.Status = otlpStatus{Code: spanStatusError}
+		}
+		scope.Spans[i] = o
+	}
+	rs := otlpResourceSpans{ScopeSpans: []otlpScopeSpans{scope}}
+	rs.Resource.Attributes = []otlpAttribute{
+		stringAttribute("service.name", "bombardier"),
+	}
+	return json.Marshal(otlpTracesRequest{
+		ResourceSpans: []otlpResourceSpans{rs},
+	})
+}
+
+func (e *otlpExporter) export(spans []span) error {
+	body, err := encodeSpans(spans)
+	if err != nil {
+		return err
+	}
+	resp, err := e.client.Post(e.url, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("collector responded with %v", resp.Status)
+	}
+	return nil
+}
@@ -0,0 +1,170 @@
+package main
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestTraceparent(t *testing.T) {
+	re := regexp.MustCompile(`^00-[0-9a-f]{32}-[0-9a-f]{16}-01$`)
+	seen := make(map[string]bool)
+	for i := 0; i < 100; i++ {
+		tp := newRequestTrace().traceparent()
+		if !re.MatchString(tp) {
+			t.Fatalf("invalid traceparent: %q", tp)
+		}
+		if seen[tp[3:35]] {
+			t.Fatalf("trace id %v was generated twice", tp[3:35])
+		}
+		seen[tp[3:35]] = true
+	}
+}
+
+func TestTracerKeepsSlowest(t *testing.T) {
+	tr := newTracer(3, "", defaultTimeout)
+	for _, us := range []uint64{50, 10, 70, 30, 90, 20, 60} {
+		rt := newRequestTrace()
+		rt.fill("GET", "http://localhost/")
+		var err error
+		code := 200
+		if us == 70 {
+			code, err = -1, errors.New("timeout")
+		}
+		tr.finish(rt, code, us, err)
+	}
+	info := tr.info()
+	if len(info.Slowest) != 3 {
+		t.Fatalf("expected 3 requests, but got %+v", info.Slowest)
+	}
+	for i, us := range []uint64{90, 70, 60} {
+		if info.Slowest[i].Latency != us {
+			t.Errorf("expected %v at %v, but got %+v", us, i, info.Slowest)
+		}
+	}
+	if r := info.Slowest[1]; r.Code != 0 || r.Error != "timeout" {
+		t.Errorf("expected failed request, but got %+v", r)
+	}
+	if r := info.Slowest[0]; r.Code != 200 || r.Method != "GET" ||
+		len(r.TraceID) != 32 {
+		t.Errorf("unexpected request: %+v", r)
+	}
+	if err := tr.close(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestOTLPURL(t *testing.T) {
+	expectations := []struct {
+		in, out string
+		err     error
+	}{
+		{"http://localhost:4318", "http://localhost:4318/v1/traces", nil},
+		{"https://collector/", "https://collector/v1/traces", nil},
+		{"http://localhost:4318/otlp/traces", "http://localhost:4318/otlp/traces", nil},
+		{"localhost:4318", "", errInvalidOTLPEndpoint},
+		{"grpc://localhost:4317", "", errInvalidOTLPEndpoint},
+		{"http://", "", errInvalidOTLPEndpoint},
+	}
+	for _, e := range expectations {
+		out, err := otlpURL(e.in)
+		if out != e.out || err != e.err {
+			t.Errorf("expected (%q, %v) for %q, but got (%q, %v)",
+				e.out, e.err, e.in, out, err)
+		}
+	}
+}
+
+func TestOTLPExporter(t *testing.T) {
+	var (
+		mu       sync.Mutex
+		received []otlpSpan
+	)
+	s := httptest.NewServer(
+		http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+			if r.URL.Path != otlpTracesPath ||
+				r.Header.Get("Content-Type") != "application/json" {
+				rw.WriteHeader(http.StatusNotFound)
+				return
+			}
+			body, _ := io.ReadAll(r.Body)
+			var req otlpTracesRequest
+			if err := json.Unmarshal(body, &req); err != nil {
+				rw.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			mu.Lock()
+			defer mu.Unlock()
+			for _, rs := range req.ResourceSpans {
+				for _, ss := range rs.ScopeSpans {
+					received = append(received, ss.Spans...)
+				}
+			}
+		}),
+	)
+	defer s.Close()
+	tr := newTracer(1, s.URL, defaultTimeout)
+	ok, failed := newRequestTrace(), newRequestTrace()
+	ok.fill("GET", "http://localhost/a")
+	failed.fill("POST", "http://localhost/b")
+	tr.finish(ok, 503, 1500, nil)
+	tr.finish(failed, -1, 2000, errors.New("timeout"))
+	if err := tr.close(); err != nil {
+		t.Fatal(err)
+	}
+	info := tr.info()
+	if info.Exported != 2 || info.Dropped != 0 {
+		t.Errorf("expected 2 spans exported, but got %+v", info)
+	}
+	if len(received) != 2 {
+		t.Fatalf("expected 2 spans, but got %+v", received)
+	}
+	first := received[0]
+	if first.TraceID != hex.EncodeToString(ok.traceID[:]) ||
+		first.SpanID != hex.EncodeToString(ok.spanID[:]) ||
+		first.Name != "GET" || first.Kind != spanKindClient ||
+		first.Status.Code != spanStatusError {
+		t.Errorf("unexpected span: %+v", first)
+	}
+	attrs := make(map[string]otlpValue)
+	for _, a := range first.Attributes {
+		attrs[a.Key] = a.Value
+	}
+	if v := attrs["http.response.status_code"]; v.IntValue == nil ||
+		*v.IntValue != "503" {
+		t.Errorf("unexpected attributes: %+v", first.Attributes)
+	}
+	if v := attrs["url.full"]; v.StringValue == nil ||
+		*v.StringValue != "http://localhost/a" {
+		t.Errorf("unexpected attributes: %+v", first.Attributes)
+	}
+	second := received[1]
+	if second.Status.Message != "timeout" {
+		t.Errorf("expected error status, but got %+v", second.Status)
+	}
+	for _, a := range second.Attributes {
+		if a.Key == "http.response.status_code" {
+			t.Errorf("unexpected status code of failed request: %+v", a)
+		}
+	}
+	start, err := strconv.ParseInt(first.StartTimeUnixNano, decBase, 64)
+	if err != nil {
+		t.Fatal(err)
+	}
+	end, err := strconv.ParseInt(first.EndTimeUnixNano, decBase, 64)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if time.Duration(end-start) != 1500*time.Microsecond {
+		t.Errorf("expected span of 1.5ms, but got %v",
+			time.Duration(end-start))
+	}
+}